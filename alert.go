@@ -1,145 +1,75 @@
 package dtls
 
-import "fmt"
+import (
+	"fmt"
 
-type alertLevel byte
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+)
+
+// AlertLevel is the severity of an alert, fatal alerts terminate the
+// connection
+type AlertLevel = alert.Level
 
+// AlertLevel enums
 const (
-	alertLevelWarning alertLevel = 1
-	alertLevelFatal   alertLevel = 2
+	AlertLevelWarning = alert.Warning
+	AlertLevelFatal   = alert.Fatal
 )
 
-func (a alertLevel) String() string {
-	switch a {
-	case alertLevelWarning:
-		return "LevelWarning"
-	case alertLevelFatal:
-		return "LevelFatal"
-	default:
-		return "Invalid alert level"
-	}
-}
-
-type alertDescription byte
+// AlertDescription is the reason of an alert
+type AlertDescription = alert.Description
 
+// AlertDescription enums
 const (
-	alertCloseNotify            alertDescription = 0
-	alertUnexpectedMessage      alertDescription = 10
-	alertBadRecordMac           alertDescription = 20
-	alertDecryptionFailed       alertDescription = 21
-	alertRecordOverflow         alertDescription = 22
-	alertDecompressionFailure   alertDescription = 30
-	alertHandshakeFailure       alertDescription = 40
-	alertNoCertificate          alertDescription = 41
-	alertBadCertificate         alertDescription = 42
-	alertUnsupportedCertificate alertDescription = 43
-	alertCertificateRevoked     alertDescription = 44
-	alertCertificateExpired     alertDescription = 45
-	alertCertificateUnknown     alertDescription = 46
-	alertIllegalParameter       alertDescription = 47
-	alertUnknownCA              alertDescription = 48
-	alertAccessDenied           alertDescription = 49
-	alertDecodeError            alertDescription = 50
-	alertDecryptError           alertDescription = 51
-	alertExportRestriction      alertDescription = 60
-	alertProtocolVersion        alertDescription = 70
-	alertInsufficientSecurity   alertDescription = 71
-	alertInternalError          alertDescription = 80
-	alertUserCanceled           alertDescription = 90
-	alertNoRenegotiation        alertDescription = 100
-	alertUnsupportedExtension   alertDescription = 110
+	AlertCloseNotify            = alert.CloseNotify
+	AlertUnexpectedMessage      = alert.UnexpectedMessage
+	AlertBadRecordMac           = alert.BadRecordMac
+	AlertDecryptionFailed       = alert.DecryptionFailed
+	AlertRecordOverflow         = alert.RecordOverflow
+	AlertDecompressionFailure   = alert.DecompressionFailure
+	AlertHandshakeFailure       = alert.HandshakeFailure
+	AlertNoCertificate          = alert.NoCertificate
+	AlertBadCertificate         = alert.BadCertificate
+	AlertUnsupportedCertificate = alert.UnsupportedCertificate
+	AlertCertificateRevoked     = alert.CertificateRevoked
+	AlertCertificateExpired     = alert.CertificateExpired
+	AlertCertificateUnknown     = alert.CertificateUnknown
+	AlertIllegalParameter       = alert.IllegalParameter
+	AlertUnknownCA              = alert.UnknownCA
+	AlertAccessDenied           = alert.AccessDenied
+	AlertDecodeError            = alert.DecodeError
+	AlertDecryptError           = alert.DecryptError
+	AlertExportRestriction      = alert.ExportRestriction
+	AlertProtocolVersion        = alert.ProtocolVersion
+	AlertInsufficientSecurity   = alert.InsufficientSecurity
+	AlertInternalError          = alert.InternalError
+	AlertUserCanceled           = alert.UserCanceled
+	AlertNoRenegotiation        = alert.NoRenegotiation
+	AlertUnsupportedExtension   = alert.UnsupportedExtension
+	AlertNoApplicationProtocol  = alert.NoApplicationProtocol
 )
 
-func (a alertDescription) String() string {
-	switch a {
-	case alertCloseNotify:
-		return "CloseNotify"
-	case alertUnexpectedMessage:
-		return "UnexpectedMessage"
-	case alertBadRecordMac:
-		return "BadRecordMac"
-	case alertDecryptionFailed:
-		return "DecryptionFailed"
-	case alertRecordOverflow:
-		return "RecordOverflow"
-	case alertDecompressionFailure:
-		return "DecompressionFailure"
-	case alertHandshakeFailure:
-		return "HandshakeFailure"
-	case alertNoCertificate:
-		return "NoCertificate"
-	case alertBadCertificate:
-		return "BadCertificate"
-	case alertUnsupportedCertificate:
-		return "UnsupportedCertificate"
-	case alertCertificateRevoked:
-		return "CertificateRevoked"
-	case alertCertificateExpired:
-		return "CertificateExpired"
-	case alertCertificateUnknown:
-		return "CertificateUnknown"
-	case alertIllegalParameter:
-		return "IllegalParameter"
-	case alertUnknownCA:
-		return "UnknownCA"
-	case alertAccessDenied:
-		return "AccessDenied"
-	case alertDecodeError:
-		return "DecodeError"
-	case alertDecryptError:
-		return "DecryptError"
-	case alertExportRestriction:
-		return "ExportRestriction"
-	case alertProtocolVersion:
-		return "ProtocolVersion"
-	case alertInsufficientSecurity:
-		return "InsufficientSecurity"
-	case alertInternalError:
-		return "InternalError"
-	case alertUserCanceled:
-		return "UserCanceled"
-	case alertNoRenegotiation:
-		return "NoRenegotiation"
-	case alertUnsupportedExtension:
-		return "UnsupportedExtension"
-	default:
-		return "Invalid alert description"
-	}
-}
+// AlertError is the error of a connection aborted by an alert, either
+// received from the remote or sent to it. Use errors.As to inspect it.
+type AlertError struct {
+	Level       AlertLevel
+	Description AlertDescription
 
-// One of the content types supported by the TLS record layer is the
-// alert type.  Alert messages convey the severity of the message
-// (warning or fatal) and a description of the alert.  Alert messages
-// with a level of fatal result in the immediate termination of the
-// connection.  In this case, other connections corresponding to the
-// session may continue, but the session identifier MUST be invalidated,
-// preventing the failed session from being used to establish new
-// connections.  Like other messages, alert messages are encrypted and
-// compressed, as specified by the current connection state.
-// https://tools.ietf.org/html/rfc5246#section-7.2
-type alert struct {
-	alertLevel       alertLevel
-	alertDescription alertDescription
-}
+	// Remote is true if the alert was received from the remote
+	Remote bool
 
-func (a alert) contentType() contentType {
-	return contentTypeAlert
+	// Err is the local error the alert was sent for, nil if Remote is true
+	Err error
 }
 
-func (a *alert) Marshal() ([]byte, error) {
-	return []byte{byte(a.alertLevel), byte(a.alertDescription)}, nil
-}
-
-func (a *alert) Unmarshal(data []byte) error {
-	if len(data) != 2 {
-		return errBufferTooSmall
+func (e *AlertError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
 	}
-
-	a.alertLevel = alertLevel(data[0])
-	a.alertDescription = alertDescription(data[1])
-	return nil
+	return fmt.Sprintf("alert: Alert %s: %s", e.Level, e.Description)
 }
 
-func (a *alert) String() string {
-	return fmt.Sprintf("Alert %s: %s", a.alertLevel, a.alertDescription)
+// Unwrap returns the local error the alert was sent for
+func (e *AlertError) Unwrap() error {
+	return e.Err
 }
@@ -1,48 +1,51 @@
 package dtls
 
 import (
-	"reflect"
+	"errors"
+	"fmt"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
 )
 
-func TestAlert(t *testing.T) {
-	for _, test := range []struct {
-		Name               string
-		Data               []byte
-		Want               *alert
-		WantUnmarshalError error
-	}{
-		{
-			Name: "Valid Alert",
-			Data: []byte{0x02, 0x0A},
-			Want: &alert{
-				alertLevel:       alertLevelFatal,
-				alertDescription: alertUnexpectedMessage,
-			},
-		},
-		{
-			Name:               "Invalid alert length",
-			Data:               []byte{0x00},
-			Want:               &alert{},
-			WantUnmarshalError: errBufferTooSmall,
-		},
-	} {
-		a := &alert{}
-		if err := a.Unmarshal(test.Data); err != test.WantUnmarshalError {
-			t.Errorf("Unexpected Error %v: exp: %v got: %v", test.Name, test.WantUnmarshalError, err)
-		} else if !reflect.DeepEqual(test.Want, a) {
-			t.Errorf("%q alert.unmarshal: got %v, want %v", test.Name, a, test.Want)
-		}
+func TestAlertError(t *testing.T) {
+	received := &AlertError{Level: AlertLevelFatal, Description: AlertBadCertificate, Remote: true}
+	if received.Error() != "alert: Alert LevelFatal: BadCertificate" {
+		t.Errorf("Received alert: unexpected error %q", received.Error())
+	}
 
-		if test.WantUnmarshalError != nil {
-			return
-		}
+	sent := error(&AlertError{Level: AlertLevelFatal, Description: AlertHandshakeFailure, Err: errVerifyDataMismatch})
+	if sent.Error() != errVerifyDataMismatch.Error() {
+		t.Errorf("Sent alert: expected(%v) actual(%v)", errVerifyDataMismatch, sent)
+	}
+	if !errors.Is(sent, errVerifyDataMismatch) {
+		t.Errorf("Sent alert does not wrap %v", errVerifyDataMismatch)
+	}
+	var alertErr *AlertError
+	if !errors.As(fmt.Errorf("handshake: %w", sent), &alertErr) || alertErr.Description != AlertHandshakeFailure {
+		t.Errorf("Sent alert: expected %s, got %v", AlertHandshakeFailure, alertErr)
+	}
+}
+
+func TestSendAlertError(t *testing.T) {
+	client, err := NewClientEngine(&Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errWrite := errors.New("write failed")
+	client.conn.sendDatagrams = func([][]byte) error {
+		return errWrite
+	}
+
+	// A failure to send the alert is returned if it was not caused by an error
+	err = client.conn.sendAlert(&alert.Alert{Level: alert.Warning, Description: alert.NoRenegotiation}, nil)
+	if !errors.Is(err, errWrite) {
+		t.Errorf("sendAlert: expected(%v) actual(%v)", errWrite, err)
+	}
 
-		data, marshalErr := a.Marshal()
-		if marshalErr != nil {
-			t.Errorf("Unexpected Error %v: got: %v", test.Name, marshalErr)
-		} else if !reflect.DeepEqual(test.Data, data) {
-			t.Errorf("%q alert.marshal: got % 02x, want % 02x", test.Name, data, test.Data)
-		}
+	err = client.conn.sendAlert(&alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch)
+	var alertErr *AlertError
+	if !errors.As(err, &alertErr) || !errors.Is(err, errVerifyDataMismatch) {
+		t.Errorf("sendAlert: expected(%v) actual(%v)", errVerifyDataMismatch, err)
 	}
 }
@@ -0,0 +1,17 @@
+package dtls
+
+// negotiateALPN returns the first of the server protocols also offered by
+// the client, following the preference order of the server
+func negotiateALPN(localProtocols, remoteProtocols []string) (string, error) {
+	if len(localProtocols) == 0 || len(remoteProtocols) == 0 {
+		return "", nil
+	}
+	for _, local := range localProtocols {
+		for _, remote := range remoteProtocols {
+			if local == remote {
+				return local, nil
+			}
+		}
+	}
+	return "", errALPNNoAppProto
+}
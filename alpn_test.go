@@ -0,0 +1,36 @@
+package dtls
+
+import "testing"
+
+func TestNegotiateALPN(t *testing.T) {
+	for name, tt := range map[string]struct {
+		server, client []string
+		expected       string
+		expectedErr    error
+	}{
+		"ServerPreference": {
+			server:   []string{"c-webrtc", "webrtc"},
+			client:   []string{"webrtc", "c-webrtc"},
+			expected: "c-webrtc",
+		},
+		"NotConfigured": {
+			client: []string{"webrtc"},
+		},
+		"NotOffered": {
+			server: []string{"webrtc"},
+		},
+		"NoIntersection": {
+			server:      []string{"coap"},
+			client:      []string{"webrtc"},
+			expectedErr: errALPNNoAppProto,
+		},
+	} {
+		proto, err := negotiateALPN(tt.server, tt.client)
+		if err != tt.expectedErr {
+			t.Errorf("%s: expected error %v, got %v", name, tt.expectedErr, err)
+		}
+		if proto != tt.expected {
+			t.Errorf("%s: expected %q, got %q", name, tt.expected, proto)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package dtls
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/internal/net/udp"
 	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
 	"github.com/pion/logging"
 	"github.com/pion/transport/test"
@@ -115,3 +118,110 @@ func BenchmarkConnReadWrite(b *testing.B) {
 		benchmarkConn(b, n)
 	}
 }
+
+// benchmarkReceiveRecord measures the receive path of a single record of
+// application data, from the datagram read by inboundLoop to ReadBuffer
+func benchmarkReceiveRecord(b *testing.B, cipherSuite CipherSuiteID, size int) {
+	ca, cb := dpipe.Pipe()
+	serverConn := &recordingConn{Conn: cb}
+
+	isPSK := cipherSuiteForID(cipherSuite).isPSK()
+	clientConfig := &Config{CipherSuites: []CipherSuiteID{cipherSuite}}
+	serverConfig := &Config{CipherSuites: []CipherSuiteID{cipherSuite}}
+	if isPSK {
+		psk := func([]byte) ([]byte, error) {
+			return []byte{0xAB, 0xC1, 0x23}, nil
+		}
+		clientConfig.PSK, clientConfig.PSKIdentityHint = psk, []byte("Pion DTLS Client")
+		serverConfig.PSK = psk
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	server := make(chan *Conn, 1)
+	go func() {
+		s, err := testServer(ctx, serverConn, serverConfig, !isPSK)
+		serverErr <- err
+		server <- s
+	}()
+	client, err := testClient(ctx, ca, clientConfig, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = <-serverErr; err != nil {
+		b.Fatal(err)
+	}
+	s := <-server
+	defer func() {
+		_ = s.Close()
+		_ = client.Close()
+	}()
+
+	// Capture the datagram of a record sent by the server
+	if _, err = s.Write(make([]byte, size)); err != nil {
+		b.Fatal(err)
+	}
+	record, err := client.ReadBuffer()
+	if err != nil {
+		b.Fatal(err)
+	}
+	record.Release()
+	serverConn.lock.Lock()
+	datagram := serverConn.written[len(serverConn.written)-1]
+	serverConn.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < b.N; i++ {
+			r, rErr := client.ReadBuffer()
+			if rErr != nil {
+				b.Error(rErr)
+				return
+			}
+			r.Release()
+		}
+	}()
+
+	reader := bytes.NewReader(nil)
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The datagram is decrypted in place, each iteration starts from a copy
+		buf := udp.GetBuffer()
+		reader.Reset(datagram)
+		if err = buf.Fill(reader); err != nil {
+			b.Fatal(err)
+		}
+		err = client.handleDatagram(buf.Bytes(), buf)
+		buf.Release()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func BenchmarkReceiveRecord(b *testing.B) {
+	for _, cipherSuite := range []CipherSuiteID{
+		TLS_ECDHE_ECDSA_WITH_AES_128_CCM,
+		TLS_ECDHE_ECDSA_WITH_AES_128_CCM_8,
+		TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		TLS_PSK_WITH_AES_128_CCM,
+		TLS_PSK_WITH_AES_128_CCM_8,
+		TLS_PSK_WITH_AES_128_GCM_SHA256,
+	} {
+		cipherSuite := cipherSuite
+		b.Run(cipherSuite.String(), func(b *testing.B) {
+			for _, size := range []int{16, 1024} {
+				b.Run(fmt.Sprintf("%d", size), func(b *testing.B) {
+					benchmarkReceiveRecord(b, cipherSuite, size)
+				})
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package dtls
 
 import (
-	"encoding/binary"
 	"fmt"
 	"hash"
+	"io"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 // CipherSuiteID is an ID for our supported CipherSuites
@@ -60,7 +63,7 @@ func (c CipherSuiteID) String() string {
 type cipherSuite interface {
 	String() string
 	ID() CipherSuiteID
-	certificateType() clientCertificateType
+	certificateType() clientcertificate.Type
 	hashFunc() func() hash.Hash
 	isPSK() bool
 	isInitialized() bool
@@ -68,10 +71,31 @@ type cipherSuite interface {
 	// Generate the internal encryption state
 	init(masterSecret, clientRandom, serverRandom []byte, isClient bool) error
 
-	encrypt(pkt *recordLayer, raw []byte) ([]byte, error)
+	encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error)
 	decrypt(in []byte) ([]byte, error)
 }
 
+// Implemented by the cipher suites using a block cipher, which switch to
+// encrypt-then-MAC once it was negotiated https://tools.ietf.org/html/rfc7366
+type encryptThenMACCipherSuite interface {
+	setEncryptThenMAC()
+}
+
+// Implemented by the cipher suites whose keys may protect fewer records than
+// the sequence numbers allow, such as the AEADs
+// https://tools.ietf.org/html/rfc8446#section-5.5
+type recordLimitCipherSuite interface {
+	recordLimit() uint64
+}
+
+// Number of records AES-GCM and AES-CCM may protect with the same keys
+// before the confidentiality margin is used up, AES-CCM is limited to
+// 2^23.5 https://tools.ietf.org/html/rfc9147#section-4.5.3
+const (
+	aesGCMRecordLimit = 1 << 24
+	aesCCMRecordLimit = 11863283
+)
+
 // CipherSuiteName provides the same functionality as tls.CipherSuiteName
 // that appeared first in Go 1.14.
 //
@@ -136,32 +160,29 @@ func allCipherSuites() []cipherSuite {
 	}
 }
 
-func decodeCipherSuites(buf []byte) ([]cipherSuite, error) {
-	if len(buf) < 2 {
-		return nil, errDTLSPacketInvalidLength
-	}
-	cipherSuitesCount := int(binary.BigEndian.Uint16(buf[0:])) / 2
-	rtrn := []cipherSuite{}
-	for i := 0; i < cipherSuitesCount; i++ {
-		if len(buf) < (i*2 + 4) {
-			return nil, errBufferTooSmall
-		}
-		id := CipherSuiteID(binary.BigEndian.Uint16(buf[(i*2)+2:]))
-		if c := cipherSuiteForID(id); c != nil {
-			rtrn = append(rtrn, c)
+// renegotiationInfoSCSV is the signaling cipher suite value a client may send
+// instead of an empty renegotiation_info extension
+// https://tools.ietf.org/html/rfc5746#section-3.3
+const renegotiationInfoSCSV CipherSuiteID = 0x00ff
+
+// cipherSuitesForIDs maps the IDs offered in a ClientHello to the cipher suites
+// we implement, IDs we don't know about are skipped
+func cipherSuitesForIDs(ids []uint16) []cipherSuite {
+	cipherSuites := []cipherSuite{}
+	for _, id := range ids {
+		if c := cipherSuiteForID(CipherSuiteID(id)); c != nil {
+			cipherSuites = append(cipherSuites, c)
 		}
 	}
-	return rtrn, nil
+	return cipherSuites
 }
 
-func encodeCipherSuites(cipherSuites []cipherSuite) []byte {
-	out := []byte{0x00, 0x00}
-	binary.BigEndian.PutUint16(out[len(out)-2:], uint16(len(cipherSuites)*2))
+func cipherSuiteIDs(cipherSuites []cipherSuite) []uint16 {
+	rtrn := make([]uint16, 0, len(cipherSuites))
 	for _, c := range cipherSuites {
-		out = append(out, []byte{0x00, 0x00}...)
-		binary.BigEndian.PutUint16(out[len(out)-2:], uint16(c.ID()))
+		rtrn = append(rtrn, uint16(c.ID()))
 	}
-	return out
+	return rtrn
 }
 
 func parseCipherSuites(userSelectedSuites []CipherSuiteID, excludePSK, excludeNonPSK bool) ([]cipherSuite, error) {
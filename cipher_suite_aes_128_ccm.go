@@ -4,18 +4,22 @@ import (
 	"crypto/sha256"
 	"errors"
 	"hash"
+	"io"
 	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 type cipherSuiteAes128Ccm struct {
 	ccm                   atomic.Value // *cryptoCCM
-	clientCertificateType clientCertificateType
+	clientCertificateType clientcertificate.Type
 	id                    CipherSuiteID
 	psk                   bool
 	cryptoCCMTagLen       cryptoCCMTagLen
 }
 
-func newCipherSuiteAes128Ccm(clientCertificateType clientCertificateType, id CipherSuiteID, psk bool, cryptoCCMTagLen cryptoCCMTagLen) *cipherSuiteAes128Ccm {
+func newCipherSuiteAes128Ccm(clientCertificateType clientcertificate.Type, id CipherSuiteID, psk bool, cryptoCCMTagLen cryptoCCMTagLen) *cipherSuiteAes128Ccm {
 	return &cipherSuiteAes128Ccm{
 		clientCertificateType: clientCertificateType,
 		id:                    id,
@@ -24,7 +28,7 @@ func newCipherSuiteAes128Ccm(clientCertificateType clientCertificateType, id Cip
 	}
 }
 
-func (c *cipherSuiteAes128Ccm) certificateType() clientCertificateType {
+func (c *cipherSuiteAes128Ccm) certificateType() clientcertificate.Type {
 	return c.clientCertificateType
 }
 
@@ -44,6 +48,10 @@ func (c *cipherSuiteAes128Ccm) isPSK() bool {
 	return c.psk
 }
 
+func (c *cipherSuiteAes128Ccm) recordLimit() uint64 {
+	return aesCCMRecordLimit
+}
+
 func (c *cipherSuiteAes128Ccm) isInitialized() bool {
 	return c.ccm.Load() != nil
 }
@@ -71,13 +79,13 @@ func (c *cipherSuiteAes128Ccm) init(masterSecret, clientRandom, serverRandom []b
 	return err
 }
 
-func (c *cipherSuiteAes128Ccm) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
+func (c *cipherSuiteAes128Ccm) encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error) {
 	ccm := c.ccm.Load()
 	if ccm == nil { // !c.isInitialized()
 		return nil, errors.New("CipherSuite has not been initialized, unable to encrypt")
 	}
 
-	return ccm.(*cryptoCCM).encrypt(pkt, raw)
+	return ccm.(*cryptoCCM).encrypt(pkt, raw, rand)
 }
 
 func (c *cipherSuiteAes128Ccm) decrypt(raw []byte) ([]byte, error) {
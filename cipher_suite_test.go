@@ -1,24 +1,19 @@
 package dtls
 
 import (
+	"reflect"
 	"testing"
 )
 
-func TestDecodeCipherSuites(t *testing.T) {
-	testCases := []struct {
-		buf    []byte
-		result []*cipherSuite
-		err    error
-	}{
-		{[]byte{}, nil, errDTLSPacketInvalidLength},
-	}
+func TestCipherSuitesForIDs(t *testing.T) {
+	ids := []uint16{uint16(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256), uint16(renegotiationInfoSCSV), 0x0000}
 
-	for _, testCase := range testCases {
-		_, err := decodeCipherSuites(testCase.buf)
-		if err != testCase.err {
-			t.Fatal("Unexpected error", err)
-		}
-		// todo: compare result
+	cipherSuites := cipherSuitesForIDs(ids)
+	if len(cipherSuites) != 1 || cipherSuites[0].ID() != TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("Unexpected cipher suites: %v", cipherSuites)
+	}
+	if !reflect.DeepEqual(cipherSuiteIDs(cipherSuites), ids[:1]) {
+		t.Fatalf("Unexpected cipher suite IDs: %v", cipherSuiteIDs(cipherSuites))
 	}
 }
 
@@ -4,15 +4,19 @@ import (
 	"crypto/sha256"
 	"errors"
 	"hash"
+	"io"
 	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 type cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256 struct {
 	gcm atomic.Value // *cryptoGCM
 }
 
-func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) certificateType() clientCertificateType {
-	return clientCertificateTypeECDSASign
+func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) certificateType() clientcertificate.Type {
+	return clientcertificate.ECDSASign
 }
 
 func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) ID() CipherSuiteID {
@@ -31,6 +35,10 @@ func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) isPSK() bool {
 	return false
 }
 
+func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) recordLimit() uint64 {
+	return aesGCMRecordLimit
+}
+
 func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) isInitialized() bool {
 	return c.gcm.Load() != nil
 }
@@ -58,13 +66,13 @@ func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) init(masterSecret, clientR
 	return err
 }
 
-func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
+func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error) {
 	gcm := c.gcm.Load()
 	if gcm == nil { // !c.isInitialized()
 		return nil, errors.New("CipherSuite has not been initialized, unable to encrypt")
 	}
 
-	return gcm.(*cryptoGCM).encrypt(pkt, raw)
+	return gcm.(*cryptoGCM).encrypt(pkt, raw, rand)
 }
 
 func (c *cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256) decrypt(raw []byte) ([]byte, error) {
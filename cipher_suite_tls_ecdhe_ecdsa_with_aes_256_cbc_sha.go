@@ -4,15 +4,20 @@ import (
 	"crypto/sha256"
 	"errors"
 	"hash"
+	"io"
 	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 type cipherSuiteTLSEcdheEcdsaWithAes256CbcSha struct {
-	cbc atomic.Value // *cryptoCBC
+	cbc            atomic.Value // *cryptoCBC
+	encryptThenMAC bool
 }
 
-func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) certificateType() clientCertificateType {
-	return clientCertificateTypeECDSASign
+func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) certificateType() clientcertificate.Type {
+	return clientcertificate.ECDSASign
 }
 
 func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) ID() CipherSuiteID {
@@ -31,6 +36,10 @@ func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) isPSK() bool {
 	return false
 }
 
+func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) setEncryptThenMAC() {
+	c.encryptThenMAC = true
+}
+
 func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) isInitialized() bool {
 	return c.cbc.Load() != nil
 }
@@ -52,11 +61,13 @@ func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) init(masterSecret, clientRand
 		cbc, err = newCryptoCBC(
 			keys.clientWriteKey, keys.clientWriteIV, keys.clientMACKey,
 			keys.serverWriteKey, keys.serverWriteIV, keys.serverMACKey,
+			c.encryptThenMAC,
 		)
 	} else {
 		cbc, err = newCryptoCBC(
 			keys.serverWriteKey, keys.serverWriteIV, keys.serverMACKey,
 			keys.clientWriteKey, keys.clientWriteIV, keys.clientMACKey,
+			c.encryptThenMAC,
 		)
 	}
 	c.cbc.Store(cbc)
@@ -64,13 +75,13 @@ func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) init(masterSecret, clientRand
 	return err
 }
 
-func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
+func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error) {
 	cbc := c.cbc.Load()
 	if cbc == nil { // !c.isInitialized()
 		return nil, errors.New("CipherSuite has not been initialized, unable to encrypt")
 	}
 
-	return cbc.(*cryptoCBC).encrypt(pkt, raw)
+	return cbc.(*cryptoCBC).encrypt(pkt, raw, rand)
 }
 
 func (c *cipherSuiteTLSEcdheEcdsaWithAes256CbcSha) decrypt(raw []byte) ([]byte, error) {
@@ -5,158 +5,217 @@ import (
 	"crypto"
 	"crypto/x509"
 	"fmt"
-	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
-func initalizeCipherSuite(c *Conn, h *handshakeMessageServerKeyExchange) (*alert, error) {
+func initalizeCipherSuite(c *Conn, h *handshake.MessageServerKeyExchange) (*alert.Alert, error) {
 	clientRandom, err := c.state.localRandom.Marshal()
 	if err != nil {
-		return &alert{alertLevelFatal, alertInternalError}, err
+		return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 	}
 	serverRandom, err := c.state.remoteRandom.Marshal()
 	if err != nil {
-		return &alert{alertLevelFatal, alertInternalError}, err
+		return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 	}
 
 	if c.state.extendedMasterSecret {
 		var sessionHash []byte
 		sessionHash, err = c.handshakeCache.sessionHash(c.state.cipherSuite.hashFunc())
 		if err != nil {
-			return &alert{alertLevelFatal, alertInternalError}, err
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 
 		c.state.masterSecret, err = prfExtendedMasterSecret(c.state.preMasterSecret, sessionHash, c.state.cipherSuite.hashFunc())
 		if err != nil {
-			return &alert{alertLevelFatal, alertIllegalParameter}, err
+			return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
 		}
 	} else {
 		c.state.masterSecret, err = prfMasterSecret(c.state.preMasterSecret, clientRandom, serverRandom, c.state.cipherSuite.hashFunc())
 		if err != nil {
-			return &alert{alertLevelFatal, alertInternalError}, err
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 	}
 
 	if c.localPSKCallback == nil {
-		expectedHash := valueKeySignature(clientRandom, serverRandom, h.publicKey, h.namedCurve, h.hashAlgorithm)
-		if err = verifyKeySignature(expectedHash, h.signature, h.hashAlgorithm, c.state.remoteCertificate); err != nil {
-			return &alert{alertLevelFatal, alertBadCertificate}, err
+		expectedHash := valueKeySignature(clientRandom, serverRandom, h.PublicKey, h.NamedCurve, h.HashAlgorithm)
+		if err = verifyKeySignature(expectedHash, h.Signature, h.HashAlgorithm, c.state.remoteCertificate); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 		}
 		var chains [][]*x509.Certificate
 		if !c.insecureSkipVerify {
-			if chains, err = verifyServerCert(c.state.remoteCertificate, c.rootCAs, c.serverName); err != nil {
-				return &alert{alertLevelFatal, alertBadCertificate}, err
+			if chains, err = verifyServerCert(c.state.remoteCertificate, c.rootCAs, c.serverName, c.clock.Now()); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
 		}
 		if c.verifyPeerCertificate != nil {
 			if err = c.verifyPeerCertificate(c.state.remoteCertificate, chains); err != nil {
-				return &alert{alertLevelFatal, alertBadCertificate}, err
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
 		}
 	}
 
 	if err = c.state.cipherSuite.init(c.state.masterSecret, clientRandom, serverRandom /* isClient */, true); err != nil {
-		return &alert{alertLevelFatal, alertInternalError}, err
+		return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 	}
 	return nil, nil
 }
 
-func handleServerKeyExchange(c *Conn, h *handshakeMessageServerKeyExchange) (*alert, error) {
+func handleServerKeyExchange(c *Conn, h *handshake.MessageServerKeyExchange) (*alert.Alert, error) {
 	var err error
 	if c.localPSKCallback != nil {
 		var psk []byte
-		if psk, err = c.localPSKCallback(h.identityHint); err != nil {
-			return &alert{alertLevelFatal, alertInternalError}, err
+		if psk, err = c.localPSKCallback(h.IdentityHint); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 
 		c.state.preMasterSecret = prfPSKPreMasterSecret(psk)
 	} else {
-		if c.localKeypair, err = generateKeypair(h.namedCurve); err != nil {
-			return &alert{alertLevelFatal, alertInternalError}, err
+		if c.localKeypair, err = elliptic.GenerateKeypair(h.NamedCurve, c.rand); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 
-		if c.state.preMasterSecret, err = prfPreMasterSecret(h.publicKey, c.localKeypair.privateKey, c.localKeypair.curve); err != nil {
-			return &alert{alertLevelFatal, alertInternalError}, err
+		if c.state.preMasterSecret, err = prfPreMasterSecret(h.PublicKey, c.localKeypair.PrivateKey, c.localKeypair.Curve); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 		}
 	}
 
 	return nil, nil
 }
 
-func clientHandshakeHandler(c *Conn) (*alert, error) {
-	handleSingleHandshake := func(buf []byte) (*alert, error) {
-		rawHandshake := &handshake{}
+func clientHandshakeHandler(c *Conn) (*alert.Alert, error) {
+	handleSingleHandshake := func(buf []byte) (*alert.Alert, error) {
+		rawHandshake := &handshake.Handshake{}
 		if err := rawHandshake.Unmarshal(buf); err != nil {
-			return &alert{alertLevelFatal, alertDecodeError}, err
+			return &alert.Alert{Level: alert.Fatal, Description: alert.DecodeError}, err
 		}
 
-		c.log.Tracef("[handshake] <- %s", rawHandshake.handshakeMessage.handshakeType().String())
-		switch h := rawHandshake.handshakeMessage.(type) {
-		case *handshakeMessageHelloVerifyRequest:
-			c.cookie = append([]byte{}, h.cookie...)
+		c.log.Tracef("[handshake] <- %s", rawHandshake.Message.Type().String())
+		switch h := rawHandshake.Message.(type) {
+		case *handshake.MessageHelloVerifyRequest:
+			c.cookie = append([]byte{}, h.Cookie...)
+
+		case *handshake.MessageServerHello:
+			if h.CipherSuiteID == nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidCipherSuite
+			}
+			selectedCipherSuite := cipherSuiteForID(CipherSuiteID(*h.CipherSuiteID))
+			if selectedCipherSuite == nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errInvalidCipherSuite
+			}
 
-		case *handshakeMessageServerHello:
-			for _, extension := range h.extensions {
-				switch e := extension.(type) {
-				case *extensionUseSRTP:
-					profile, ok := findMatchingSRTPProfile(e.protectionProfiles, c.localSRTPProtectionProfiles)
+			var renegotiationInfo *extension.RenegotiationInfo
+			for _, val := range h.Extensions {
+				switch e := val.(type) {
+				case *extension.UseSRTP:
+					profile, ok := findMatchingSRTPProfile(e.ProtectionProfiles, c.localSRTPProtectionProfiles)
 					if !ok {
-						return &alert{alertLevelFatal, alertIllegalParameter}, errClientNoMatchingSRTPProfile
+						return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errClientNoMatchingSRTPProfile
+					}
+					if len(e.MasterKeyIdentifier) > 0 && !bytes.Equal(e.MasterKeyIdentifier, c.localSRTPMasterKeyIdentifier) {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errSRTPMasterKeyIdentifierMismatch
 					}
 					c.state.srtpProtectionProfile = profile
-				case *extensionUseExtendedMasterSecret:
+					c.state.srtpMasterKeyIdentifier = e.MasterKeyIdentifier
+				case *extension.UseExtendedMasterSecret:
 					if c.extendedMasterSecret != DisableExtendedMasterSecret {
 						c.state.extendedMasterSecret = true
 					}
+				case *extension.RenegotiationInfo:
+					renegotiationInfo = e
+				case *extension.ALPN:
+					if len(e.ProtocolNameList) != 1 {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errALPNInvalidFormat
+					}
+					if _, err := negotiateALPN(c.localNextProtos, e.ProtocolNameList); err != nil || len(c.localNextProtos) == 0 {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, errALPNNotOffered
+					}
+					c.state.negotiatedProtocol = e.ProtocolNameList[0]
+				case *extension.EncryptThenMAC:
+					if cipherSuite, ok := selectedCipherSuite.(encryptThenMACCipherSuite); ok {
+						cipherSuite.setEncryptThenMAC()
+						c.state.encryptThenMAC = true
+					}
+				case *extension.Raw:
+					if !c.offeredHelloExtension(e.Type) {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.UnsupportedExtension}, errHelloExtensionNotOffered
+					}
+				}
+			}
+			switch {
+			case renegotiationInfo != nil:
+				expected := append(append([]byte{}, c.clientVerifyData...), c.serverVerifyData...)
+				if !bytes.Equal(renegotiationInfo.RenegotiatedConnection, expected) {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errRenegotiationInfoMismatch
 				}
+				c.secureRenegotiation = true
+			case c.renegotiating:
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errRenegotiationUnsupported
 			}
 			if c.extendedMasterSecret == RequireExtendedMasterSecret && !c.state.extendedMasterSecret {
-				return &alert{alertLevelFatal, alertInsufficientSecurity}, errClientRequiredButNoServerEMS
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errClientRequiredButNoServerEMS
 			}
 			if len(c.localSRTPProtectionProfiles) > 0 && c.state.srtpProtectionProfile == 0 {
-				return &alert{alertLevelFatal, alertInsufficientSecurity}, errRequestedButNoSRTPExtension
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errRequestedButNoSRTPExtension
+			}
+			if _, ok := findMatchingCipherSuite([]cipherSuite{selectedCipherSuite}, c.localCipherSuites); !ok {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errCipherSuiteNoIntersection
 			}
-			if _, ok := findMatchingCipherSuite([]cipherSuite{h.cipherSuite}, c.localCipherSuites); !ok {
-				return &alert{alertLevelFatal, alertInsufficientSecurity}, errCipherSuiteNoIntersection
+
+			if _, err := c.handleRemoteHelloExtensions(h.Extensions); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 
-			c.state.cipherSuite = h.cipherSuite
-			c.state.remoteRandom = h.random
-			c.log.Tracef("[handshake] use cipher suite: %s", h.cipherSuite.String())
+			c.state.cipherSuite = selectedCipherSuite
+			c.state.remoteRandom = h.Random
+			c.log.Tracef("[handshake] use cipher suite: %s", selectedCipherSuite.String())
 
-		case *handshakeMessageCertificate:
-			c.state.remoteCertificate = h.certificate
+		case *handshake.MessageCertificate:
+			c.state.remoteCertificate = h.Certificate
 
-		case *handshakeMessageServerKeyExchange:
+		case *handshake.MessageServerKeyExchange:
 			alertPtr, err := handleServerKeyExchange(c, h)
 			if err != nil {
 				return alertPtr, err
 			}
-		case *handshakeMessageCertificateRequest:
+		case *handshake.MessageCertificateRequest:
 			c.remoteRequestedCertificate = true
-		case *handshakeMessageServerHelloDone:
-		case *handshakeMessageFinished:
+		case *handshake.MessageServerHelloDone:
+		case *handshake.MessageFinished:
 			plainText := c.handshakeCache.pullAndMerge(
-				handshakeCachePullRule{handshakeTypeClientHello, true},
-				handshakeCachePullRule{handshakeTypeServerHello, false},
-				handshakeCachePullRule{handshakeTypeCertificate, false},
-				handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-				handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-				handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-				handshakeCachePullRule{handshakeTypeCertificate, true},
-				handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
-				handshakeCachePullRule{handshakeTypeCertificateVerify, true},
-				handshakeCachePullRule{handshakeTypeFinished, true},
+				handshakeCachePullRule{handshake.TypeClientHello, true},
+				handshakeCachePullRule{handshake.TypeServerHello, false},
+				handshakeCachePullRule{handshake.TypeCertificate, false},
+				handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+				handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+				handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+				handshakeCachePullRule{handshake.TypeCertificate, true},
+				handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
+				handshakeCachePullRule{handshake.TypeCertificateVerify, true},
+				handshakeCachePullRule{handshake.TypeFinished, true},
 			)
 
 			expectedVerifyData, err := prfVerifyDataServer(c.state.masterSecret, plainText, c.state.cipherSuite.hashFunc())
 			if err != nil {
-				return &alert{alertLevelFatal, alertInternalError}, err
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
-			if !bytes.Equal(expectedVerifyData, h.verifyData) {
-				return &alert{alertLevelFatal, alertHandshakeFailure}, errVerifyDataMismatch
+			if !bytes.Equal(expectedVerifyData, h.VerifyData) {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch
 			}
+			c.clientVerifyData = c.localVerifyData
+			c.serverVerifyData = append([]byte{}, h.VerifyData...)
+		case *handshake.MessageHelloRequest:
+			// Handled by handleIncomingPacket when the renegotiation was started
 		default:
-			return &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("unhandled handshake %d", h.handshakeType())
+			return &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("unhandled handshake %d", h.Type())
 		}
 
 		return nil, nil
@@ -166,8 +225,8 @@ func clientHandshakeHandler(c *Conn) (*alert, error) {
 	case flight1:
 		// HelloVerifyRequest can be skipped by the server, so allow ServerHello during flight1 also
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeHelloVerifyRequest, false},
-			handshakeCachePullRule{handshakeTypeServerHello, false},
+			handshakeCachePullRule{handshake.TypeHelloVerifyRequest, false},
+			handshakeCachePullRule{handshake.TypeServerHello, false},
 		)
 
 		switch {
@@ -177,35 +236,39 @@ func clientHandshakeHandler(c *Conn) (*alert, error) {
 			}
 			c.handshakeMessageSequence++
 		case expectedMessages[1] != nil:
-			if alertPtr, err := handleSingleHandshake(expectedMessages[1].data); err != nil {
-				return alertPtr, err
+			// Process the whole of flight4 as if we had sent a second ClientHello
+			if err := c.currFlight.set(flight3); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
+			return clientHandshakeHandler(c)
 		default:
 			return nil, nil // We have no messages we can handle yet
 		}
 
-		c.currFlight.set(flight3)
+		if err := c.currFlight.set(flight3); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
 	case flight3:
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeServerHello, false},
-			handshakeCachePullRule{handshakeTypeCertificate, false},
-			handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-			handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-			handshakeCachePullRule{handshakeTypeServerHelloDone, false},
+			handshakeCachePullRule{handshake.TypeServerHello, false},
+			handshakeCachePullRule{handshake.TypeCertificate, false},
+			handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+			handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+			handshakeCachePullRule{handshake.TypeServerHelloDone, false},
 		)
 		// We don't have enough data to even assert validity
 		if expectedMessages[0] == nil {
-			return &alert{alertLevelFatal, alertHandshakeFailure}, nil
+			return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, nil
 		}
 
 		expectedSeqnum := expectedMessages[0].messageSequence
 		for i, msg := range expectedMessages {
 			switch {
-			// handshakeTypeCertificate and handshakeTypeServerKeyExchange can be nil
+			// handshake.TypeCertificate and handshake.TypeServerKeyExchange can be nil
 			// when doing PSK
 			case c.localPSKCallback != nil && (i == 1 || i == 2) && msg == nil:
 				continue
-			// handshakeMessageCertificateRequest can be nil
+			// handshake.MessageCertificateRequest can be nil
 			case i == 3 && msg == nil:
 				continue
 			case msg == nil:
@@ -225,10 +288,12 @@ func clientHandshakeHandler(c *Conn) (*alert, error) {
 		}
 
 		c.handshakeMessageSequence++
-		c.currFlight.set(flight5)
+		if err := c.currFlight.set(flight5); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
 	case flight5:
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeFinished, false},
+			handshakeCachePullRule{handshake.TypeFinished, false},
 		)
 
 		if expectedMessages[0] == nil {
@@ -237,18 +302,18 @@ func clientHandshakeHandler(c *Conn) (*alert, error) {
 			return alertPtr, err
 		}
 
-		c.setLocalEpoch(1)
+		c.setLocalEpoch(c.handshakeEpoch + 1)
 		c.handshakeMessageSequence = 1
-		atomic.StoreUint64(&c.state.localSequenceNumber, 1)
+		c.finishRenegotiation()
 		c.handshakeDoneSignal.Close()
 	default:
-		return &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("client asked to handle unknown flight (%d)", c.currFlight.get())
+		return &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("client asked to handle unknown flight (%d)", c.currFlight.get())
 	}
 
 	return nil, nil
 }
 
-func clientFlightHandler(c *Conn) (bool, *alert, error) {
+func clientFlightHandler(c *Conn) (bool, *alert.Alert, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -256,74 +321,100 @@ func clientFlightHandler(c *Conn) (bool, *alert, error) {
 	case flight1:
 		fallthrough
 	case flight3:
-		extensions := []extension{
-			&extensionSupportedSignatureAlgorithms{
-				signatureHashAlgorithms: []signatureHashAlgorithm{
-					{hashAlgorithmSHA256, signatureAlgorithmECDSA},
-					{hashAlgorithmSHA384, signatureAlgorithmECDSA},
-					{hashAlgorithmSHA512, signatureAlgorithmECDSA},
-					{hashAlgorithmSHA256, signatureAlgorithmRSA},
-					{hashAlgorithmSHA384, signatureAlgorithmRSA},
-					{hashAlgorithmSHA512, signatureAlgorithmRSA},
+		extensions := []extension.Extension{
+			&extension.SupportedSignatureAlgorithms{
+				SignatureHashAlgorithms: []signaturehash.Algorithm{
+					{Hash: hash.SHA256, Signature: signature.ECDSA},
+					{Hash: hash.SHA384, Signature: signature.ECDSA},
+					{Hash: hash.SHA512, Signature: signature.ECDSA},
+					{Hash: hash.SHA256, Signature: signature.RSA},
+					{Hash: hash.SHA384, Signature: signature.RSA},
+					{Hash: hash.SHA512, Signature: signature.RSA},
 				},
 			},
 		}
 		if c.localPSKCallback == nil {
-			extensions = append(extensions, []extension{
-				&extensionSupportedEllipticCurves{
-					ellipticCurves: []namedCurve{namedCurveX25519, namedCurveP256, namedCurveP384},
+			extensions = append(extensions, []extension.Extension{
+				&extension.SupportedEllipticCurves{
+					EllipticCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384},
 				},
-				&extensionSupportedPointFormats{
-					pointFormats: []ellipticCurvePointFormat{ellipticCurvePointFormatUncompressed},
+				&extension.SupportedPointFormats{
+					PointFormats: []extension.EllipticCurvePointFormat{extension.EllipticCurvePointFormatUncompressed},
 				},
 			}...)
 		}
 
 		if len(c.localSRTPProtectionProfiles) > 0 {
-			extensions = append(extensions, &extensionUseSRTP{
-				protectionProfiles: c.localSRTPProtectionProfiles,
+			extensions = append(extensions, &extension.UseSRTP{
+				ProtectionProfiles:  c.localSRTPProtectionProfiles,
+				MasterKeyIdentifier: c.localSRTPMasterKeyIdentifier,
 			})
 		}
 
 		if c.extendedMasterSecret == RequestExtendedMasterSecret ||
 			c.extendedMasterSecret == RequireExtendedMasterSecret {
-			extensions = append(extensions, &extensionUseExtendedMasterSecret{
-				supported: true,
+			extensions = append(extensions, &extension.UseExtendedMasterSecret{
+				Supported: true,
 			})
 		}
 
 		if len(c.serverName) > 0 {
-			extensions = append(extensions, &extensionServerName{serverName: c.serverName})
+			extensions = append(extensions, &extension.ServerName{ServerName: c.serverName})
+		}
+
+		extensions = append(extensions, &extension.RenegotiationInfo{
+			RenegotiatedConnection: c.clientVerifyData,
+		})
+
+		if len(c.localNextProtos) > 0 {
+			extensions = append(extensions, &extension.ALPN{
+				ProtocolNameList: c.localNextProtos,
+			})
+		}
+
+		for _, s := range c.localCipherSuites {
+			if _, ok := s.(encryptThenMACCipherSuite); ok {
+				extensions = append(extensions, &extension.EncryptThenMAC{
+					Supported: true,
+				})
+				break
+			}
+		}
+
+		for i := range c.localHelloExtensions {
+			extensions = append(extensions, &c.localHelloExtensions[i])
 		}
 
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(c.handshakeMessageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(c.handshakeMessageSequence),
 					},
-					handshakeMessage: &handshakeMessageClientHello{
-						version:            protocolVersion1_2,
-						cookie:             c.cookie,
-						random:             c.state.localRandom,
-						cipherSuites:       c.localCipherSuites,
-						compressionMethods: defaultCompressionMethods,
-						extensions:         extensions,
+					Message: &handshake.MessageClientHello{
+						Version:            protocol.Version1_2,
+						Cookie:             c.cookie,
+						Random:             c.state.localRandom,
+						CipherSuiteIDs:     cipherSuiteIDs(c.localCipherSuites),
+						CompressionMethods: defaultCompressionMethods(),
+						Extensions:         extensions,
 					}},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 	case flight5:
 		// TODO: Better way to end handshake
-		if c.getRemoteEpoch() != 0 && c.getLocalEpoch() == 1 {
+		if c.getRemoteEpoch() > c.handshakeEpoch && c.getLocalEpoch() > c.handshakeEpoch {
 			// Handshake is done
 			return true, nil, nil
 		}
@@ -333,7 +424,7 @@ func clientFlightHandler(c *Conn) (bool, *alert, error) {
 		if len(c.localCertificates) > 0 {
 			certificate, err := c.getCertificate(c.serverName)
 			if err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 			certBytes = certificate.Certificate
 			privateKey = certificate.PrivateKey
@@ -342,74 +433,78 @@ func clientFlightHandler(c *Conn) (bool, *alert, error) {
 		messageSequence := c.handshakeMessageSequence
 		if c.remoteRequestedCertificate {
 			if err := c.bufferPacket(&packet{
-				record: &recordLayer{
-					recordLayerHeader: recordLayerHeader{
-						protocolVersion: protocolVersion1_2,
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Epoch:   c.handshakeEpoch,
+						Version: protocol.Version1_2,
 					},
-					content: &handshake{
-						handshakeHeader: handshakeHeader{
-							messageSequence: uint16(messageSequence),
+					Content: &handshake.Handshake{
+						Header: handshake.Header{
+							MessageSequence: uint16(messageSequence),
 						},
-						handshakeMessage: &handshakeMessageCertificate{
-							certificate: certBytes,
+						Message: &handshake.MessageCertificate{
+							Certificate: certBytes,
 						}},
 				},
+				shouldEncrypt: c.handshakeEpoch != 0,
 			}); err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 
 			messageSequence++
 		}
 
-		clientKeyExchange := &handshakeMessageClientKeyExchange{}
+		clientKeyExchange := &handshake.MessageClientKeyExchange{}
 		if c.localPSKCallback == nil {
-			clientKeyExchange.publicKey = c.localKeypair.publicKey
+			clientKeyExchange.PublicKey = c.localKeypair.PublicKey
 		} else {
-			clientKeyExchange.identityHint = c.localPSKIdentityHint
+			clientKeyExchange.IdentityHint = c.localPSKIdentityHint
 		}
 
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(messageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(messageSequence),
 					},
-					handshakeMessage: clientKeyExchange,
+					Message: clientKeyExchange,
 				},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		messageSequence++
 
 		serverKeyExchangeData := c.handshakeCache.pullAndMerge(
-			handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
+			handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
 		)
 
-		serverKeyExchange := &handshakeMessageServerKeyExchange{}
+		serverKeyExchange := &handshake.MessageServerKeyExchange{}
 
-		// handshakeMessageServerKeyExchange is optional for PSK
+		// handshake.MessageServerKeyExchange is optional for PSK
 		if len(serverKeyExchangeData) == 0 {
-			alertPtr, err := handleServerKeyExchange(c, &handshakeMessageServerKeyExchange{})
+			alertPtr, err := handleServerKeyExchange(c, &handshake.MessageServerKeyExchange{})
 			if err != nil {
 				return false, alertPtr, err
 			}
 		} else {
-			rawHandshake := &handshake{}
+			rawHandshake := &handshake.Handshake{}
 			err := rawHandshake.Unmarshal(serverKeyExchangeData)
 			if err != nil {
-				return false, &alert{alertLevelFatal, alertUnexpectedMessage}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, err
 			}
 
-			switch h := rawHandshake.handshakeMessage.(type) {
-			case *handshakeMessageServerKeyExchange:
+			switch h := rawHandshake.Message.(type) {
+			case *handshake.MessageServerKeyExchange:
 				serverKeyExchange = h
 			default:
-				return false, &alert{alertLevelFatal, alertUnexpectedMessage}, errInvalidContentType
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, errInvalidContentType
 			}
 		}
 
@@ -423,106 +518,109 @@ func clientFlightHandler(c *Conn) (bool, *alert, error) {
 		if c.remoteRequestedCertificate && len(c.localCertificates) > 0 {
 			if len(c.localCertificatesVerify) == 0 {
 				plainText := c.handshakeCache.pullAndMerge(
-					handshakeCachePullRule{handshakeTypeClientHello, true},
-					handshakeCachePullRule{handshakeTypeServerHello, false},
-					handshakeCachePullRule{handshakeTypeCertificate, false},
-					handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-					handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-					handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-					handshakeCachePullRule{handshakeTypeCertificate, true},
-					handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
+					handshakeCachePullRule{handshake.TypeClientHello, true},
+					handshakeCachePullRule{handshake.TypeServerHello, false},
+					handshakeCachePullRule{handshake.TypeCertificate, false},
+					handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+					handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+					handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+					handshakeCachePullRule{handshake.TypeCertificate, true},
+					handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
 				)
 
-				certVerify, err := generateCertificateVerify(plainText, privateKey)
+				certVerify, err := generateCertificateVerify(plainText, privateKey, c.rand)
 				if err != nil {
-					return false, &alert{alertLevelFatal, alertInternalError}, err
+					return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 				c.localCertificatesVerify = certVerify
 			}
 
 			if err := c.bufferPacket(&packet{
-				record: &recordLayer{
-					recordLayerHeader: recordLayerHeader{
-						protocolVersion: protocolVersion1_2,
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Epoch:   c.handshakeEpoch,
+						Version: protocol.Version1_2,
 					},
-					content: &handshake{
-						handshakeHeader: handshakeHeader{
-							messageSequence: uint16(messageSequence),
+					Content: &handshake.Handshake{
+						Header: handshake.Header{
+							MessageSequence: uint16(messageSequence),
 						},
-						handshakeMessage: &handshakeMessageCertificateVerify{
-							hashAlgorithm:      hashAlgorithmSHA256,
-							signatureAlgorithm: signatureAlgorithmECDSA,
-							signature:          c.localCertificatesVerify,
+						Message: &handshake.MessageCertificateVerify{
+							HashAlgorithm:      hash.SHA256,
+							SignatureAlgorithm: signature.ECDSA,
+							Signature:          c.localCertificatesVerify,
 						}},
 				},
+				shouldEncrypt: c.handshakeEpoch != 0,
 			}); err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 
 			messageSequence++
 		}
 
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &changeCipherSpec{},
+				Content: &protocol.ChangeCipherSpec{},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if len(c.localVerifyData) == 0 {
 			plainText := c.handshakeCache.pullAndMerge(
-				handshakeCachePullRule{handshakeTypeClientHello, true},
-				handshakeCachePullRule{handshakeTypeServerHello, false},
-				handshakeCachePullRule{handshakeTypeCertificate, false},
-				handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-				handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-				handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-				handshakeCachePullRule{handshakeTypeCertificate, true},
-				handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
-				handshakeCachePullRule{handshakeTypeCertificateVerify, true},
+				handshakeCachePullRule{handshake.TypeClientHello, true},
+				handshakeCachePullRule{handshake.TypeServerHello, false},
+				handshakeCachePullRule{handshake.TypeCertificate, false},
+				handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+				handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+				handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+				handshakeCachePullRule{handshake.TypeCertificate, true},
+				handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
+				handshakeCachePullRule{handshake.TypeCertificateVerify, true},
 			)
 
 			var err error
 			c.localVerifyData, err = prfVerifyDataClient(c.state.masterSecret, plainText, c.state.cipherSuite.hashFunc())
 			if err != nil {
-				return false, &alert{alertLevelFatal, alertInternalError}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
 		}
 
 		// TODO: Fix hard-coded epoch, taking retransmitting into account.
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					epoch:           1,
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch + 1,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(messageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(messageSequence),
 					},
-					handshakeMessage: &handshakeMessageFinished{
-						verifyData: c.localVerifyData,
+					Message: &handshake.MessageFinished{
+						VerifyData: c.localVerifyData,
 					}},
 			},
-			shouldEncrypt:            true,
-			resetLocalSequenceNumber: true,
+			shouldEncrypt: true,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 	default:
-		return false, &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("unhandled flight %s", c.currFlight.get())
+		return false, &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("unhandled flight %s", c.currFlight.get())
 	}
 	return false, nil, nil
 }
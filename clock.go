@@ -0,0 +1,74 @@
+package dtls
+
+import (
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/deadline"
+)
+
+// Clock is the source of time of a connection. It can be replaced to run
+// the retransmission logic under a simulated clock.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// NewTicker returns a Ticker sending the time on its channel
+	// after each period d
+	NewTicker(d time.Duration) Ticker
+
+	// NewTimer returns a Timer sending the time on its channel after d
+	NewTimer(d time.Duration) Timer
+}
+
+// Ticker is a ticker created by a Clock, see time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is a timer created by a Clock, see time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// realClock is the Clock of the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// deadlineClock adapts a Clock to the timers of the deadline package
+type deadlineClock struct {
+	Clock
+}
+
+func (c deadlineClock) NewTimer(d time.Duration) deadline.Timer {
+	return c.Clock.NewTimer(d)
+}
@@ -0,0 +1,242 @@
+package dtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	mathRand "math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/transport/test"
+	"golang.org/x/crypto/ed25519"
+)
+
+// fakeClock is a Clock which only advances when told to
+type fakeClock struct {
+	lock    sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+type fakeTicker struct {
+	clock  *fakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+	done   bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	t.done = true
+}
+
+// fakeTimer fires once, it is a ticker stopped after the first tick
+type fakeTimer struct {
+	*fakeTicker
+}
+
+func (t fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	wasActive := !t.done
+	t.done = true
+	return wasActive
+}
+
+func (t fakeTimer) Reset(time.Duration) bool {
+	panic("not implemented")
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return fakeTimer{t}
+}
+
+// Advance moves the clock forward and fires the tickers which are due
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		for !t.done && !t.next.After(c.now) {
+			select {
+			case t.c <- c.now:
+			default: // Like time.Ticker, drop ticks for slow receivers
+			}
+			if t.period == 0 {
+				t.done = true
+				break
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+// recordingConn records all datagrams written to it
+type recordingConn struct {
+	net.Conn
+	lock    sync.Mutex
+	written [][]byte
+}
+
+func (c *recordingConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	c.written = append(c.written, append([]byte{}, b...))
+	c.lock.Unlock()
+	return c.Conn.Write(b)
+}
+
+func TestDeterministicHandshake(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := selfsign.SelfSign(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both sides write the same datagrams when the handshake is
+	// run twice with the same Rand and Clock
+	handshake := func() [][]byte {
+		ca, cb := dpipe.Pipe()
+		clientConn := &recordingConn{Conn: ca}
+		serverConn := &recordingConn{Conn: cb}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		type result struct {
+			c   *Conn
+			err error
+		}
+		c := make(chan result)
+		go func() {
+			client, err := ClientWithContext(ctx, clientConn, &Config{
+				CipherSuites:       []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+				InsecureSkipVerify: true,
+				Rand:               mathRand.New(mathRand.NewSource(1)),
+				Clock:              newFakeClock(),
+			})
+			c <- result{client, err}
+		}()
+		server, err := ServerWithContext(ctx, serverConn, &Config{
+			Certificates: []tls.Certificate{cert},
+			Rand:         mathRand.New(mathRand.NewSource(2)),
+			Clock:        newFakeClock(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := <-c
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		_ = res.c.Close()
+		_ = server.Close()
+
+		clientConn.lock.Lock()
+		defer clientConn.lock.Unlock()
+		serverConn.lock.Lock()
+		defer serverConn.lock.Unlock()
+		return append(clientConn.written, serverConn.written...)
+	}
+
+	first := handshake()
+	second := handshake()
+	if len(first) != len(second) {
+		t.Fatalf("Transcript length mismatch: %d != %d", len(first), len(second))
+	}
+	for i := range first {
+		if !bytes.Equal(first[i], second[i]) {
+			t.Errorf("Datagram %d mismatch:\n%x\n%x", i, first[i], second[i])
+		}
+	}
+}
+
+func TestSimulatedClockRetransmission(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := dpipe.Pipe()
+	clock := newFakeClock()
+	client, err := NewClient(ca, &Config{InsecureSkipVerify: true, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handshakeErr := make(chan error)
+	go func() {
+		handshakeErr <- client.Handshake(ctx)
+	}()
+
+	readClientHello := func() []byte {
+		buf := make([]byte, 8192)
+		n, err := cb.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf[:n]
+	}
+	first := readClientHello()
+
+	// Nothing is retransmitted until the clock passes the flight interval
+	if err = cb.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cb.Read(make([]byte, 8192)); err == nil {
+		t.Fatal("ClientHello was retransmitted before the flight interval")
+	}
+	if err = cb.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(initialTickerInterval)
+	retransmitted := readClientHello()
+	if !bytes.Equal(first[13:], retransmitted[13:]) {
+		t.Errorf("Retransmitted ClientHello mismatch:\n%x\n%x", first, retransmitted)
+	}
+
+	cancel()
+	if err = <-handshakeErr; err != errConnectTimeout {
+		t.Errorf("Handshake: expected(%v) actual(%v)", errConnectTimeout, err)
+	}
+}
@@ -3,12 +3,15 @@ package dtls
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"io"
 	"time"
 
 	"golang.org/x/crypto/ed25519"
 
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 	"github.com/pion/logging"
 )
 
@@ -29,6 +32,13 @@ type Config struct {
 	// Servers will assert that clients send one of these profiles and will respond as needed
 	SRTPProtectionProfiles []SRTPProtectionProfile
 
+	// SRTPMasterKeyIdentifier is the SRTP Master Key Identifier (MKI) offered
+	// by clients in use_srtp, signalling that MKI will be used in SRTP packets.
+	// Servers accept MKI by echoing the value offered by the client, so this is
+	// ignored by servers. The negotiated value is available from
+	// Conn.SRTPMasterKeyIdentifier.
+	SRTPMasterKeyIdentifier []byte
+
 	// ClientAuth determines the server's policy for
 	// TLS Client Authentication. The default is NoClientCert.
 	ClientAuth ClientAuthType
@@ -95,12 +105,86 @@ type Config struct {
 	// MTU is the length at which handshake messages will be fragmented to
 	// fit within the maximum transmission unit (default is 1200 bytes)
 	MTU int
+
+	// Renegotiation determines if renegotiation is allowed on an established
+	// connection. When disabled (the default) Conn.Renegotiate fails and
+	// renegotiations requested by the remote are refused with a no_renegotiation
+	// warning. Only secure renegotiation (RFC 5746) is ever performed.
+	Renegotiation RenegotiationSupport
+
+	// RekeyThreshold is the number of records sent with the same keys after
+	// which a renegotiation is started to replace them. If Renegotiation is
+	// disabled or the remote refuses, writes fail with ErrRekeyRequired
+	// instead. If Renegotiation is enabled the default depends on the cipher
+	// suite, 2^24 records for AES-GCM, 2^23.5 for AES-CCM and 2^47 for the
+	// others. Otherwise the keys are used until the sequence numbers run out.
+	RekeyThreshold uint64
+
+	// NextProtos is a list of supported application level protocols, in
+	// order of preference, negotiated with the ALPN extension (RFC 7301).
+	// The server picks the first of its protocols offered by the client and
+	// aborts the handshake with a no_application_protocol alert if there is
+	// none, WebRTC uses "webrtc" and "c-webrtc" (RFC 8833).
+	NextProtos []string
+
+	// Rand provides the source of entropy for randoms, cookies, key
+	// exchanges, signatures and the explicit IVs and nonces of records. If
+	// nil, crypto/rand is used. Some signature algorithms of the Go standard
+	// library do not use it deterministically.
+	Rand io.Reader
+
+	// Clock provides the current time and the timers used for
+	// retransmissions and deadlines. If nil, the time package is used.
+	Clock Clock
+
+	// OnHandshakeStateChange, if not nil, is called each time the handshake
+	// moves to another HandshakeState, including when a renegotiation starts
+	// over. The time spent in each state helps to find where stuck handshakes
+	// are waiting. It is called from the goroutines running the handshake and
+	// must not block or call methods of the Conn.
+	OnHandshakeStateChange func(HandshakeStateChange)
+
+	// OnWarningAlert, if not nil, is called with the warning alerts received
+	// from the remote, such as no_renegotiation or unsupported_certificate.
+	// They don't close the connection, except for a user_canceled during the
+	// handshake. Fatal alerts are returned as an AlertError instead. It is
+	// called from the goroutine reading from the connection and must not block.
+	OnWarningAlert func(AlertDescription)
+
+	// HelloExtensions, if not nil, returns raw extensions added to the
+	// ClientHello or ServerHello we send, to negotiate capabilities this
+	// package doesn't implement. Clients call it once per handshake with nil,
+	// servers with the extensions of the ClientHello they reply to which this
+	// package doesn't implement. The handshake fails if it returns a type this
+	// package implements. Servers only reply to extensions the client offered,
+	// others are dropped.
+	HelloExtensions func(remote []extension.Raw) []extension.Raw
+
+	// OnHelloExtensions, if not nil, is called with the extensions of the
+	// remote's ClientHello or ServerHello which this package doesn't implement,
+	// before the reply to it is built. If it returns an error the handshake is
+	// aborted with a handshake_failure alert.
+	OnHelloExtensions func(remote []extension.Raw) error
 }
 
 func defaultConnectContextMaker() (context.Context, func()) {
 	return context.WithTimeout(context.Background(), 30*time.Second)
 }
 
+func (c *Config) rand() io.Reader {
+	if c.Rand == nil {
+		return rand.Reader
+	}
+	return c.Rand
+}
+
+func (c *Config) clock() Clock {
+	if c.Clock == nil {
+		return realClock{}
+	}
+	return c.Clock
+}
+
 func (c *Config) connectContextMaker() (context.Context, func()) {
 	if c.ConnectContextMaker == nil {
 		return defaultConnectContextMaker()
@@ -138,6 +222,16 @@ const (
 	DisableExtendedMasterSecret
 )
 
+// RenegotiationSupport declares the policy the client and server will
+// follow for renegotiating an established connection
+type RenegotiationSupport int
+
+// RenegotiationSupport enums
+const (
+	RenegotiateNever RenegotiationSupport = iota
+	RenegotiateFreely
+)
+
 func validateConfig(config *Config) error {
 	switch {
 	case config == nil:
@@ -148,6 +242,16 @@ func validateConfig(config *Config) error {
 		return errIdentityNoPSK
 	}
 
+	if len(config.SRTPMasterKeyIdentifier) > 255 {
+		return errSRTPMasterKeyIdentifierTooLong
+	}
+
+	for _, proto := range config.NextProtos {
+		if len(proto) == 0 || len(proto) > 255 {
+			return errInvalidNextProto
+		}
+	}
+
 	for _, cert := range config.Certificates {
 		if cert.Certificate == nil {
 			return errInvalidCertificate
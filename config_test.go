@@ -65,6 +65,15 @@ func TestValidateConfig(t *testing.T) {
 		t.Fatalf("TestValidateConfig: Client error exp(%v) failed(%v)", errInvalidCertificate, err)
 	}
 
+	//Empty application protocol
+	config = &Config{
+		CipherSuites: []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		NextProtos:   []string{"webrtc", ""},
+	}
+	if err = validateConfig(config); err != errInvalidNextProto {
+		t.Fatalf("TestValidateConfig: Client error exp(%v) failed(%v)", errInvalidNextProto, err)
+	}
+
 	//Invalid cipher suites
 	config = &Config{CipherSuites: []CipherSuiteID{0x0000}}
 	if err = validateConfig(config); err == nil {
@@ -2,7 +2,6 @@ package dtls
 
 import (
 	"context"
-	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -15,14 +14,24 @@ import (
 
 	"github.com/pion/dtls/v2/internal/closer"
 	"github.com/pion/dtls/v2/internal/net/deadline"
+	"github.com/pion/dtls/v2/internal/net/udp"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 	"github.com/pion/logging"
+	"golang.org/x/xerrors"
 )
 
 const (
 	initialTickerInterval = time.Second
 	cookieLength          = 20
-	defaultNamedCurve     = namedCurveX25519
-	inboundBufferSize     = 8192
+	defaultNamedCurve     = elliptic.X25519
+	maxEarlyRecords       = 16 // Records kept which arrived before the keys of their epoch
+	maxDecryptedRecords   = 16 // Records of application data queued for Read
+	defaultRekeyThreshold = 1 << 47
 )
 
 var invalidKeyingLabels = map[string]bool{
@@ -32,17 +41,19 @@ var invalidKeyingLabels = map[string]bool{
 	"key expansion":   true,
 }
 
-type handshakeMessageHandler func(*Conn) (*alert, error)
-type flightHandler func(*Conn) (bool, *alert, error)
+type handshakeMessageHandler func(*Conn) (*alert.Alert, error)
+type flightHandler func(*Conn) (bool, *alert.Alert, error)
 
 // Conn represents a DTLS connection
 type Conn struct {
-	lock           sync.RWMutex    // Internal lock (must not be public)
-	nextConn       net.Conn        // Embedded Conn, typically a udpconn we read/write from
-	fragmentBuffer *fragmentBuffer // out-of-order and missing fragment handling
-	handshakeCache *handshakeCache // caching of handshake messages for verifyData generation
-	decrypted      chan []byte     // Decrypted Application Data, pull by calling `Read`
-	workerTicker   *time.Ticker
+	invalidRecords uint64 // Records dropped by inboundLoop, accessed atomically and first for 64-bit alignment
+
+	lock           sync.RWMutex       // Internal lock (must not be public)
+	nextConn       net.Conn           // Embedded Conn, typically a udpconn we read/write from
+	fragmentBuffer *fragmentBuffer    // out-of-order and missing fragment handling
+	handshakeCache *handshakeCache    // caching of handshake messages for verifyData generation
+	decrypted      chan inboundRecord // Decrypted Application Data, pull by calling `Read` or `ReadBuffer`
+	workerTicker   Ticker             // Drives retransmissions, nil if driven by an Engine
 
 	state State // Internal state
 
@@ -50,16 +61,20 @@ type Conn struct {
 
 	remoteRequestedCertificate bool // Did we get a CertificateRequest
 
-	localSRTPProtectionProfiles []SRTPProtectionProfile // Available SRTPProtectionProfiles, if empty no SRTP support
-	localCipherSuites           []cipherSuite           // Available CipherSuites, if empty use default list
+	localSRTPProtectionProfiles  []SRTPProtectionProfile // Available SRTPProtectionProfiles, if empty no SRTP support
+	localNextProtos              []string                // Available application protocols, if empty no ALPN support
+	localSRTPMasterKeyIdentifier []byte                  // SRTP MKI offered by the client, if empty MKI is not used
+	localCipherSuites            []cipherSuite           // Available CipherSuites, if empty use default list
 
 	clientAuth           ClientAuthType           // If we are a client should we request a client certificate
 	extendedMasterSecret ExtendedMasterSecretType // Policy for the Extended Master Support extension
+	renegotiation        RenegotiationSupport     // Policy for renegotiating an established connection
+	rekeyThreshold       uint64                   // Records sent in an epoch before a rekey is started, 0 to use the cipher suite's limit
 
 	currFlight        *flight
-	namedCurve        namedCurve
+	namedCurve        elliptic.Curve
 	localCertificates []tls.Certificate
-	localKeypair      *namedCurveKeypair
+	localKeypair      *elliptic.Keypair
 	cookie            []byte
 
 	localPSKCallback     PSKCallback
@@ -75,35 +90,127 @@ type Conn struct {
 	rootCAs               *x509.CertPool
 	clientCAs             *x509.CertPool
 	serverName            string
+	onWarningAlert        func(alert.Description)
+	helloExtensions       func([]extension.Raw) []extension.Raw
+	onHelloExtensions     func([]extension.Raw) error
+
+	localHelloExtensions []extension.Raw // Raw extensions of the hello we send, see Config.HelloExtensions
 
 	handshakeMessageSequence       int
 	handshakeMessageHandler        handshakeMessageHandler
 	flightHandler                  flightHandler
 	handshakeDoneSignal            *closer.Closer
+	handshakeMutex                 sync.Mutex // Serializes calls to Handshake
+	handshakeStarted               int32      // Set atomically once the handshake goroutines run
 	handshakeCompletedSuccessfully atomic.Value
+	handshakeEpoch                 uint16 // Epoch the handshake in progress is protected with
+	connectContextMaker            func() (context.Context, func())
+
+	secureRenegotiation bool             // Remote signaled RFC 5746 support
+	renegotiating       bool             // A handshake is in progress on an established connection
+	helloRequestPending bool             // Server sent HelloRequest and is waiting for a ClientHello
+	clientVerifyData    []byte           // verify_data of the last client Finished, used by renegotiation_info
+	serverVerifyData    []byte           // verify_data of the last server Finished, used by renegotiation_info
+	prevLocalRandom     handshake.Random // Restored if the remote refuses to renegotiate
+	renegotiationErr    *atomicError     // Error if the remote refused to renegotiate
+
+	epochCipherSuitesLock sync.RWMutex
+	epochCipherSuites     map[uint16]cipherSuite // CipherSuites of previous epochs, used while renegotiating
 
 	bufferedPackets []*packet
 
 	connectionClosed *closer.Closer // Closed on connection close and unblock read
+	closeNotifyOnce  sync.Once      // close_notify is sent at most once
 	handshakeErr     *atomicError   // Error if one occurred during handshake
 	readErr          *atomicError   // Error if one occurred in inboundLoop
 
+	writesLock sync.Mutex
+	writes     sync.WaitGroup // In-flight calls to Write
+	closing    bool           // Set by CloseWithContext, new writes are refused
+
 	readDeadline  *deadline.Deadline
 	writeDeadline *deadline.Deadline
 
 	log logging.LeveledLogger
 
+	rand  io.Reader // Source of entropy, see Config.Rand
+	clock Clock     // Source of time, see Config.Clock
+
+	// Hooks of the driver of the connection, either the goroutines of Conn
+	// reading from and writing to nextConn, or an Engine
+	sendDatagrams     func([][]byte) error
+	deliverData       func([]byte)
+	inbound           *udp.Buffer   // Pooled buffer of the datagram being handled, if any
+	inboundRecords    [][]byte      // Records of the datagram being handled, reused
+	earlyRecords      [][]byte      // Records received before the keys of their epoch, see maxEarlyRecords
+	sansIO            bool          // Driven by an Engine, no goroutines are started
+	flightInterval    time.Duration // Interval of retransmissions
+	flightsRunning    bool          // Sans-IO state of the handshake worker
+	nextFlightTimeout time.Time     // Sans-IO deadline of the next retransmission
+
 	nameToCertificate map[string]*tls.Certificate
 }
 
-func createConn(ctx context.Context, nextConn net.Conn, flightHandler flightHandler, handshakeMessageHandler handshakeMessageHandler, config *Config, isClient bool) (*Conn, error) {
-	err := validateConfig(config)
+// batchWriter is implemented by connections which write several datagrams
+// with a single syscall, such as the Conns of the UDP Listener
+type batchWriter interface {
+	WriteBatch(datagrams [][]byte) (int, error)
+}
+
+// bufferReader is implemented by connections which hand out the pooled
+// buffer a datagram was received in, such as the Conns of the UDP Listener
+type bufferReader interface {
+	ReadBuffer() (*udp.Buffer, error)
+}
+
+func createConn(nextConn net.Conn, flightHandler flightHandler, handshakeMessageHandler handshakeMessageHandler, config *Config, isClient bool) (*Conn, error) {
+	if nextConn == nil {
+		return nil, errNilNextConn
+	}
+
+	c, err := createCore(nextConn, flightHandler, handshakeMessageHandler, config, isClient)
 	if err != nil {
 		return nil, err
 	}
 
-	if nextConn == nil {
-		return nil, errNilNextConn
+	c.workerTicker = c.clock.NewTicker(c.flightInterval)
+	c.sendDatagrams = func(datagrams [][]byte) error {
+		// Flights of several datagrams are written with a single syscall
+		// if nextConn supports it
+		if w, ok := c.nextConn.(batchWriter); ok && len(datagrams) > 1 {
+			_, err := w.WriteBatch(datagrams)
+			return err
+		}
+		for _, b := range datagrams {
+			if _, err := c.nextConn.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	c.deliverData = func(b []byte) {
+		// The record is decrypted in place, the buffer is released by the reader
+		record := inboundRecord{data: b, buf: c.inbound}
+		if record.buf != nil {
+			record.buf.Retain()
+		}
+		select {
+		case c.decrypted <- record:
+		case <-c.connectionClosed.Done():
+			if record.buf != nil {
+				record.buf.Release()
+			}
+		}
+	}
+	return c, nil
+}
+
+// createCore creates the state of a connection without starting any I/O,
+// nextConn is nil if the connection is driven by an Engine
+func createCore(nextConn net.Conn, flightHandler flightHandler, handshakeMessageHandler handshakeMessageHandler, config *Config, isClient bool) (*Conn, error) {
+	err := validateConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
 	cipherSuites, err := parseCipherSuites(config.CipherSuites, config.PSK == nil, config.PSK != nil)
@@ -128,6 +235,11 @@ func createConn(ctx context.Context, nextConn net.Conn, flightHandler flightHand
 		mtu = defaultMTU
 	}
 
+	rekeyThreshold := config.RekeyThreshold
+	if rekeyThreshold > recordlayer.MaxSequenceNumber {
+		rekeyThreshold = defaultRekeyThreshold
+	}
+
 	handshakeDoneSignal := closer.NewCloser()
 	connectionClosed := closer.NewCloser()
 
@@ -151,43 +263,55 @@ func createConn(ctx context.Context, nextConn net.Conn, flightHandler flightHand
 	}
 
 	c := &Conn{
-		nextConn:                    nextConn,
-		currFlight:                  newFlight(isClient, logger),
-		fragmentBuffer:              newFragmentBuffer(),
-		handshakeCache:              newHandshakeCache(),
-		handshakeMessageHandler:     handshakeMessageHandler,
-		flightHandler:               flightHandler,
-		maximumTransmissionUnit:     mtu,
-		localCertificates:           config.Certificates,
-		nameToCertificate:           nameToCertificate,
-		clientAuth:                  config.ClientAuth,
-		extendedMasterSecret:        config.ExtendedMasterSecret,
-		insecureSkipVerify:          config.InsecureSkipVerify,
-		verifyPeerCertificate:       config.VerifyPeerCertificate,
-		rootCAs:                     config.RootCAs,
-		clientCAs:                   config.ClientCAs,
-		serverName:                  config.ServerName,
-		localSRTPProtectionProfiles: config.SRTPProtectionProfiles,
-		localCipherSuites:           cipherSuites,
-		namedCurve:                  defaultNamedCurve,
+		nextConn:                     nextConn,
+		currFlight:                   newFlight(isClient, config.clock(), config.OnHandshakeStateChange, logger),
+		fragmentBuffer:               newFragmentBuffer(),
+		handshakeCache:               newHandshakeCache(),
+		handshakeMessageHandler:      handshakeMessageHandler,
+		flightHandler:                flightHandler,
+		maximumTransmissionUnit:      mtu,
+		localCertificates:            config.Certificates,
+		nameToCertificate:            nameToCertificate,
+		clientAuth:                   config.ClientAuth,
+		extendedMasterSecret:         config.ExtendedMasterSecret,
+		renegotiation:                config.Renegotiation,
+		rekeyThreshold:               rekeyThreshold,
+		connectContextMaker:          config.connectContextMaker,
+		insecureSkipVerify:           config.InsecureSkipVerify,
+		verifyPeerCertificate:        config.VerifyPeerCertificate,
+		rootCAs:                      config.RootCAs,
+		clientCAs:                    config.ClientCAs,
+		serverName:                   config.ServerName,
+		onWarningAlert:               config.OnWarningAlert,
+		helloExtensions:              config.HelloExtensions,
+		onHelloExtensions:            config.OnHelloExtensions,
+		localSRTPProtectionProfiles:  config.SRTPProtectionProfiles,
+		localNextProtos:              config.NextProtos,
+		localSRTPMasterKeyIdentifier: config.SRTPMasterKeyIdentifier,
+		localCipherSuites:            cipherSuites,
+		namedCurve:                   defaultNamedCurve,
 
 		localPSKCallback:     config.PSK,
 		localPSKIdentityHint: config.PSKIdentityHint,
 
-		decrypted:           make(chan []byte),
-		workerTicker:        time.NewTicker(workerInterval),
+		decrypted:           make(chan inboundRecord, maxDecryptedRecords),
+		flightInterval:      workerInterval,
+		rand:                config.rand(),
+		clock:               config.clock(),
 		handshakeDoneSignal: handshakeDoneSignal,
 		connectionClosed:    connectionClosed,
 		log:                 logger,
 		handshakeErr:        &atomicError{},
 		readErr:             &atomicError{},
+		renegotiationErr:    &atomicError{},
+		epochCipherSuites:   map[uint16]cipherSuite{},
 
-		readDeadline:  deadline.New(),
-		writeDeadline: deadline.New(),
+		readDeadline:  deadline.NewWithClock(deadlineClock{config.clock()}),
+		writeDeadline: deadline.NewWithClock(deadlineClock{config.clock()}),
 	}
 
 	// Use host from conn address when serverName is not provided
-	if isClient && c.serverName == "" && nextConn.RemoteAddr() != nil {
+	if isClient && c.serverName == "" && nextConn != nil && nextConn.RemoteAddr() != nil {
 		remoteAddr := nextConn.RemoteAddr().String()
 		var host string
 		host, _, err = net.SplitHostPort(remoteAddr)
@@ -202,22 +326,51 @@ func createConn(ctx context.Context, nextConn net.Conn, flightHandler flightHand
 	c.state.remoteEpoch.Store(zeroEpoch)
 	c.state.isClient = isClient
 
-	if err = c.state.localRandom.populate(); err != nil {
+	if err = c.state.localRandom.Populate(c.clock.Now(), c.rand); err != nil {
 		return nil, err
 	}
-	if !isClient {
+	if isClient {
+		if err = c.populateLocalHelloExtensions(nil); err != nil {
+			return nil, err
+		}
+	} else {
 		c.cookie = make([]byte, cookieLength)
-		if _, err = rand.Read(c.cookie); err != nil {
+		if _, err = io.ReadFull(c.rand, c.cookie); err != nil {
 			return nil, err
 		}
 	}
 
-	// Trigger outbound
-	c.startHandshakeOutbound()
+	return c, nil
+}
 
-	// Handle inbound
-	go c.inboundLoop()
+// Handshake runs the DTLS handshake, unless it has already been run.
+// Most uses of this package need not call Handshake explicitly, the first
+// Read or Write will call it automatically using ConnectContextMaker in
+// the Config. If the handshake fails or ctx is done first, the connection
+// is closed and the error is returned by all later calls.
+func (c *Conn) Handshake(ctx context.Context) error {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
 
+	if err := c.handshakeErr.load(); err != nil {
+		return err
+	}
+	if c.isHandshakeCompletedSuccessfully() {
+		return nil
+	}
+	if c.connectionClosed.Err() != nil {
+		return ErrConnClosed
+	}
+
+	if atomic.CompareAndSwapInt32(&c.handshakeStarted, 0, 1) {
+		// Trigger outbound
+		c.startHandshakeOutbound()
+
+		// Handle inbound
+		go c.inboundLoop()
+	}
+
+	var err error
 	select {
 	case <-c.handshakeDoneSignal.Done():
 		err = c.handshakeErr.load()
@@ -235,7 +388,49 @@ func createConn(ctx context.Context, nextConn net.Conn, flightHandler flightHand
 
 	c.log.Trace(fmt.Sprintf("Handshake Completed (Error: %v)", err))
 
-	return c, err
+	return err
+}
+
+// CancelHandshake aborts the handshake in progress. The remote is sent a
+// user_canceled alert followed by a close_notify and the connection is
+// closed, Handshake returns ErrHandshakeCanceled. An established
+// connection is closed with Close instead.
+func (c *Conn) CancelHandshake() error {
+	if err := c.cancelHandshake(); err != nil {
+		return err
+	}
+	return c.close()
+}
+
+// cancelHandshake notifies the remote and fails the handshake, the caller
+// closes the connection
+func (c *Conn) cancelHandshake() error {
+	switch {
+	case c.isHandshakeCompletedSuccessfully():
+		return errHandshakeComplete
+	case c.connectionClosed.Err() != nil:
+		return ErrConnClosed
+	}
+
+	if atomic.LoadInt32(&c.handshakeStarted) == 1 && c.handshakeErr.load() == nil {
+		if err := c.notify(alert.Warning, alert.UserCanceled); err != nil {
+			return err
+		}
+		c.sendCloseNotify()
+	}
+	c.handshakeErr.store(ErrHandshakeCanceled)
+	return nil
+}
+
+// handshakeIfNeeded runs the handshake on the first Read or Write
+func (c *Conn) handshakeIfNeeded() error {
+	if c.isHandshakeCompletedSuccessfully() {
+		return nil
+	}
+
+	ctx, cancel := c.connectContextMaker()
+	defer cancel()
+	return c.Handshake(ctx)
 }
 
 // Dial connects to the given network address and establishes a DTLS connection on top.
@@ -279,6 +474,32 @@ func DialWithContext(ctx context.Context, network string, raddr *net.UDPAddr, co
 
 // ClientWithContext establishes a DTLS connection over an existing connection.
 func ClientWithContext(ctx context.Context, conn net.Conn, config *Config) (*Conn, error) {
+	c, err := NewClient(conn, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Handshake(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ServerWithContext listens for incoming DTLS connections.
+func ServerWithContext(ctx context.Context, conn net.Conn, config *Config) (*Conn, error) {
+	c, err := NewServer(conn, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Handshake(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient returns a new DTLS client connection over an existing
+// connection without running the handshake. The handshake is run by
+// Handshake or by the first Read or Write.
+func NewClient(conn net.Conn, config *Config) (*Conn, error) {
 	switch {
 	case config == nil:
 		return nil, errNoConfigProvided
@@ -286,11 +507,13 @@ func ClientWithContext(ctx context.Context, conn net.Conn, config *Config) (*Con
 		return nil, errPSKAndIdentityMustBeSetForClient
 	}
 
-	return createConn(ctx, conn, clientFlightHandler, clientHandshakeHandler, config, true)
+	return createConn(conn, clientFlightHandler, clientHandshakeHandler, config, true)
 }
 
-// ServerWithContext listens for incoming DTLS connections.
-func ServerWithContext(ctx context.Context, conn net.Conn, config *Config) (*Conn, error) {
+// NewServer returns a new DTLS server connection over an existing
+// connection without running the handshake. The handshake is run by
+// Handshake or by the first Read or Write.
+func NewServer(conn net.Conn, config *Config) (*Conn, error) {
 	switch {
 	case config == nil:
 		return nil, errNoConfigProvided
@@ -298,7 +521,7 @@ func ServerWithContext(ctx context.Context, conn net.Conn, config *Config) (*Con
 		return nil, errServerMustHaveCertificate
 	}
 
-	return createConn(ctx, conn, serverFlightHandler, serverHandshakeHandler, config, false)
+	return createConn(conn, serverFlightHandler, serverHandshakeHandler, config, false)
 }
 
 func (c *Conn) getCertificate(serverName string) (*tls.Certificate, error) {
@@ -338,31 +561,55 @@ func (c *Conn) getCertificate(serverName string) (*tls.Certificate, error) {
 
 // Read reads data from the connection.
 func (c *Conn) Read(p []byte) (n int, err error) {
-	var out []byte
-	var ok bool
+	if err := c.handshakeIfNeeded(); err != nil {
+		return 0, err
+	}
+
+	out, err := c.readRecord()
+	if err != nil {
+		return 0, err
+	}
+	defer out.Release()
+
+	data := out.Bytes()
+	if len(p) < len(data) {
+		return 0, errBufferTooSmall
+	}
+	copy(p, data)
+	return len(data), nil
+}
+
+// ReadBuffer reads the next record of application data without copying
+// it, the returned RecordBuffer must be released once the data was used.
+// Holding on to it keeps the buffer of the received datagram from being
+// reused.
+func (c *Conn) ReadBuffer() (RecordBuffer, error) {
+	if err := c.handshakeIfNeeded(); err != nil {
+		return RecordBuffer{}, err
+	}
+	return c.readRecord()
+}
+
+func (c *Conn) readRecord() (RecordBuffer, error) {
 	select {
-	case out, ok = <-c.decrypted:
+	case out, ok := <-c.decrypted:
 		// inboundLoop has closed but error has not been set yet
 		if !ok {
 			if err := c.handshakeErr.load(); err != nil {
-				return 0, err
+				return RecordBuffer{}, err
 			}
 			if c.connectionClosed.Err() != nil {
-				return 0, io.EOF
+				return RecordBuffer{}, io.EOF
 			}
 			if err := c.readErr.load(); err != nil {
-				return 0, err
+				return RecordBuffer{}, err
 			}
-			return 0, io.EOF
+			return RecordBuffer{}, io.EOF
 		}
+		return RecordBuffer{record: out}, nil
 	case <-c.readDeadline.Done():
-		return 0, context.DeadlineExceeded
-	}
-	if len(p) < len(out) {
-		return 0, errBufferTooSmall
+		return RecordBuffer{}, context.DeadlineExceeded
 	}
-	copy(p, out)
-	return len(out), nil
 }
 
 // Write writes len(p) bytes from p to the DTLS connection
@@ -379,29 +626,46 @@ func (c *Conn) Write(p []byte) (int, error) {
 	if c.connectionClosed.Err() != nil {
 		return 0, ErrConnClosed
 	}
-	if !c.isHandshakeCompletedSuccessfully() {
-		return 0, errHandshakeInProgress
+	if err := c.handshakeIfNeeded(); err != nil {
+		return 0, err
+	}
+
+	if err := c.beginWrite(); err != nil {
+		return 0, err
 	}
+	defer c.writes.Done()
 
+	if err := c.writeApplicationData(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeApplicationData encrypts and sends p once the handshake is complete
+func (c *Conn) writeApplicationData(p []byte) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if err := c.rekeyIfNeeded(); err != nil {
+		return err
+	}
+
 	if err := c.bufferPacket(&packet{
-		record: &recordLayer{
-			recordLayerHeader: recordLayerHeader{
-				epoch:           c.getLocalEpoch(),
-				protocolVersion: protocolVersion1_2,
+		record: &recordlayer.RecordLayer{
+			Header: recordlayer.Header{
+				Epoch:   c.getLocalEpoch(),
+				Version: protocol.Version1_2,
 			},
-			content: &applicationData{
-				data: p,
+			Content: &protocol.ApplicationData{
+				Data: p,
 			},
 		},
 		shouldEncrypt: true,
 	}); err != nil {
-		return 0, err
+		return err
 	}
 
-	return len(p), c.flushPacketBuffer()
+	return c.flushPacketBuffer()
 }
 
 // Close closes the connection.
@@ -409,6 +673,78 @@ func (c *Conn) Close() error {
 	return c.close()
 }
 
+// CloseWithContext gracefully closes the connection. New writes are
+// refused, writes in progress are completed and a close_notify is sent
+// before the connection is closed. ctx.Err() is returned if the writes
+// did not complete before ctx is done.
+func (c *Conn) CloseWithContext(ctx context.Context) error {
+	return c.closeWithContext(ctx, false)
+}
+
+// CloseAndWait gracefully closes the connection like CloseWithContext, and
+// then waits for the close_notify of the remote until ctx is done. The
+// connection is closed in any case, ctx.Err() is returned if the remote
+// did not respond in time.
+func (c *Conn) CloseAndWait(ctx context.Context) error {
+	return c.closeWithContext(ctx, true)
+}
+
+func (c *Conn) closeWithContext(ctx context.Context, waitRemote bool) error {
+	if c.connectionClosed.Err() != nil || !c.isHandshakeCompletedSuccessfully() {
+		return c.close()
+	}
+
+	c.writesLock.Lock()
+	c.closing = true
+	c.writesLock.Unlock()
+
+	writesDone := make(chan struct{})
+	go func() {
+		c.writes.Wait()
+		close(writesDone)
+	}()
+
+	select {
+	case <-writesDone:
+	case <-ctx.Done():
+		_ = c.close()
+		return ctx.Err()
+	}
+
+	c.sendCloseNotify()
+	if !waitRemote {
+		return c.close()
+	}
+
+	// The close_notify of the remote closes the connection
+	select {
+	case <-c.connectionClosed.Done():
+		return nil
+	case <-ctx.Done():
+		_ = c.close()
+		return ctx.Err()
+	}
+}
+
+// beginWrite registers a Write in progress, it fails once
+// CloseWithContext has been called
+func (c *Conn) beginWrite() error {
+	c.writesLock.Lock()
+	defer c.writesLock.Unlock()
+
+	if c.closing {
+		return ErrConnClosed
+	}
+	c.writes.Add(1)
+	return nil
+}
+
+func (c *Conn) sendCloseNotify() {
+	c.closeNotifyOnce.Do(func() {
+		_ = c.notify(alert.Warning, alert.CloseNotify)
+	})
+}
+
 // RemoteCertificate exposes the remote certificate
 func (c *Conn) RemoteCertificate() [][]byte {
 	c.lock.RLock()
@@ -428,17 +764,78 @@ func (c *Conn) SelectedSRTPProtectionProfile() (SRTPProtectionProfile, bool) {
 	return c.state.srtpProtectionProfile, true
 }
 
+// SRTPMasterKeyIdentifier returns the SRTP Master Key Identifier negotiated
+// with use_srtp, or nil if MKI is not used
+func (c *Conn) SRTPMasterKeyIdentifier() []byte {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]byte{}, c.state.srtpMasterKeyIdentifier...)
+}
+
+// SRTPKeyingMaterial derives the SRTP master keys and salts from
+// ExportKeyingMaterial, with the lengths of the selected SRTPProtectionProfile
+// https://tools.ietf.org/html/rfc5764#section-4.2
+func (c *Conn) SRTPKeyingMaterial() (*SRTPKeyingMaterial, error) {
+	profile, ok := c.SelectedSRTPProtectionProfile()
+	if !ok {
+		return nil, errNoSRTPProtectionProfile
+	}
+	keyLen, err := profile.KeyLen()
+	if err != nil {
+		return nil, err
+	}
+	saltLen, err := profile.SaltLen()
+	if err != nil {
+		return nil, err
+	}
+
+	keyingMaterial, err := c.ExportKeyingMaterial(labelExtractorDtlsSrtp, nil, (keyLen*2)+(saltLen*2))
+	if err != nil {
+		return nil, err
+	}
+
+	// client_write_SRTP_master_key | server_write_SRTP_master_key |
+	// client_write_SRTP_master_salt | server_write_SRTP_master_salt
+	offset := 0
+	next := func(n int) []byte {
+		b := keyingMaterial[offset : offset+n]
+		offset += n
+		return b
+	}
+	clientKey, serverKey := next(keyLen), next(keyLen)
+	clientSalt, serverSalt := next(saltLen), next(saltLen)
+
+	if c.state.isClient {
+		return &SRTPKeyingMaterial{clientKey, clientSalt, serverKey, serverSalt}, nil
+	}
+	return &SRTPKeyingMaterial{serverKey, serverSalt, clientKey, clientSalt}, nil
+}
+
+// NegotiatedProtocol returns the application protocol selected with ALPN,
+// or an empty string if none was negotiated
+func (c *Conn) NegotiatedProtocol() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.state.negotiatedProtocol
+}
+
 // ExportKeyingMaterial from https://tools.ietf.org/html/rfc5705
 // This allows protocols to use DTLS for key establishment, but
-// then use some of the keying material for their own purposes
+// then use some of the keying material for their own purposes.
+// As in crypto/tls, a nil context is not included in the derivation while
+// an empty non-nil context is, the two produce different keying material.
+// Earlier releases ignored an empty context, callers relying on that must
+// now pass nil to keep deriving the same keying material.
 func (c *Conn) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if c.getLocalEpoch() == 0 {
+	if c.getLocalEpoch() == 0 || c.renegotiating {
 		return nil, errHandshakeInProgress
-	} else if len(context) != 0 {
-		return nil, errContextUnsupported
+	} else if len(context) > 0xffff {
+		return nil, errContextTooLong
 	} else if _, ok := invalidKeyingLabels[label]; ok {
 		return nil, errReservedExportKeyingMaterial
 	}
@@ -458,18 +855,361 @@ func (c *Conn) ExportKeyingMaterial(label string, context []byte, length int) ([
 	} else {
 		seed = append(append(seed, remoteRandom...), localRandom...)
 	}
+	if context != nil {
+		seed = append(seed, byte(len(context)>>8), byte(len(context)))
+		seed = append(seed, context...)
+	}
 	return prfPHash(c.state.masterSecret, seed, length, c.state.cipherSuite.hashFunc())
 }
 
+// Renegotiate performs a new handshake on an established connection, replacing
+// the keys and the negotiated parameters. Both sides must allow it with
+// Config.Renegotiation and the remote must support RFC 5746. Application data
+// keeps flowing with the previous keys until the new handshake is finished.
+// Renegotiation will timeout using ConnectContextMaker in the Config.
+// If you want to specify the timeout duration, use RenegotiateWithContext() instead.
+func (c *Conn) Renegotiate() error {
+	ctx, cancel := c.connectContextMaker()
+	defer cancel()
+
+	return c.RenegotiateWithContext(ctx)
+}
+
+// RenegotiateWithContext performs a new handshake on an established connection.
+func (c *Conn) RenegotiateWithContext(ctx context.Context) error {
+	if err := c.handshakeErr.load(); err != nil {
+		return err
+	}
+	if c.connectionClosed.Err() != nil {
+		return ErrConnClosed
+	}
+	if !c.isHandshakeCompletedSuccessfully() {
+		return errHandshakeInProgress
+	}
+
+	c.lock.Lock()
+	if err := c.requestRenegotiation(); err != nil {
+		c.lock.Unlock()
+		return err
+	}
+	handshakeDoneSignal := c.handshakeDoneSignal
+	c.lock.Unlock()
+
+	select {
+	case <-handshakeDoneSignal.Done():
+	case <-ctx.Done():
+		c.lock.Lock()
+		helloRequestPending := c.helloRequestPending
+		c.helloRequestPending = false
+		c.lock.Unlock()
+
+		if helloRequestPending {
+			// The client never answered, the current keys are still valid
+			handshakeDoneSignal.Close()
+			return errConnectTimeout
+		}
+
+		c.handshakeErr.store(errConnectTimeout)
+		c.close() // nolint
+		return errConnectTimeout
+	}
+
+	if err := c.handshakeErr.load(); err != nil {
+		return err
+	}
+	if err := c.renegotiationErr.load(); err != nil {
+		return err
+	}
+	if c.connectionClosed.Err() != nil {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// requestRenegotiation starts a new handshake as the client, or asks the
+// client to start one as the server. Must be called with c.lock held.
+func (c *Conn) requestRenegotiation() error {
+	switch {
+	case c.renegotiation != RenegotiateFreely:
+		return errRenegotiationDisabled
+	case !c.secureRenegotiation:
+		return errRenegotiationUnsupported
+	case c.renegotiating || c.helloRequestPending:
+		return errRenegotiationInProgress
+	}
+
+	c.renegotiationErr.store(nil)
+	if c.state.isClient {
+		return c.startRenegotiation()
+	}
+
+	// Ask the client to start a new handshake, see serverFlightHandler
+	c.helloRequestPending = true
+	c.handshakeDoneSignal = closer.NewCloser()
+	if err := c.currFlight.set(flight0); err != nil {
+		return err
+	}
+	c.startHandshakeOutbound()
+	return nil
+}
+
+// rekeyIfNeeded starts a renegotiation once the current epoch protected
+// rekeyThreshold records. If the keys can't be replaced the connection fails
+// closed instead of using them past the limit. Must be called with c.lock held.
+func (c *Conn) rekeyIfNeeded() error {
+	sequenceNumber := c.localSequenceNumber(c.getLocalEpoch())
+	switch {
+	case sequenceNumber < c.getRekeyThreshold():
+		return nil
+	case sequenceNumber >= recordlayer.MaxSequenceNumber:
+		return ErrRekeyRequired
+	case c.renegotiating || c.helloRequestPending:
+		return nil // Keep using the current keys until the new ones are ready
+	case c.renegotiationErr.load() != nil:
+		return ErrRekeyRequired
+	}
+
+	if err := c.requestRenegotiation(); err != nil {
+		c.log.Debugf("rekey failed: %s", err)
+		return ErrRekeyRequired
+	}
+	return nil
+}
+
+// getRekeyThreshold returns the number of records sent in an epoch before a
+// rekey is started, derived from the cipher suite unless set by the Config.
+// Without renegotiation the keys are used until the sequence numbers run out.
+// Must be called with c.lock held.
+func (c *Conn) getRekeyThreshold() uint64 {
+	if c.rekeyThreshold != 0 {
+		return c.rekeyThreshold
+	}
+	if c.renegotiation != RenegotiateFreely {
+		return recordlayer.MaxSequenceNumber
+	}
+	if cipherSuite, ok := c.state.cipherSuite.(recordLimitCipherSuite); ok {
+		return cipherSuite.recordLimit()
+	}
+	return defaultRekeyThreshold
+}
+
+// localSequenceNumber returns the number of records sent in the given epoch.
+// Must be called with c.lock held.
+func (c *Conn) localSequenceNumber(epoch uint16) uint64 {
+	if int(epoch) >= len(c.state.localSequenceNumber) {
+		return 0
+	}
+	return c.state.localSequenceNumber[epoch]
+}
+
+// nextLocalSequenceNumber returns the sequence number of the next record sent
+// in the given epoch. Must be called with c.lock held.
+func (c *Conn) nextLocalSequenceNumber(epoch uint16) uint64 {
+	for int(epoch) >= len(c.state.localSequenceNumber) {
+		c.state.localSequenceNumber = append(c.state.localSequenceNumber, 0)
+	}
+	sequenceNumber := c.state.localSequenceNumber[epoch]
+	c.state.localSequenceNumber[epoch]++
+	return sequenceNumber
+}
+
+// startRenegotiation resets the handshake state so a new handshake can run
+// protected by the current epoch. Must be called with c.lock held.
+func (c *Conn) startRenegotiation() error {
+	c.epochCipherSuitesLock.Lock()
+	c.epochCipherSuites[c.getLocalEpoch()] = c.state.cipherSuite
+	c.epochCipherSuitesLock.Unlock()
+
+	c.prevLocalRandom = c.state.localRandom
+	if err := c.state.localRandom.Populate(c.clock.Now(), c.rand); err != nil {
+		return err
+	}
+
+	if c.state.isClient {
+		if err := c.populateLocalHelloExtensions(nil); err != nil {
+			return err
+		}
+	}
+
+	c.handshakeEpoch = c.getLocalEpoch()
+	c.renegotiating = true
+	c.fragmentBuffer = newFragmentBuffer()
+	c.handshakeCache = newHandshakeCache()
+
+	c.localKeypair = nil
+	c.localCertificatesVerify = nil
+	c.localVerifyData = nil
+	c.localKeySignature = nil
+	c.remoteRequestedCertificate = false
+	c.remoteCertificateVerified = false
+	c.state.remoteCertificate = nil
+	c.state.extendedMasterSecret = false
+	c.state.encryptThenMAC = false
+	c.state.negotiatedProtocol = ""
+	c.state.srtpProtectionProfile = 0
+	c.state.srtpMasterKeyIdentifier = nil
+
+	nextFlight := flight0
+	c.handshakeMessageSequence = 0
+	if c.state.isClient {
+		c.cookie = nil
+		nextFlight = flight1
+	} else if c.helloRequestPending {
+		// HelloRequest already used message_seq 0
+		c.handshakeMessageSequence = 1
+	}
+	if err := c.currFlight.set(nextFlight); err != nil {
+		return err
+	}
+
+	if c.helloRequestPending {
+		// RenegotiateWithContext is already waiting on the outbound loop
+		c.helloRequestPending = false
+		return nil
+	}
+
+	c.handshakeDoneSignal = closer.NewCloser()
+	c.startHandshakeOutbound()
+	return nil
+}
+
+// populateLocalHelloExtensions asks the Config for the raw extensions of the
+// next hello we send, servers pass those of the ClientHello they reply to
+func (c *Conn) populateLocalHelloExtensions(remote []extension.Raw) error {
+	c.localHelloExtensions = nil
+	if c.helloExtensions == nil {
+		return nil
+	}
+
+	for _, e := range c.helloExtensions(remote) {
+		if extension.Implemented(e.Type) {
+			return xerrors.Errorf("%w: %d", errHelloExtensionImplemented, e.Type)
+		}
+		// Servers must not reply with extensions the client didn't offer
+		if !c.state.isClient && !containsHelloExtension(remote, e.Type) {
+			c.log.Debugf("dropping hello extension %d the client did not offer", e.Type)
+			continue
+		}
+		c.localHelloExtensions = append(c.localHelloExtensions, e)
+	}
+	return nil
+}
+
+func containsHelloExtension(extensions []extension.Raw, t extension.TypeValue) bool {
+	for _, e := range extensions {
+		if e.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// offeredHelloExtension returns true if the hello we sent carried a raw
+// extension of type t
+func (c *Conn) offeredHelloExtension(t extension.TypeValue) bool {
+	return containsHelloExtension(c.localHelloExtensions, t)
+}
+
+// handleRemoteHelloExtensions returns the extensions of the remote's hello
+// which this package doesn't implement, after passing them to the Config
+func (c *Conn) handleRemoteHelloExtensions(extensions []extension.Extension) ([]extension.Raw, error) {
+	var remote []extension.Raw
+	for _, e := range extensions {
+		if raw, ok := e.(*extension.Raw); ok {
+			remote = append(remote, *raw)
+		}
+	}
+
+	if c.onHelloExtensions != nil {
+		if err := c.onHelloExtensions(remote); err != nil {
+			return nil, err
+		}
+	}
+	return remote, nil
+}
+
+// abortRenegotiation handles a no_renegotiation warning from the remote.
+func (c *Conn) abortRenegotiation() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch {
+	case c.helloRequestPending:
+		c.helloRequestPending = false
+	case c.renegotiating && c.state.isClient && c.currFlight.get() == flight1:
+		c.state.localRandom = c.prevLocalRandom
+		c.handshakeEpoch = c.getLocalEpoch() - 1
+		c.renegotiating = false
+		if err := c.currFlight.set(flight5); err != nil {
+			c.log.Errorf("abortRenegotiation: %v", err)
+		}
+	default:
+		return
+	}
+
+	c.renegotiationErr.store(errRenegotiationRefused)
+	c.handshakeDoneSignal.Close()
+}
+
+// finishRenegotiation drops the keys of epochs that can no longer be
+// used by the remote. Must be called with c.lock held.
+func (c *Conn) finishRenegotiation() {
+	c.renegotiating = false
+
+	c.epochCipherSuitesLock.Lock()
+	defer c.epochCipherSuitesLock.Unlock()
+	for epoch := range c.epochCipherSuites {
+		if epoch < c.handshakeEpoch {
+			delete(c.epochCipherSuites, epoch)
+		}
+	}
+}
+
+// isRenegotiationStart returns true if buf is the first message of a
+// handshake started by the remote on an established connection.
+// Must be called with c.lock held.
+func (c *Conn) isRenegotiationStart(h *recordlayer.Header, buf []byte) bool {
+	if !c.isHandshakeCompletedSuccessfully() || c.renegotiating ||
+		h.Epoch == 0 || h.Epoch != c.getRemoteEpoch() || len(buf) <= recordlayer.HeaderSize {
+		return false
+	}
+
+	header := &handshake.Header{}
+	if err := header.Unmarshal(buf[recordlayer.HeaderSize:]); err != nil {
+		return false
+	}
+
+	if c.state.isClient {
+		return header.Type == handshake.TypeHelloRequest
+	}
+	return header.Type == handshake.TypeClientHello && header.MessageSequence == 0
+}
+
+func (c *Conn) renegotiationAllowed() bool {
+	return c.renegotiation == RenegotiateFreely && c.secureRenegotiation
+}
+
+// cipherSuiteForEpoch returns the CipherSuite protecting records of the given
+// epoch, previous epochs are kept around until renegotiation is finished
+func (c *Conn) cipherSuiteForEpoch(epoch uint16) cipherSuite {
+	c.epochCipherSuitesLock.RLock()
+	defer c.epochCipherSuitesLock.RUnlock()
+
+	if cipherSuite, ok := c.epochCipherSuites[epoch]; ok {
+		return cipherSuite
+	}
+	return c.state.cipherSuite
+}
+
 func (c *Conn) bufferPacket(p *packet) error {
-	if h, ok := p.record.content.(*handshake); ok {
+	if h, ok := p.record.Content.(*handshake.Handshake); ok {
 		handshakeRaw, err := p.record.Marshal()
 		if err != nil {
 			return err
 		}
 
-		c.log.Tracef("[handshake] -> %s", h.handshakeHeader.handshakeType.String())
-		c.handshakeCache.push(handshakeRaw[recordLayerHeaderSize:], h.handshakeHeader.messageSequence, h.handshakeHeader.handshakeType, c.state.isClient)
+		c.log.Tracef("[handshake] -> %s", h.Header.Type.String())
+		c.handshakeCache.push(handshakeRaw[recordlayer.HeaderSize:], h.Header.MessageSequence, h.Header.Type, c.state.isClient)
 	}
 
 	c.bufferedPackets = append(c.bufferedPackets, p)
@@ -481,11 +1221,7 @@ func (c *Conn) flushPacketBuffer() error {
 	var rawPackets [][]byte
 
 	for _, p := range c.bufferedPackets {
-		if p.resetLocalSequenceNumber {
-			atomic.StoreUint64(&c.state.localSequenceNumber, 0)
-		}
-
-		if h, ok := p.record.content.(*handshake); ok {
+		if h, ok := p.record.Content.(*handshake.Handshake); ok {
 			rawHandshakePackets, err := c.processHandshakePacket(p, h)
 			if err != nil {
 				return err
@@ -503,15 +1239,7 @@ func (c *Conn) flushPacketBuffer() error {
 	}
 
 	c.bufferedPackets = []*packet{}
-	compactedRawPackets := c.compactRawPackets(rawPackets)
-
-	for _, compactedRawPackets := range compactedRawPackets {
-		if _, err := c.nextConn.Write(compactedRawPackets); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return c.sendDatagrams(c.compactRawPackets(rawPackets))
 }
 
 func (c *Conn) compactRawPackets(rawPackets [][]byte) [][]byte {
@@ -532,8 +1260,7 @@ func (c *Conn) compactRawPackets(rawPackets [][]byte) [][]byte {
 }
 
 func (c *Conn) processPacket(p *packet) ([]byte, error) {
-	p.record.recordLayerHeader.sequenceNumber = atomic.LoadUint64(&c.state.localSequenceNumber)
-	atomic.AddUint64(&c.state.localSequenceNumber, 1)
+	p.record.Header.SequenceNumber = c.nextLocalSequenceNumber(p.record.Header.Epoch)
 
 	rawPacket, err := p.record.Marshal()
 	if err != nil {
@@ -542,7 +1269,7 @@ func (c *Conn) processPacket(p *packet) ([]byte, error) {
 
 	if p.shouldEncrypt {
 		var err error
-		rawPacket, err = c.state.cipherSuite.encrypt(p.record, rawPacket)
+		rawPacket, err = c.cipherSuiteForEpoch(p.record.Header.Epoch).encrypt(p.record, rawPacket, c.rand)
 		if err != nil {
 			return nil, err
 		}
@@ -551,7 +1278,7 @@ func (c *Conn) processPacket(p *packet) ([]byte, error) {
 	return rawPacket, nil
 }
 
-func (c *Conn) processHandshakePacket(p *packet, h *handshake) ([][]byte, error) {
+func (c *Conn) processHandshakePacket(p *packet, h *handshake.Handshake) ([][]byte, error) {
 	rawPackets := make([][]byte, 0)
 
 	handshakeFragments, err := c.fragmentHandshake(h)
@@ -560,16 +1287,14 @@ func (c *Conn) processHandshakePacket(p *packet, h *handshake) ([][]byte, error)
 	}
 
 	for _, handshakeFragment := range handshakeFragments {
-		recordLayerHeader := &recordLayerHeader{
-			contentType:     p.record.recordLayerHeader.contentType,
-			contentLen:      uint16(len(handshakeFragment)),
-			protocolVersion: p.record.recordLayerHeader.protocolVersion,
-			epoch:           p.record.recordLayerHeader.epoch,
-			sequenceNumber:  atomic.LoadUint64(&c.state.localSequenceNumber),
+		recordLayerHeader := &recordlayer.Header{
+			ContentType:    p.record.Header.ContentType,
+			ContentLen:     uint16(len(handshakeFragment)),
+			Version:        p.record.Header.Version,
+			Epoch:          p.record.Header.Epoch,
+			SequenceNumber: c.nextLocalSequenceNumber(p.record.Header.Epoch),
 		}
 
-		atomic.AddUint64(&c.state.localSequenceNumber, 1)
-
 		recordLayerHeaderBytes, err := recordLayerHeader.Marshal()
 		if err != nil {
 			return nil, err
@@ -578,7 +1303,7 @@ func (c *Conn) processHandshakePacket(p *packet, h *handshake) ([][]byte, error)
 		rawPacket := append(recordLayerHeaderBytes, handshakeFragment...)
 		if p.shouldEncrypt {
 			var err error
-			rawPacket, err = c.state.cipherSuite.encrypt(p.record, rawPacket)
+			rawPacket, err = c.cipherSuiteForEpoch(recordLayerHeader.Epoch).encrypt(&recordlayer.RecordLayer{Header: *recordLayerHeader, Content: p.record.Content}, rawPacket, c.rand)
 			if err != nil {
 				return nil, err
 			}
@@ -590,8 +1315,8 @@ func (c *Conn) processHandshakePacket(p *packet, h *handshake) ([][]byte, error)
 	return rawPackets, nil
 }
 
-func (c *Conn) fragmentHandshake(h *handshake) ([][]byte, error) {
-	content, err := h.handshakeMessage.Marshal()
+func (c *Conn) fragmentHandshake(h *handshake.Handshake) ([][]byte, error) {
+	content, err := h.Message.Marshal()
 	if err != nil {
 		return nil, err
 	}
@@ -609,12 +1334,12 @@ func (c *Conn) fragmentHandshake(h *handshake) ([][]byte, error) {
 	for _, contentFragment := range contentFragments {
 		contentFragmentLen := len(contentFragment)
 
-		handshakeHeaderFragment := &handshakeHeader{
-			handshakeType:   h.handshakeHeader.handshakeType,
-			length:          h.handshakeHeader.length,
-			messageSequence: h.handshakeHeader.messageSequence,
-			fragmentOffset:  uint32(offset),
-			fragmentLength:  uint32(contentFragmentLen),
+		handshakeHeaderFragment := &handshake.Header{
+			Type:            h.Header.Type,
+			Length:          h.Header.Length,
+			MessageSequence: h.Header.MessageSequence,
+			FragmentOffset:  uint32(offset),
+			FragmentLength:  uint32(contentFragmentLen),
 		}
 
 		offset += contentFragmentLen
@@ -636,74 +1361,206 @@ func (c *Conn) inboundLoop() {
 		close(c.decrypted)
 	}()
 
-	b := make([]byte, inboundBufferSize)
 	for {
-		i, err := c.nextConn.Read(b)
+		b, err := c.readDatagram()
 		if err != nil {
 			c.readErr.store(err)
 			return
 		}
 
-		pkts, err := unpackDatagram(b[:i])
+		err = c.handleDatagram(b.Bytes(), b)
+		b.Release()
 		if err != nil {
 			c.readErr.store(err)
 			return
 		}
+	}
+}
 
-		for _, p := range pkts {
-			alert, err := c.handleIncomingPacket(p)
-			if alert != nil {
-				if alertErr := c.notify(alert.alertLevel, alert.alertDescription); alertErr != nil {
-					err = fmt.Errorf("%v %v", err, alertErr)
-				}
-			}
-			if err != nil {
-				c.readErr.store(err)
-				return
-			}
+// readDatagram returns the next datagram of nextConn in a pooled buffer,
+// which is only copied if nextConn does not receive in pooled buffers itself
+func (c *Conn) readDatagram() (*udp.Buffer, error) {
+	if r, ok := c.nextConn.(bufferReader); ok {
+		return r.ReadBuffer()
+	}
+	b := udp.GetBuffer()
+	if err := b.Fill(c.nextConn); err != nil {
+		b.Release()
+		return nil, err
+	}
+	return b, nil
+}
+
+// handleDatagram processes all records of a received datagram, which are
+// decrypted in place. Application data delivered from the pooled buffer
+// owner retains it, owner is nil if the caller owns the datagram. An error
+// is returned if the connection can no longer be used.
+func (c *Conn) handleDatagram(buf []byte, owner *udp.Buffer) error {
+	c.inbound = owner
+	defer func() {
+		c.inbound = nil
+	}()
+
+	pkts, err := recordlayer.AppendDatagramRecords(c.inboundRecords[:0], buf)
+	c.inboundRecords = pkts[:0]
+	if err != nil {
+		// Invalid datagrams are dropped, a spoofed one must not close
+		// the connection [RFC6347 Section 4.1.2.7]
+		c.dropInvalidRecord(err)
+		return nil
+	}
+
+	for _, p := range pkts {
+		if err := c.handlePacket(p); err != nil {
+			return err
 		}
 	}
+
+	c.inbound = nil
+	return c.replayEarlyRecords()
 }
 
-func (c *Conn) handleIncomingPacket(buf []byte) (*alert, error) {
+// replayEarlyRecords processes the queued records whose epoch keys were
+// installed since they arrived. The others stay queued, a replayed record
+// which fails to decrypt is dropped like any other invalid record.
+func (c *Conn) replayEarlyRecords() error {
+	var ready [][]byte
+	pending := c.earlyRecords[:0]
+	for _, p := range c.earlyRecords {
+		// The header was parsed before the record was queued
+		h := &recordlayer.Header{}
+		if err := h.Unmarshal(p); err == nil && c.hasEpochKeys(h.Epoch) {
+			ready = append(ready, p)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+	c.earlyRecords = pending
+
+	for _, p := range ready {
+		if err := c.handlePacket(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasEpochKeys reports whether the keys to decrypt records of epoch are
+// installed
+func (c *Conn) hasEpochKeys(epoch uint16) bool {
+	cipherSuite := c.cipherSuiteForEpoch(epoch)
+	return cipherSuite != nil && cipherSuite.isInitialized()
+}
+
+// handlePacket processes a record and sends the alert it caused, an error
+// is returned if the connection can no longer be used
+func (c *Conn) handlePacket(buf []byte) error {
+	alert, err := c.handleIncomingPacket(buf)
+	if alert != nil {
+		err = c.sendAlert(alert, err)
+	}
+	return err
+}
+
+// sendAlert sends the alert caused by err, which is wrapped in an
+// AlertError if the alert is fatal
+func (c *Conn) sendAlert(a *alert.Alert, err error) error {
+	if err != nil && a.Level == alert.Fatal {
+		err = &AlertError{Level: a.Level, Description: a.Description, Err: err}
+	}
+	if alertErr := c.notify(a.Level, a.Description); alertErr != nil {
+		if err == nil {
+			return xerrors.Errorf("dtls: failed to send alert: %w", alertErr)
+		}
+		return xerrors.Errorf("%w %v", err, alertErr)
+	}
+	return err
+}
+
+func (c *Conn) handleIncomingPacket(buf []byte) (*alert.Alert, error) {
 	// TODO: avoid separate unmarshal
-	h := &recordLayerHeader{}
+	h := &recordlayer.Header{}
 	if err := h.Unmarshal(buf); err != nil {
-		return &alert{alertLevelFatal, alertDecodeError}, err
+		c.dropInvalidRecord(err)
+		return nil, nil
 	}
 
-	if h.epoch < c.getRemoteEpoch() {
+	// Application data from the previous epoch is expected while renegotiating,
+	// anything else means the remote is still waiting on our last flight
+	if h.Epoch < c.getRemoteEpoch() && h.ContentType != protocol.ContentTypeApplicationData {
 		if _, alertPtr, err := c.flightHandler(c); err != nil {
 			return alertPtr, err
 		}
 	}
 
-	if h.epoch != 0 {
-		if c.state.cipherSuite == nil || !c.state.cipherSuite.isInitialized() {
-			c.log.Debug("handleIncoming: Handshake not finished, dropping packet")
+	if h.Epoch != 0 {
+		if !c.hasEpochKeys(h.Epoch) {
+			// The record is ahead of the handshake message which installs
+			// its keys, such as a Finished reordered before its flight.
+			// The keys of the current epoch are still missing if its
+			// ChangeCipherSpec was processed before the flight.
+			if remoteEpoch := c.getRemoteEpoch(); h.Epoch != remoteEpoch && h.Epoch != remoteEpoch+1 {
+				c.dropInvalidRecord(errUnexpectedEpoch)
+				return nil, nil
+			}
+			c.queueEarlyRecord(buf)
 			return nil, nil
 		}
 
 		var err error
-		buf, err = c.state.cipherSuite.decrypt(buf)
+		buf, err = c.cipherSuiteForEpoch(h.Epoch).decrypt(buf)
 		if err != nil {
-			c.log.Debugf("decrypt failed: %s", err)
+			c.dropInvalidRecord(err)
 			return nil, nil
 		}
 	}
 
-	isHandshake, err := c.fragmentBuffer.push(append([]byte{}, buf...))
+	if h.ContentType == protocol.ContentTypeHandshake {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.isRenegotiationStart(h, buf) {
+			if !c.renegotiationAllowed() {
+				c.log.Debug("handleIncoming: refusing renegotiation")
+				return &alert.Alert{Level: alert.Warning, Description: alert.NoRenegotiation}, nil
+			}
+			if err := c.startRenegotiation(); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			}
+		}
+	}
+
+	if h.ContentType == protocol.ContentTypeApplicationData {
+		// Application data is delivered without being copied
+		return c.handleApplicationData(h, buf[recordlayer.HeaderSize:])
+	}
+
+	// The fragmentBuffer copies the fragments it keeps
+	isHandshake, err := c.fragmentBuffer.push(buf)
 	if err != nil {
-		return &alert{alertLevelFatal, alertDecodeError}, err
+		if a := fragmentAlert(err); a != nil {
+			return a, err
+		}
+		c.dropInvalidRecord(err)
+		return nil, nil
 	} else if isHandshake {
 		newHandshakeMessage := false
 		for out := c.fragmentBuffer.pop(); out != nil; out = c.fragmentBuffer.pop() {
-			rawHandshake := &handshake{}
+			rawHandshake := &handshake.Handshake{}
 			if err := rawHandshake.Unmarshal(out); err != nil {
-				return &alert{alertLevelFatal, alertDecodeError}, err
+				if xerrors.Is(err, extension.ErrDuplicateExtension) {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.DecodeError}, err
+				}
+				c.dropInvalidRecord(err)
+				continue
 			}
 
-			if c.handshakeCache.push(out, rawHandshake.handshakeHeader.messageSequence, rawHandshake.handshakeHeader.handshakeType, !c.state.isClient) {
+			if !c.currFlight.expects(rawHandshake.Header.Type) {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage},
+					xerrors.Errorf("%w: %s in %s", errUnexpectedHandshakeMessage, rawHandshake.Header.Type, c.currFlight.get())
+			}
+
+			if c.handshakeCache.push(out, rawHandshake.Header.MessageSequence, rawHandshake.Header.Type, !c.state.isClient) {
 				newHandshakeMessage = true
 			}
 		}
@@ -711,67 +1568,129 @@ func (c *Conn) handleIncomingPacket(buf []byte) (*alert, error) {
 			return nil, nil
 		}
 
-		c.lock.Lock()
-		defer c.lock.Unlock()
 		return c.handshakeMessageHandler(c)
 	}
 
-	r := &recordLayer{}
+	r := &recordlayer.RecordLayer{}
 	if err := r.Unmarshal(buf); err != nil {
-		return &alert{alertLevelFatal, alertDecodeError}, err
+		c.dropInvalidRecord(err)
+		return nil, nil
 	}
 
-	switch content := r.content.(type) {
-	case *alert:
+	switch content := r.Content.(type) {
+	case *alert.Alert:
 		c.log.Tracef("<- %s", content.String())
-		if content.alertDescription == alertCloseNotify {
+		if h.Epoch == 0 && c.isHandshakeCompletedSuccessfully() {
+			// Once keys are established only authenticated alerts are trusted
+			c.dropInvalidRecord(fmt.Errorf("unauthenticated alert: %v", content))
+			return nil, nil
+		}
+		if content.Description == alert.CloseNotify {
 			// Respond with a close_notify [RFC5246 Section 7.2.1]
-			_ = c.notify(alertLevelWarning, alertCloseNotify)
+			c.sendCloseNotify()
 			return nil, c.Close()
 		}
-		return nil, fmt.Errorf("alert: %v", content)
-	case *changeCipherSpec:
+		if content.Level == alert.Warning {
+			return nil, c.handleWarningAlert(content.Description)
+		}
+		return nil, &AlertError{Level: content.Level, Description: content.Description, Remote: true}
+	case *protocol.ChangeCipherSpec:
 		c.log.Trace("<- ChangeCipherSpec")
 
-		newRemoteEpoch := h.epoch + 1
+		newRemoteEpoch := h.Epoch + 1
 		if c.getRemoteEpoch() < newRemoteEpoch {
 			c.setRemoteEpoch(newRemoteEpoch)
 		}
-	case *applicationData:
-		if h.epoch == 0 {
-			return &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("ApplicationData with epoch of 0")
-		}
+	default:
+		return &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("unhandled contentType %d", content.ContentType())
+	}
+	return nil, nil
+}
 
-		if err := c.handshakeErr.load(); err != nil {
-			return nil, err
-		}
-		if c.connectionClosed.Err() != nil {
-			return nil, io.EOF
-		}
+// handleWarningAlert reports a warning alert to the application, the
+// connection is kept unless the remote canceled the handshake
+func (c *Conn) handleWarningAlert(desc alert.Description) error {
+	if c.onWarningAlert != nil {
+		c.onWarningAlert(desc)
+	}
 
-		select {
-		case c.decrypted <- content.data:
-		case <-c.connectionClosed.Done():
-		}
-	default:
-		return &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("unhandled contentType %d", content.contentType())
+	switch {
+	case desc == alert.NoRenegotiation:
+		// Retransmitted requests may be refused more than once
+		c.abortRenegotiation()
+	case desc == alert.UserCanceled && !c.isHandshakeCompletedSuccessfully():
+		// The remote gave up on the handshake, a close_notify follows
+		return &AlertError{Level: alert.Warning, Description: desc, Remote: true}
+	}
+	return nil
+}
+
+func (c *Conn) handleApplicationData(h *recordlayer.Header, data []byte) (*alert.Alert, error) {
+	if h.Epoch == 0 {
+		c.dropInvalidRecord(errApplicationDataEpochZero)
+		return nil, nil
+	}
+
+	if err := c.handshakeErr.load(); err != nil {
+		return nil, err
+	}
+	if c.connectionClosed.Err() != nil {
+		return nil, io.EOF
 	}
+
+	c.deliverData(data)
 	return nil, nil
 }
 
-func (c *Conn) notify(level alertLevel, desc alertDescription) error {
+// queueEarlyRecord keeps a copy of a record which can not be decrypted
+// yet, the queue is bounded as the record is not authenticated
+func (c *Conn) queueEarlyRecord(buf []byte) {
+	if len(c.earlyRecords) >= maxEarlyRecords {
+		c.log.Debug("handleIncoming: Handshake not finished, dropping packet")
+		return
+	}
+	c.earlyRecords = append(c.earlyRecords, append([]byte{}, buf...))
+}
+
+// fragmentAlert returns the fatal alert sent for a handshake fragment the
+// fragmentBuffer rejected. It is nil for fragments which may be legitimate,
+// such as one reordered too far ahead, they are dropped instead.
+func fragmentAlert(err error) *alert.Alert {
+	switch err {
+	case errHandshakeMessageTooLarge, errInvalidFragment, errFragmentMismatch:
+		return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}
+	case errFragmentBufferFull:
+		return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}
+	}
+	return nil
+}
+
+// dropInvalidRecord discards a record which is malformed or failed
+// authentication, it is counted instead of closing the connection
+func (c *Conn) dropInvalidRecord(err error) {
+	atomic.AddUint64(&c.invalidRecords, 1)
+	c.log.Debugf("dropping invalid record: %v", err)
+}
+
+// InvalidRecords returns the number of received records which were
+// dropped because they were malformed or failed authentication
+func (c *Conn) InvalidRecords() uint64 {
+	return atomic.LoadUint64(&c.invalidRecords)
+}
+
+func (c *Conn) notify(level alert.Level, desc alert.Description) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	if err := c.bufferPacket(&packet{
-		record: &recordLayer{
-			recordLayerHeader: recordLayerHeader{
-				epoch:           c.getLocalEpoch(),
-				protocolVersion: protocolVersion1_2,
+		record: &recordlayer.RecordLayer{
+			Header: recordlayer.Header{
+				Epoch:   c.getLocalEpoch(),
+				Version: protocol.Version1_2,
 			},
-			content: &alert{
-				alertLevel:       level,
-				alertDescription: desc,
+			Content: &alert.Alert{
+				Level:       level,
+				Description: desc,
 			},
 		},
 		shouldEncrypt: c.isHandshakeCompletedSuccessfully(),
@@ -792,59 +1711,107 @@ func (c *Conn) isHandshakeCompletedSuccessfully() bool {
 }
 
 func (c *Conn) startHandshakeOutbound() {
+	if c.sansIO {
+		// The Engine runs the flights once the current event is handled
+		c.flightsRunning = true
+		c.nextFlightTimeout = time.Time{}
+		return
+	}
+
+	handshakeDoneSignal := c.handshakeDoneSignal
 	go func() {
-		defer func() {
-			if c.handshakeErr.load() != nil {
-				if err := c.close(); err != nil {
-					c.log.Errorf(fmt.Sprintf("Failed to close (%v)", err))
-				}
-			}
-		}()
+		defer c.closeOnHandshakeErr()
 		for {
-			var (
-				isFinished bool
-				alertPtr   *alert
-				err        error
-			)
 			select {
-			case <-c.handshakeDoneSignal.Done():
+			case <-handshakeDoneSignal.Done():
 				return
-			case <-c.workerTicker.C:
-				isFinished, alertPtr, err = c.flightHandler(c)
+			case <-c.workerTicker.C():
 			case <-c.currFlight.workerTrigger:
-				isFinished, alertPtr, err = c.flightHandler(c)
-			}
-
-			if alertPtr != nil {
-				if alertErr := c.notify(alertPtr.alertLevel, alertPtr.alertDescription); alertErr != nil {
-					err = fmt.Errorf("%v %v", err, alertErr)
-				}
 			}
-
-			switch {
-			case err != nil:
-				c.handshakeErr.store(err)
-				return
-			case c.readErr.load() != nil:
-				c.handshakeErr.store(c.readErr.load()) // Promote readErr to handshakeErr during handshake
+			if c.handleFlight() {
 				return
-			case isFinished:
-				return // Handshake is complete
 			}
 		}
 	}()
 	c.currFlight.workerTrigger <- struct{}{}
 }
 
+// runFlights is the sans-IO version of the worker started by
+// startHandshakeOutbound, it runs the flight handler if it was triggered
+// or the retransmission timeout has passed
+func (c *Conn) runFlights(now time.Time) {
+	for c.flightsRunning {
+		if c.handshakeDoneSignal.Err() != nil {
+			c.flightsRunning = false
+			return
+		}
+
+		select {
+		case <-c.currFlight.workerTrigger:
+		default:
+			if now.Before(c.nextFlightTimeout) {
+				return
+			}
+		}
+
+		c.nextFlightTimeout = now.Add(c.flightInterval)
+		if c.handleFlight() {
+			c.flightsRunning = false
+			c.closeOnHandshakeErr()
+		}
+	}
+}
+
+// handleFlight runs the flight handler once, it returns true when the
+// handshake worker has to stop
+func (c *Conn) handleFlight() bool {
+	isFinished, alertPtr, err := c.flightHandler(c)
+	if alertPtr != nil {
+		err = c.sendAlert(alertPtr, err)
+	}
+
+	switch {
+	case err != nil:
+		c.handshakeErr.store(err)
+		return true
+	case c.readErr.load() != nil:
+		c.handshakeErr.store(c.readErr.load()) // Promote readErr to handshakeErr during handshake
+		return true
+	case isFinished:
+		return true // Handshake is complete
+	}
+	return false
+}
+
+func (c *Conn) closeOnHandshakeErr() {
+	if c.handshakeErr.load() != nil {
+		if err := c.close(); err != nil {
+			c.log.Errorf(fmt.Sprintf("Failed to close (%v)", err))
+		}
+	}
+}
+
 func (c *Conn) close() error {
-	if c.connectionClosed.Err() == nil && c.handshakeErr.load() == nil {
-		_ = c.notify(alertLevelWarning, alertCloseNotify)
+	if c.connectionClosed.Err() == nil && c.handshakeErr.load() == nil && atomic.LoadInt32(&c.handshakeStarted) == 1 {
+		c.sendCloseNotify()
 	}
 
-	c.workerTicker.Stop()
-	c.handshakeDoneSignal.Close()
+	if c.workerTicker != nil {
+		c.workerTicker.Stop()
+	}
 	c.connectionClosed.Close()
-	return c.nextConn.Close()
+	var err error
+	if c.nextConn != nil {
+		err = c.nextConn.Close()
+	}
+
+	// Flight handlers may hold the lock while blocked on nextConn
+	c.lock.RLock()
+	handshakeDoneSignal := c.handshakeDoneSignal
+	c.lock.RUnlock()
+	handshakeDoneSignal.Close()
+
+	return err
 }
 
 func (c *Conn) setLocalEpoch(epoch uint16) {
@@ -117,7 +117,9 @@ func TestContextConfig(t *testing.T) {
 					return
 				}
 				done <- struct{}{}
-				_ = conn.Close()
+				if c, ok := conn.(*Conn); !ok || c != nil {
+					t.Errorf("Expected no Conn with the error, got: %v", conn)
+				}
 			}()
 
 			var order []byte
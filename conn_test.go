@@ -8,13 +8,25 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
 	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 	"github.com/pion/transport/test"
 )
 
@@ -86,6 +98,230 @@ func TestRoutineLeakOnClose(t *testing.T) {
 	// inboundLoop routine should not be leaked.
 }
 
+func TestReadBuffer(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(5 * time.Second)
+	defer lim.Stop()
+
+	ca, cb, err := pipeMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = ca.Close()
+		_ = cb.Close()
+	}()
+
+	messages := [][]byte{[]byte("hello"), []byte("world")}
+	go func() {
+		for _, m := range messages {
+			if _, wErr := ca.Write(m); wErr != nil {
+				t.Error(wErr)
+			}
+		}
+	}()
+
+	// Records stay valid until they are released
+	var records []RecordBuffer
+	for range messages {
+		r, rErr := cb.ReadBuffer()
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		records = append(records, r)
+	}
+	for i, r := range records {
+		if !bytes.Equal(r.Bytes(), messages[i]) {
+			t.Errorf("record %d: got %q, want %q", i, r.Bytes(), messages[i])
+		}
+		r.Release()
+	}
+}
+
+// batchConn records the sizes of the batches written with WriteBatch
+type batchConn struct {
+	net.Conn
+	lock    sync.Mutex
+	batches []int
+}
+
+func (c *batchConn) WriteBatch(datagrams [][]byte) (int, error) {
+	c.lock.Lock()
+	c.batches = append(c.batches, len(datagrams))
+	c.lock.Unlock()
+	for i, d := range datagrams {
+		if _, err := c.Conn.Write(d); err != nil {
+			return i, err
+		}
+	}
+	return len(datagrams), nil
+}
+
+func TestFlightWriteBatch(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := dpipe.Pipe()
+	serverConn := &batchConn{Conn: cb}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		client, err := testClient(ctx, ca, &Config{}, false)
+		if err == nil {
+			err = client.Close()
+		}
+		clientErr <- err
+	}()
+
+	// The certificate does not fit into a single datagram
+	server, err := testServer(ctx, serverConn, &Config{MTU: 200}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-clientErr; err != nil {
+		t.Fatal(err)
+	}
+	_ = server.Close()
+
+	serverConn.lock.Lock()
+	defer serverConn.lock.Unlock()
+	if len(serverConn.batches) == 0 {
+		t.Fatal("no flight was written with WriteBatch")
+	}
+	for _, n := range serverConn.batches {
+		if n < 2 {
+			t.Errorf("WriteBatch called with %d datagrams", n)
+		}
+	}
+}
+
+func TestEarlyRecordsReplayed(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Records of a flight are sent in separate datagrams
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true, MTU: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerEngine(&Config{Certificates: []tls.Certificate{cert}, MTU: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every flight is delivered in reverse, so the Finished message and
+	// the first application data arrive before the keys of their epoch
+	deliver := func(to *Engine, datagrams [][]byte) {
+		for i := len(datagrams) - 1; i >= 0; i-- {
+			if err := to.HandleDatagram(now, datagrams[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i := 0; i < 10 && !(client.HandshakeComplete() && server.HandshakeComplete()); i++ {
+		deliver(server, client.Outgoing())
+		if server.HandshakeComplete() && !client.HandshakeComplete() {
+			if err = server.Write(now, []byte("early")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		deliver(client, server.Outgoing())
+	}
+	if !client.HandshakeComplete() || !server.HandshakeComplete() {
+		t.Fatal("Handshake did not complete without retransmissions")
+	}
+
+	if received := client.Received(); len(received) != 1 || string(received[0]) != "early" {
+		t.Errorf("Received: got %q, want [early]", received)
+	}
+	if client.conn.InvalidRecords() != 0 || server.conn.InvalidRecords() != 0 {
+		t.Errorf("InvalidRecords: got %d and %d, want none", client.conn.InvalidRecords(), server.conn.InvalidRecords())
+	}
+}
+
+func TestEarlyRecordsDropped(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerEngine(&Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forgedRecord := func(epoch uint16) []byte {
+		raw, err := (&recordlayer.Header{
+			ContentType:    protocol.ContentTypeApplicationData,
+			Version:        protocol.Version1_2,
+			Epoch:          epoch,
+			SequenceNumber: 1,
+			ContentLen:     32,
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(raw, make([]byte, 32)...)
+	}
+
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only records of the next epoch are kept until its keys are installed
+	for _, epoch := range []uint16{1, 2} {
+		if err = server.HandleDatagram(now, forgedRecord(epoch)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(server.conn.earlyRecords) != 1 || server.conn.InvalidRecords() != 1 {
+		t.Fatalf("Queued %d records and dropped %d, want 1 and 1", len(server.conn.earlyRecords), server.conn.InvalidRecords())
+	}
+
+	for i := 0; i < 10 && !(client.HandshakeComplete() && server.HandshakeComplete()); i++ {
+		for _, d := range client.Outgoing() {
+			if err = server.HandleDatagram(now, d); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, d := range server.Outgoing() {
+			if err = client.HandleDatagram(now, d); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !client.HandshakeComplete() || !server.HandshakeComplete() {
+		t.Fatal("Handshake did not complete")
+	}
+
+	// The queued record fails authentication once the keys are installed
+	if len(server.conn.earlyRecords) != 0 || server.conn.InvalidRecords() != 2 {
+		t.Errorf("Queued %d records and dropped %d, want 0 and 2", len(server.conn.earlyRecords), server.conn.InvalidRecords())
+	}
+}
+
 func pipeMemory() (*Conn, *Conn, error) {
 	// In memory pipe
 	ca, cb := dpipe.Pipe()
@@ -167,13 +403,180 @@ func TestHandshakeWithAlert(t *testing.T) {
 		CipherSuites: []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
 	}
 
-	if _, err := testServer(ctx, cb, config, true); err != errCipherSuiteNoIntersection {
-		t.Fatalf("TestHandshakeWithAlert: Client error exp(%v) failed(%v)", errCipherSuiteNoIntersection, err)
+	_, err := testServer(ctx, cb, config, true)
+	if !errors.Is(err, errCipherSuiteNoIntersection) {
+		t.Fatalf("TestHandshakeWithAlert: Server error exp(%v) failed(%v)", errCipherSuiteNoIntersection, err)
+	}
+	var serverAlert *AlertError
+	if !errors.As(err, &serverAlert) || serverAlert.Remote || serverAlert.Description != AlertInsufficientSecurity {
+		t.Fatalf("TestHandshakeWithAlert: Server error exp sent %s alert, failed(%#v)", AlertInsufficientSecurity, err)
 	}
 
-	if err := <-clientErr; err.Error() != alertErr.Error() {
+	err = <-clientErr
+	if err.Error() != alertErr.Error() {
 		t.Fatalf("TestHandshakeWithAlert: Client error exp(%v) failed(%v)", alertErr, err)
 	}
+	var clientAlert *AlertError
+	if !errors.As(err, &clientAlert) || !clientAlert.Remote || clientAlert.Level != AlertLevelFatal || clientAlert.Description != AlertInsufficientSecurity {
+		t.Fatalf("TestHandshakeWithAlert: Client error exp received %s alert, failed(%#v)", AlertInsufficientSecurity, err)
+	}
+}
+
+func TestHandshakeStateChange(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	changes := map[bool][]HandshakeState{}
+	onChange := func(isClient bool) func(HandshakeStateChange) {
+		return func(change HandshakeStateChange) {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(changes[isClient]) == 0 {
+				changes[isClient] = append(changes[isClient], change.From)
+			}
+			changes[isClient] = append(changes[isClient], change.To)
+		}
+	}
+
+	ca, cb := dpipe.Pipe()
+	type result struct {
+		c   *Conn
+		err error
+	}
+	c := make(chan result)
+	go func() {
+		client, err := testClient(ctx, ca, &Config{OnHandshakeStateChange: onChange(true)}, true)
+		c <- result{client, err}
+	}()
+
+	server, err := testServer(ctx, cb, &Config{OnHandshakeStateChange: onChange(false)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-c
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	defer func() {
+		_ = res.c.Close()
+		_ = server.Close()
+	}()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for isClient, expected := range map[bool][]HandshakeState{
+		true:  {HandshakeStateClientHello, HandshakeStateClientHelloCookie, HandshakeStateClientKeyExchange},
+		false: {HandshakeStateWaitClientHello, HandshakeStateHelloVerifyRequest, HandshakeStateServerHello, HandshakeStateServerFinished},
+	} {
+		if fmt.Sprint(changes[isClient]) != fmt.Sprint(expected) {
+			t.Errorf("States (isClient %t): expected(%v) actual(%v)", isClient, expected, changes[isClient])
+		}
+	}
+}
+
+func TestUnexpectedHandshakeMessage(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ca, cb := dpipe.Pipe()
+	defer func() {
+		_ = ca.Close()
+	}()
+
+	// A Finished can't be the first message of a handshake
+	raw, err := (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version: protocol.Version1_2,
+		},
+		Content: &handshake.Handshake{
+			Message: &handshake.MessageFinished{VerifyData: make([]byte, 12)},
+		},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ca.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = testServer(ctx, cb, &Config{FlightInterval: 50 * time.Millisecond}, true); !errors.Is(err, errUnexpectedHandshakeMessage) {
+		t.Fatalf("Server error: expected(%v) actual(%v)", errUnexpectedHandshakeMessage, err)
+	}
+
+	buf := make([]byte, 8192)
+	n, err := ca.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recordlayer.RecordLayer{}
+	if err = r.Unmarshal(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := r.Content.(*alert.Alert); !ok || a.Description != AlertUnexpectedMessage {
+		t.Errorf("Expected an unexpected_message alert, got %v", r.Content)
+	}
+}
+
+func TestCancelHandshake(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := dpipe.Pipe()
+	defer func() {
+		_ = cb.Close()
+	}()
+
+	client, err := NewClient(ca, &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handshakeErr := make(chan error, 1)
+	go func() {
+		handshakeErr <- client.Handshake(context.Background())
+	}()
+
+	// Wait for the ClientHello
+	buf := make([]byte, 8192)
+	if _, err = cb.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.CancelHandshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-handshakeErr; err != ErrHandshakeCanceled {
+		t.Fatalf("Handshake: expected(%v) actual(%v)", ErrHandshakeCanceled, err)
+	}
+
+	// Retransmitted ClientHellos may be read before the alerts
+	var alerts []AlertDescription
+	for len(alerts) < 2 {
+		n, err := cb.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &recordlayer.RecordLayer{}
+		if err = r.Unmarshal(buf[:n]); err != nil {
+			t.Fatal(err)
+		}
+		if a, ok := r.Content.(*alert.Alert); ok {
+			alerts = append(alerts, a.Description)
+		}
+	}
+	if fmt.Sprint(alerts) != fmt.Sprint([]AlertDescription{AlertUserCanceled, AlertCloseNotify}) {
+		t.Errorf("Expected user_canceled and close_notify, got %v", alerts)
+	}
+
+	if err = client.CancelHandshake(); err != ErrConnClosed {
+		t.Errorf("CancelHandshake after close: expected(%v) actual(%v)", ErrConnClosed, err)
+	}
 }
 
 func TestExportKeyingMaterial(t *testing.T) {
@@ -185,8 +588,8 @@ func TestExportKeyingMaterial(t *testing.T) {
 
 	c := &Conn{
 		state: State{
-			localRandom:  handshakeRandom{time.Unix(500, 0), rand},
-			remoteRandom: handshakeRandom{time.Unix(1000, 0), rand},
+			localRandom:  handshake.Random{GMTUnixTime: time.Unix(500, 0), RandomBytes: rand},
+			remoteRandom: handshake.Random{GMTUnixTime: time.Unix(1000, 0), RandomBytes: rand},
 			cipherSuite:  &cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
 		},
 	}
@@ -198,9 +601,9 @@ func TestExportKeyingMaterial(t *testing.T) {
 	}
 
 	c.setLocalEpoch(1)
-	_, err = c.ExportKeyingMaterial(exportLabel, []byte{0x00}, 0)
-	if err != errContextUnsupported {
-		t.Errorf("ExportKeyingMaterial with context: expected '%s' actual '%s'", errContextUnsupported, err)
+	_, err = c.ExportKeyingMaterial(exportLabel, make([]byte, 0x10000), 0)
+	if err != errContextTooLong {
+		t.Errorf("ExportKeyingMaterial with long context: expected '%s' actual '%s'", errContextTooLong, err)
 	}
 
 	for k := range invalidKeyingLabels {
@@ -224,44 +627,166 @@ func TestExportKeyingMaterial(t *testing.T) {
 	} else if !bytes.Equal(keyingMaterial, expectedClientKey) {
 		t.Errorf("ExportKeyingMaterial client export: expected (% 02x) actual (% 02x)", expectedClientKey, keyingMaterial)
 	}
-}
-
-func TestPSK(t *testing.T) {
-	// Limit runtime in case of deadlocks
-	lim := test.TimeOut(time.Second * 20)
-	defer lim.Stop()
 
+	// Expected values were generated with `openssl kdf TLS1-PRF`, an empty
+	// context is length prefixed like in crypto/tls and OpenSSL
 	for _, test := range []struct {
-		Name           string
-		ServerIdentity []byte
+		Name              string
+		Context           []byte
+		ExpectedServerKey []byte
+		ExpectedClientKey []byte
 	}{
 		{
-			Name:           "Server identity specified",
-			ServerIdentity: []byte("Test Identity"),
+			Name:              "Empty context",
+			Context:           []byte{},
+			ExpectedServerKey: []byte{0x83, 0x60, 0x2a, 0x53, 0x9c, 0xf0, 0xa4, 0x89, 0x2f, 0x92},
+			ExpectedClientKey: []byte{0x0f, 0x5d, 0x07, 0x21, 0xb2, 0xfa, 0x43, 0xab, 0x06, 0x26},
 		},
 		{
-			Name:           "Server identity nil",
-			ServerIdentity: nil,
+			Name:              "Context",
+			Context:           []byte{0x01, 0x02, 0x03},
+			ExpectedServerKey: []byte{0x76, 0xe7, 0x35, 0x8d, 0xa7, 0x42, 0x70, 0xda, 0x11, 0x51},
+			ExpectedClientKey: []byte{0xb2, 0xe7, 0xb9, 0x72, 0x06, 0xac, 0x2a, 0x74, 0x82, 0xdc},
 		},
 	} {
-		test := test
-		t.Run(test.Name, func(t *testing.T) {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			clientIdentity := []byte("Client Identity")
-			clientErr := make(chan error, 1)
+		c.state.isClient = false
+		keyingMaterial, err = c.ExportKeyingMaterial(exportLabel, test.Context, 10)
+		if err != nil {
+			t.Errorf("ExportKeyingMaterial %s as server: unexpected error '%s'", test.Name, err)
+		} else if !bytes.Equal(keyingMaterial, test.ExpectedServerKey) {
+			t.Errorf("ExportKeyingMaterial %s server export: expected (% 02x) actual (% 02x)", test.Name, test.ExpectedServerKey, keyingMaterial)
+		}
 
-			ca, cb := dpipe.Pipe()
-			go func() {
-				conf := &Config{
-					PSK: func(hint []byte) ([]byte, error) {
-						if !bytes.Equal(test.ServerIdentity, hint) { // nolint
-							return nil, fmt.Errorf("TestPSK: Client got invalid identity expected(% 02x) actual(% 02x)", test.ServerIdentity, hint) // nolint
-						}
+		c.state.isClient = true
+		keyingMaterial, err = c.ExportKeyingMaterial(exportLabel, test.Context, 10)
+		if err != nil {
+			t.Errorf("ExportKeyingMaterial %s as client: unexpected error '%s'", test.Name, err)
+		} else if !bytes.Equal(keyingMaterial, test.ExpectedClientKey) {
+			t.Errorf("ExportKeyingMaterial %s client export: expected (% 02x) actual (% 02x)", test.Name, test.ExpectedClientKey, keyingMaterial)
+		}
+	}
+}
 
-						return []byte{0xAB, 0xC1, 0x23}, nil
-					},
+func TestExportKeyingMaterialCryptoTLS(t *testing.T) {
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key log gives access to the master secret of crypto/tls
+	keyLog := &bytes.Buffer{}
+	ca, cb := net.Pipe()
+	recorder := &readRecordingConn{Conn: ca}
+	client := tls.Client(recorder, &tls.Config{
+		InsecureSkipVerify: true, // nolint:gosec
+		MaxVersion:         tls.VersionTLS12,
+		CipherSuites:       []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		KeyLogWriter:       keyLog,
+	})
+	server := tls.Server(cb, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer func() {
+		_ = ca.Close()
+		_ = cb.Close()
+	}()
+
+	serverErr := make(chan error)
+	go func() {
+		serverErr <- server.Handshake()
+	}()
+	if err = client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+
+	var clientRandom, masterSecret []byte
+	if _, err = fmt.Sscanf(keyLog.String(), "CLIENT_RANDOM %x %x", &clientRandom, &masterSecret); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server random is not logged, it is read from the TLS ServerHello
+	// behind the record header (5), handshake header (4) and version (2)
+	serverRandom := recorder.read.Bytes()[5+4+2:][:handshake.RandomLength]
+
+	c := &Conn{
+		state: State{
+			masterSecret: masterSecret,
+			cipherSuite:  &cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
+			isClient:     true,
+		},
+	}
+	c.setLocalEpoch(1)
+	if err = c.state.localRandom.Unmarshal(clientRandom); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.state.remoteRandom.Unmarshal(serverRandom); err != nil {
+		t.Fatal(err)
+	}
+
+	connState := client.ConnectionState()
+	for _, context := range [][]byte{nil, {}, []byte("context")} {
+		expected, err := connState.ExportKeyingMaterial("EXPERIMENTAL_label", context, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := c.ExportKeyingMaterial("EXPERIMENTAL_label", context, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(expected, actual) {
+			t.Errorf("ExportKeyingMaterial context %#v: crypto/tls (% 02x) dtls (% 02x)", context, expected, actual)
+		}
+	}
+}
+
+type readRecordingConn struct {
+	net.Conn
+	read bytes.Buffer
+}
+
+func (c *readRecordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.read.Write(b[:n])
+	return n, err
+}
+
+func TestPSK(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	for _, test := range []struct {
+		Name           string
+		ServerIdentity []byte
+	}{
+		{
+			Name:           "Server identity specified",
+			ServerIdentity: []byte("Test Identity"),
+		},
+		{
+			Name:           "Server identity nil",
+			ServerIdentity: nil,
+		},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			clientIdentity := []byte("Client Identity")
+			clientErr := make(chan error, 1)
+
+			ca, cb := dpipe.Pipe()
+			go func() {
+				conf := &Config{
+					PSK: func(hint []byte) ([]byte, error) {
+						if !bytes.Equal(test.ServerIdentity, hint) { // nolint
+							return nil, fmt.Errorf("TestPSK: Client got invalid identity expected(% 02x) actual(% 02x)", test.ServerIdentity, hint) // nolint
+						}
+
+						return []byte{0xAB, 0xC1, 0x23}, nil
+					},
 					PSKIdentityHint: clientIdentity,
 					CipherSuites:    []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM_8},
 				}
@@ -330,7 +855,7 @@ func TestPSKHintFail(t *testing.T) {
 		t.Fatalf("TestPSK: Server error exp(%v) failed(%v)", serverAlertError, err)
 	}
 
-	if err := <-clientErr; err != pskRejected {
+	if err := <-clientErr; !errors.Is(err, pskRejected) {
 		t.Fatalf("TestPSK: Client error exp(%v) failed(%v)", pskRejected, err)
 	}
 }
@@ -384,6 +909,14 @@ func TestSRTPConfiguration(t *testing.T) {
 			WantClientError: nil,
 			WantServerError: nil,
 		},
+		{
+			Name:            "SRTP AEAD_AES_256_GCM preferred by client",
+			ClientSRTP:      []SRTPProtectionProfile{SRTP_AEAD_AES_256_GCM, SRTP_AEAD_AES_128_GCM, SRTP_AES128_CM_HMAC_SHA1_32},
+			ServerSRTP:      []SRTPProtectionProfile{SRTP_AES128_CM_HMAC_SHA1_32, SRTP_AEAD_AES_256_GCM},
+			ExpectedProfile: SRTP_AEAD_AES_256_GCM,
+			WantClientError: nil,
+			WantServerError: nil,
+		},
 		{
 			Name:            "SRTP client only",
 			ClientSRTP:      []SRTPProtectionProfile{SRTP_AES128_CM_HMAC_SHA1_80},
@@ -429,6 +962,10 @@ func TestSRTPConfiguration(t *testing.T) {
 				t.Errorf("TestSRTPConfiguration: Client Error Mismatch '%s': expected(%v) actual(%v)", test.Name, test.WantClientError, res.err)
 			}
 		}
+		if err != nil || res.err != nil {
+			// No Conn is returned if the handshake failed
+			continue
+		}
 
 		actualClientSRTP, _ := res.c.SelectedSRTPProtectionProfile()
 		if actualClientSRTP != test.ExpectedProfile {
@@ -442,6 +979,87 @@ func TestSRTPConfiguration(t *testing.T) {
 	}
 }
 
+func TestSRTPKeyingMaterial(t *testing.T) {
+	for _, test := range []struct {
+		Name        string
+		Profile     SRTPProtectionProfile
+		KeyLen      int
+		SaltLen     int
+		ClientMKI   []byte
+		ExpectedMKI []byte
+	}{
+		{"SRTP_AES128_CM_HMAC_SHA1_80", SRTP_AES128_CM_HMAC_SHA1_80, 16, 14, nil, []byte{}},
+		{"SRTP_AES128_CM_HMAC_SHA1_32", SRTP_AES128_CM_HMAC_SHA1_32, 16, 14, nil, []byte{}},
+		{"SRTP_AEAD_AES_128_GCM", SRTP_AEAD_AES_128_GCM, 16, 12, nil, []byte{}},
+		{"SRTP_AEAD_AES_256_GCM with MKI", SRTP_AEAD_AES_256_GCM, 32, 12, []byte{0x01, 0x02}, []byte{0x01, 0x02}},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					SRTPProtectionProfiles:  []SRTPProtectionProfile{test.Profile},
+					SRTPMasterKeyIdentifier: test.ClientMKI,
+				}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{SRTPProtectionProfiles: []SRTPProtectionProfile{test.Profile}}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = server.Close()
+			}()
+			res := <-c
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+			}()
+
+			for _, conn := range []*Conn{res.c, server} {
+				if mki := conn.SRTPMasterKeyIdentifier(); !bytes.Equal(mki, test.ExpectedMKI) {
+					t.Errorf("SRTPMasterKeyIdentifier: expected(%v) actual(%v)", test.ExpectedMKI, mki)
+				}
+			}
+
+			clientKeys, err := res.c.SRTPKeyingMaterial()
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverKeys, err := server.SRTPKeyingMaterial()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(clientKeys.LocalMasterKey) != test.KeyLen || len(clientKeys.RemoteMasterKey) != test.KeyLen {
+				t.Errorf("Master key length: expected(%d) actual(%d, %d)", test.KeyLen, len(clientKeys.LocalMasterKey), len(clientKeys.RemoteMasterKey))
+			}
+			if len(clientKeys.LocalMasterSalt) != test.SaltLen || len(clientKeys.RemoteMasterSalt) != test.SaltLen {
+				t.Errorf("Master salt length: expected(%d) actual(%d, %d)", test.SaltLen, len(clientKeys.LocalMasterSalt), len(clientKeys.RemoteMasterSalt))
+			}
+			if !bytes.Equal(clientKeys.LocalMasterKey, serverKeys.RemoteMasterKey) || !bytes.Equal(clientKeys.LocalMasterSalt, serverKeys.RemoteMasterSalt) ||
+				!bytes.Equal(clientKeys.RemoteMasterKey, serverKeys.LocalMasterKey) || !bytes.Equal(clientKeys.RemoteMasterSalt, serverKeys.LocalMasterSalt) {
+				t.Errorf("SRTPKeyingMaterial mismatch: client(%v) server(%v)", clientKeys, serverKeys)
+			}
+			if bytes.Equal(clientKeys.LocalMasterKey, clientKeys.RemoteMasterKey) {
+				t.Error("SRTPKeyingMaterial: local and remote master keys must differ")
+			}
+		})
+	}
+}
+
 func TestClientCertificate(t *testing.T) {
 	srvCert, err := selfsign.GenerateSelfSigned()
 	if err != nil {
@@ -1017,49 +1635,49 @@ func TestServerTimeout(t *testing.T) {
 	}
 
 	var rand [28]byte
-	random := handshakeRandom{time.Unix(500, 0), rand}
+	random := handshake.Random{GMTUnixTime: time.Unix(500, 0), RandomBytes: rand}
 
 	cipherSuites := []cipherSuite{
 		&cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
 		&cipherSuiteTLSEcdheRsaWithAes128GcmSha256{},
 	}
 
-	extensions := []extension{
-		&extensionSupportedSignatureAlgorithms{
-			signatureHashAlgorithms: []signatureHashAlgorithm{
-				{hashAlgorithmSHA256, signatureAlgorithmECDSA},
-				{hashAlgorithmSHA384, signatureAlgorithmECDSA},
-				{hashAlgorithmSHA512, signatureAlgorithmECDSA},
-				{hashAlgorithmSHA256, signatureAlgorithmRSA},
-				{hashAlgorithmSHA384, signatureAlgorithmRSA},
-				{hashAlgorithmSHA512, signatureAlgorithmRSA},
+	extensions := []extension.Extension{
+		&extension.SupportedSignatureAlgorithms{
+			SignatureHashAlgorithms: []signaturehash.Algorithm{
+				{Hash: hash.SHA256, Signature: signature.ECDSA},
+				{Hash: hash.SHA384, Signature: signature.ECDSA},
+				{Hash: hash.SHA512, Signature: signature.ECDSA},
+				{Hash: hash.SHA256, Signature: signature.RSA},
+				{Hash: hash.SHA384, Signature: signature.RSA},
+				{Hash: hash.SHA512, Signature: signature.RSA},
 			},
 		},
-		&extensionSupportedEllipticCurves{
-			ellipticCurves: []namedCurve{namedCurveX25519, namedCurveP256, namedCurveP384},
+		&extension.SupportedEllipticCurves{
+			EllipticCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384},
 		},
-		&extensionSupportedPointFormats{
-			pointFormats: []ellipticCurvePointFormat{ellipticCurvePointFormatUncompressed},
+		&extension.SupportedPointFormats{
+			PointFormats: []extension.EllipticCurvePointFormat{extension.EllipticCurvePointFormatUncompressed},
 		},
 	}
 
-	record := &recordLayer{
-		recordLayerHeader: recordLayerHeader{
-			sequenceNumber:  0,
-			protocolVersion: protocolVersion1_2,
+	record := &recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			SequenceNumber: 0,
+			Version:        protocol.Version1_2,
 		},
-		content: &handshake{
+		Content: &handshake.Handshake{
 			// sequenceNumber and messageSequence line up, may need to be re-evaluated
-			handshakeHeader: handshakeHeader{
-				messageSequence: 0,
+			Header: handshake.Header{
+				MessageSequence: 0,
 			},
-			handshakeMessage: &handshakeMessageClientHello{
-				version:            protocolVersion1_2,
-				cookie:             cookie,
-				random:             random,
-				cipherSuites:       cipherSuites,
-				compressionMethods: defaultCompressionMethods,
-				extensions:         extensions,
+			Message: &handshake.MessageClientHello{
+				Version:            protocol.Version1_2,
+				Cookie:             cookie,
+				Random:             random,
+				CipherSuiteIDs:     cipherSuiteIDs(cipherSuites),
+				CompressionMethods: defaultCompressionMethods(),
+				Extensions:         extensions,
 			}},
 	}
 
@@ -1162,8 +1780,11 @@ func TestGetCertificate(t *testing.T) {
 		},
 	}
 
-	conn, err := createConn(ctx, cb, serverFlightHandler, serverHandshakeHandler, configServer, false)
-	if err != errConnectTimeout {
+	conn, err := createConn(cb, serverFlightHandler, serverHandshakeHandler, configServer, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.Handshake(ctx); err != errConnectTimeout {
 		t.Fatal(err)
 	}
 
@@ -1212,3 +1833,859 @@ func TestGetCertificate(t *testing.T) {
 		})
 	}
 }
+
+func TestRenegotiation(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	exchange := func(t *testing.T, from, to *Conn, msg []byte) {
+		if _, err := from.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 64)
+		n, err := to.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], msg) {
+			t.Fatalf("Data mismatch: expected(%v) actual(%v)", msg, buf[:n])
+		}
+	}
+
+	for name, tt := range map[string]struct {
+		clientCfg, serverCfg *Config
+		initiatedByClient    bool
+		expectedErr          error
+	}{
+		"ClientInitiated": {
+			clientCfg:         &Config{Renegotiation: RenegotiateFreely},
+			serverCfg:         &Config{Renegotiation: RenegotiateFreely},
+			initiatedByClient: true,
+		},
+		"ServerInitiated": {
+			clientCfg: &Config{Renegotiation: RenegotiateFreely},
+			serverCfg: &Config{Renegotiation: RenegotiateFreely, ClientAuth: RequireAnyClientCert},
+		},
+		"RefusedByServer": {
+			clientCfg:         &Config{Renegotiation: RenegotiateFreely},
+			serverCfg:         &Config{},
+			initiatedByClient: true,
+			expectedErr:       errRenegotiationRefused,
+		},
+		"RefusedByClient": {
+			clientCfg:   &Config{},
+			serverCfg:   &Config{Renegotiation: RenegotiateFreely},
+			expectedErr: errRenegotiationRefused,
+		},
+		"Disabled": {
+			clientCfg:         &Config{},
+			serverCfg:         &Config{Renegotiation: RenegotiateFreely},
+			initiatedByClient: true,
+			expectedErr:       errRenegotiationDisabled,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, tt.clientCfg, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, tt.serverCfg, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := <-c
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			client := res.c
+			defer func() {
+				_ = client.Close()
+				_ = server.Close()
+			}()
+
+			keyingMaterial, err := client.ExportKeyingMaterial("EXPERIMENTAL_renegotiation", nil, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The remote must keep reading to process the new handshake
+			initiator, responder := server, client
+			if tt.initiatedByClient {
+				initiator, responder = client, server
+			}
+			readErr := make(chan error, 1)
+			go func() {
+				buf := make([]byte, 64)
+				_, err := responder.Read(buf)
+				readErr <- err
+			}()
+
+			if err := initiator.RenegotiateWithContext(ctx); err != tt.expectedErr {
+				t.Fatalf("Renegotiate error expected: \"%v\" but got \"%v\"", tt.expectedErr, err)
+			}
+
+			// Unblock the reader, and make sure the connection is still usable
+			if _, err := initiator.Write([]byte("unblock")); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-readErr; err != nil {
+				t.Fatal(err)
+			}
+			exchange(t, client, server, []byte("client"))
+			exchange(t, server, client, []byte("server"))
+
+			expectedEpoch := uint16(1)
+			if tt.expectedErr == nil {
+				expectedEpoch = 2
+			}
+			if epoch := client.getLocalEpoch(); epoch != expectedEpoch {
+				t.Errorf("Client epoch expected(%d) actual(%d)", expectedEpoch, epoch)
+			}
+			if epoch := server.getLocalEpoch(); epoch != expectedEpoch {
+				t.Errorf("Server epoch expected(%d) actual(%d)", expectedEpoch, epoch)
+			}
+
+			clientKeyingMaterial, err := client.ExportKeyingMaterial("EXPERIMENTAL_renegotiation", nil, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			serverKeyingMaterial, err := server.ExportKeyingMaterial("EXPERIMENTAL_renegotiation", nil, 32)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(clientKeyingMaterial, serverKeyingMaterial) {
+				t.Errorf("Keying material mismatch: client(%v) server(%v)", clientKeyingMaterial, serverKeyingMaterial)
+			}
+			if renegotiated := !bytes.Equal(keyingMaterial, clientKeyingMaterial); renegotiated != (tt.expectedErr == nil) {
+				t.Errorf("Keying material changed(%v), expected(%v)", renegotiated, tt.expectedErr == nil)
+			}
+		})
+	}
+}
+
+func TestRekey(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	for name, tt := range map[string]struct {
+		renegotiation RenegotiationSupport
+		expectedErr   error
+	}{
+		"Renegotiate": {
+			renegotiation: RenegotiateFreely,
+		},
+		"FailClosed": {
+			renegotiation: RenegotiateNever,
+			expectedErr:   ErrRekeyRequired,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{Renegotiation: tt.renegotiation, RekeyThreshold: 4}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{Renegotiation: tt.renegotiation}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := <-c
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			client := res.c
+			defer func() {
+				_ = client.Close()
+				_ = server.Close()
+			}()
+
+			// Keep writing until the keys were replaced at least twice
+			buf := make([]byte, 64)
+			for i := 0; i < 256 && client.getLocalEpoch() < 3; i++ {
+				if _, err := client.Write([]byte{byte(i)}); err != nil {
+					if err != tt.expectedErr {
+						t.Fatalf("Write error expected: \"%v\" but got \"%v\"", tt.expectedErr, err)
+					}
+					return
+				}
+				n, err := server.Read(buf)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != 1 || buf[0] != byte(i) {
+					t.Fatalf("Data mismatch: expected(%v) actual(%v)", []byte{byte(i)}, buf[:n])
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if tt.expectedErr != nil {
+				t.Fatalf("Write error expected: \"%v\"", tt.expectedErr)
+			}
+			if epoch := client.getLocalEpoch(); epoch < 3 {
+				t.Errorf("Client expected to rekey more than once, epoch(%d)", epoch)
+			}
+		})
+	}
+}
+
+func TestRekeyThreshold(t *testing.T) {
+	for name, tt := range map[string]struct {
+		cipherSuite       cipherSuite
+		renegotiation     RenegotiationSupport
+		rekeyThreshold    uint64
+		expectedThreshold uint64
+	}{
+		"GCM": {
+			cipherSuite:       &cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
+			renegotiation:     RenegotiateFreely,
+			expectedThreshold: aesGCMRecordLimit,
+		},
+		"CCM": {
+			cipherSuite:       newCipherSuiteTLSEcdheEcdsaWithAes128Ccm(),
+			renegotiation:     RenegotiateFreely,
+			expectedThreshold: aesCCMRecordLimit,
+		},
+		"CBC": {
+			cipherSuite:       &cipherSuiteTLSEcdheEcdsaWithAes256CbcSha{},
+			renegotiation:     RenegotiateFreely,
+			expectedThreshold: defaultRekeyThreshold,
+		},
+		"RenegotiationDisabled": {
+			cipherSuite:       &cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
+			expectedThreshold: recordlayer.MaxSequenceNumber,
+		},
+		"Configured": {
+			cipherSuite:       &cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
+			rekeyThreshold:    4,
+			expectedThreshold: 4,
+		},
+	} {
+		c := &Conn{renegotiation: tt.renegotiation, rekeyThreshold: tt.rekeyThreshold}
+		c.state.cipherSuite = tt.cipherSuite
+		if threshold := c.getRekeyThreshold(); threshold != tt.expectedThreshold {
+			t.Errorf("%s: rekey threshold expected(%d) actual(%d)", name, tt.expectedThreshold, threshold)
+		}
+	}
+}
+
+func TestEncryptThenMAC(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	for name, tt := range map[string]struct {
+		cipherSuite            CipherSuiteID
+		expectedEncryptThenMAC bool
+	}{
+		"CBC": {
+			cipherSuite:            TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			expectedEncryptThenMAC: true,
+		},
+		"AEAD": {
+			cipherSuite:            TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			expectedEncryptThenMAC: false,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{CipherSuites: []CipherSuiteID{tt.cipherSuite}}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{CipherSuites: []CipherSuiteID{tt.cipherSuite}}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := <-c
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			client := res.c
+			defer func() {
+				_ = client.Close()
+				_ = server.Close()
+			}()
+
+			if client.state.encryptThenMAC != tt.expectedEncryptThenMAC {
+				t.Errorf("Client encryptThenMAC expected(%v) actual(%v)", tt.expectedEncryptThenMAC, client.state.encryptThenMAC)
+			}
+			if server.state.encryptThenMAC != tt.expectedEncryptThenMAC {
+				t.Errorf("Server encryptThenMAC expected(%v) actual(%v)", tt.expectedEncryptThenMAC, server.state.encryptThenMAC)
+			}
+
+			if _, err := client.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 64)
+			n, err := server.Read(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf[:n], []byte("ping")) {
+				t.Errorf("Data mismatch: expected(%v) actual(%v)", []byte("ping"), buf[:n])
+			}
+		})
+	}
+}
+
+func TestALPN(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	for name, tt := range map[string]struct {
+		clientProtos, serverProtos []string
+		expectedProto              string
+		expectedClientErr          error
+		expectedServerErr          error
+	}{
+		"WebRTC": {
+			clientProtos:  []string{"webrtc", "c-webrtc"},
+			serverProtos:  []string{"webrtc"},
+			expectedProto: "webrtc",
+		},
+		"ServerPreference": {
+			clientProtos:  []string{"coap", "c-webrtc"},
+			serverProtos:  []string{"c-webrtc", "coap"},
+			expectedProto: "c-webrtc",
+		},
+		"ServerWithoutALPN": {
+			clientProtos: []string{"coap"},
+		},
+		"ClientWithoutALPN": {
+			serverProtos: []string{"coap"},
+		},
+		"NoApplicationProtocol": {
+			clientProtos:      []string{"coap"},
+			serverProtos:      []string{"webrtc"},
+			expectedClientErr: errors.New("alert: Alert LevelFatal: NoApplicationProtocol"),
+			expectedServerErr: errALPNNoAppProto,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{NextProtos: tt.clientProtos}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{NextProtos: tt.serverProtos}, true)
+			res := <-c
+
+			if tt.expectedServerErr != nil || tt.expectedClientErr != nil {
+				if !errors.Is(err, tt.expectedServerErr) {
+					t.Errorf("Server error expected: \"%v\" but got \"%v\"", tt.expectedServerErr, err)
+				}
+				if res.err == nil || res.err.Error() != tt.expectedClientErr.Error() {
+					t.Errorf("Client error expected: \"%v\" but got \"%v\"", tt.expectedClientErr, res.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+				_ = server.Close()
+			}()
+
+			if proto := res.c.NegotiatedProtocol(); proto != tt.expectedProto {
+				t.Errorf("Client protocol expected(%q) actual(%q)", tt.expectedProto, proto)
+			}
+			if proto := server.NegotiatedProtocol(); proto != tt.expectedProto {
+				t.Errorf("Server protocol expected(%q) actual(%q)", tt.expectedProto, proto)
+			}
+		})
+	}
+}
+
+func TestHelloExtensions(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	capability := extension.Raw{Type: 0xfe00, Data: []byte{0x01}}
+	errRefused := errors.New("capability refused")
+	echo := func(remote []extension.Raw) []extension.Raw {
+		return remote
+	}
+
+	// The server fails the handshake in the error cases, expectedServerErr
+	// is sent to the client as an alert of expectedAlert
+	for name, tt := range map[string]struct {
+		clientExtensions, serverExtensions func([]extension.Raw) []extension.Raw
+		serverErr                          error
+		expectedClientRemote               []extension.Raw
+		expectedServerRemote               []extension.Raw
+		expectedServerErr                  error
+		expectedAlert                      AlertDescription
+	}{
+		"Negotiated": {
+			clientExtensions:     func([]extension.Raw) []extension.Raw { return []extension.Raw{capability} },
+			serverExtensions:     echo,
+			expectedClientRemote: []extension.Raw{capability},
+			expectedServerRemote: []extension.Raw{capability},
+		},
+		"ServerIgnores": {
+			clientExtensions:     func([]extension.Raw) []extension.Raw { return []extension.Raw{capability} },
+			expectedServerRemote: []extension.Raw{capability},
+		},
+		"NotOffered": {
+			serverExtensions: func([]extension.Raw) []extension.Raw { return []extension.Raw{capability} },
+		},
+		"Refused": {
+			serverErr:         errRefused,
+			expectedServerErr: errRefused,
+			expectedAlert:     AlertHandshakeFailure,
+		},
+		"Implemented": {
+			clientExtensions: func([]extension.Raw) []extension.Raw { return []extension.Raw{capability} },
+			serverExtensions: func([]extension.Raw) []extension.Raw {
+				return []extension.Raw{{Type: extension.ALPNTypeValue, Data: []byte{}}}
+			},
+			expectedServerRemote: []extension.Raw{capability},
+			expectedServerErr:    errHelloExtensionImplemented,
+			expectedAlert:        AlertInternalError,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			var clientRemote, serverRemote []extension.Raw
+			go func() {
+				client, err := testClient(ctx, ca, &Config{
+					NextProtos:      []string{"coap"},
+					HelloExtensions: tt.clientExtensions,
+					OnHelloExtensions: func(remote []extension.Raw) error {
+						clientRemote = remote
+						return nil
+					},
+				}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, cb, &Config{
+				HelloExtensions: tt.serverExtensions,
+				OnHelloExtensions: func(remote []extension.Raw) error {
+					serverRemote = remote
+					return tt.serverErr
+				},
+			}, true)
+			res := <-c
+
+			if tt.expectedServerErr != nil {
+				var serverAlert, clientAlert *AlertError
+				if !errors.As(err, &serverAlert) || serverAlert.Remote || serverAlert.Description != tt.expectedAlert || !errors.Is(err, tt.expectedServerErr) {
+					t.Errorf("Server error expected: \"%v\" with alert %v but got \"%v\"", tt.expectedServerErr, tt.expectedAlert, err)
+				}
+				if !errors.As(res.err, &clientAlert) || !clientAlert.Remote || clientAlert.Description != tt.expectedAlert {
+					t.Errorf("Client error expected: alert %v but got \"%v\"", tt.expectedAlert, res.err)
+				}
+				if !reflect.DeepEqual(serverRemote, tt.expectedServerRemote) {
+					t.Errorf("Server remote extensions expected(%v) actual(%v)", tt.expectedServerRemote, serverRemote)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			defer func() {
+				_ = res.c.Close()
+				_ = server.Close()
+			}()
+
+			if !reflect.DeepEqual(clientRemote, tt.expectedClientRemote) {
+				t.Errorf("Client remote extensions expected(%v) actual(%v)", tt.expectedClientRemote, clientRemote)
+			}
+			if !reflect.DeepEqual(serverRemote, tt.expectedServerRemote) {
+				t.Errorf("Server remote extensions expected(%v) actual(%v)", tt.expectedServerRemote, serverRemote)
+			}
+		})
+	}
+}
+
+func TestHelloExtensionImplemented(t *testing.T) {
+	ca, cb := dpipe.Pipe()
+	defer func() {
+		_ = ca.Close()
+		_ = cb.Close()
+	}()
+
+	// The client fails before sending its ClientHello
+	_, err := Client(ca, &Config{
+		HelloExtensions: func([]extension.Raw) []extension.Raw {
+			return []extension.Raw{{Type: extension.RenegotiationInfoTypeValue, Data: []byte{0x00}}}
+		},
+	})
+	if !errors.Is(err, errHelloExtensionImplemented) {
+		t.Errorf("Client error expected: \"%v\" but got \"%v\"", errHelloExtensionImplemented, err)
+	}
+}
+
+func TestDuplicateHelloExtension(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ca, cb := dpipe.Pipe()
+	defer func() {
+		_ = ca.Close()
+	}()
+
+	raw, err := (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{
+			Version: protocol.Version1_2,
+		},
+		Content: &handshake.Handshake{Message: &handshake.MessageClientHello{
+			Version:            protocol.Version1_2,
+			CipherSuiteIDs:     []uint16{uint16(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256)},
+			CompressionMethods: defaultCompressionMethods(),
+			Extensions: []extension.Extension{
+				&extension.Raw{Type: 0xfe00, Data: []byte{}},
+				&extension.Raw{Type: 0xfe01, Data: []byte{}},
+			},
+		}},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Marshal refuses duplicates, so the second type is rewritten afterwards
+	raw = bytes.Replace(raw, []byte{0xfe, 0x01, 0x00, 0x00}, []byte{0xfe, 0x00, 0x00, 0x00}, 1)
+	if _, err = ca.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = testServer(ctx, cb, &Config{FlightInterval: 50 * time.Millisecond}, true); !errors.Is(err, extension.ErrDuplicateExtension) {
+		t.Fatalf("Server error: expected(%v) actual(%v)", extension.ErrDuplicateExtension, err)
+	}
+
+	buf := make([]byte, 8192)
+	n, err := ca.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recordlayer.RecordLayer{}
+	if err = r.Unmarshal(buf[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := r.Content.(*alert.Alert); !ok || a.Description != AlertDecodeError {
+		t.Errorf("Expected a decode_error alert, got %v", r.Content)
+	}
+}
+
+func TestInvalidRecordsDropped(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ca, cb := dpipe.Pipe()
+	type result struct {
+		c   *Conn
+		err error
+	}
+	c := make(chan result)
+
+	go func() {
+		client, err := testClient(ctx, ca, &Config{}, true)
+		c <- result{client, err}
+	}()
+
+	server, err := testServer(ctx, cb, &Config{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-c
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	client := res.c
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	marshal := func(epoch uint16, content protocol.Content) []byte {
+		raw, err := (&recordlayer.RecordLayer{
+			Header: recordlayer.Header{
+				Epoch:          epoch,
+				SequenceNumber: 100,
+				Version:        protocol.Version1_2,
+			},
+			Content: content,
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	// Spoofed datagrams written next to the client, none of them may
+	// close the connection
+	for name, raw := range map[string][]byte{
+		"Garbage":               {0xff, 0x01, 0x02},
+		"TruncatedHeader":       {0x17, 0xfe, 0xfd, 0x00, 0x01},
+		"UnauthenticatedAlert":  marshal(0, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}),
+		"UnauthenticatedData":   marshal(0, &protocol.ApplicationData{Data: []byte("spoofed")}),
+		"AuthenticationFailure": marshal(1, &protocol.ApplicationData{Data: make([]byte, 64)}),
+	} {
+		if _, err := ca.Write(raw); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	message := []byte("Hello")
+	if _, err := client.Write(message); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], message) {
+		t.Errorf("Data mismatch: expected(%s) actual(%s)", message, buf[:n])
+	}
+	if invalid := server.InvalidRecords(); invalid != 5 {
+		t.Errorf("InvalidRecords: expected(5) actual(%d)", invalid)
+	}
+}
+
+// dropConn drops all writes once drop is set
+type dropConn struct {
+	net.Conn
+	drop int32
+}
+
+func (c *dropConn) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(&c.drop) == 1 {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+func TestCloseWithContext(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	for name, tt := range map[string]struct {
+		wait           bool
+		remoteResponds bool
+		expectedErr    error
+	}{
+		"NoWait": {},
+		"CloseNotify": {
+			wait:           true,
+			remoteResponds: true,
+		},
+		"Timeout": {
+			wait:        true,
+			expectedErr: context.DeadlineExceeded,
+		},
+	} {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			ca, cb := dpipe.Pipe()
+			serverConn := &dropConn{Conn: cb}
+			type result struct {
+				c   *Conn
+				err error
+			}
+			c := make(chan result)
+
+			go func() {
+				client, err := testClient(ctx, ca, &Config{}, true)
+				c <- result{client, err}
+			}()
+
+			server, err := testServer(ctx, serverConn, &Config{}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := <-c
+			if res.err != nil {
+				t.Fatal(res.err)
+			}
+			client := res.c
+			defer func() {
+				_ = server.Close()
+			}()
+
+			if !tt.remoteResponds {
+				atomic.StoreInt32(&serverConn.drop, 1)
+			}
+
+			closeCtx, closeCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer closeCancel()
+			closeWithContext := client.CloseWithContext
+			if tt.wait {
+				closeWithContext = client.CloseAndWait
+			}
+			if err := closeWithContext(closeCtx); err != tt.expectedErr {
+				t.Errorf("CloseWithContext: expected(%v) actual(%v)", tt.expectedErr, err)
+			}
+			if _, err := client.Write([]byte("Hello")); err != ErrConnClosed {
+				t.Errorf("Write after CloseWithContext: expected(%v) actual(%v)", ErrConnClosed, err)
+			}
+			if _, err := server.Read(make([]byte, 64)); err != io.EOF {
+				t.Errorf("Remote Read: expected(%v) actual(%v)", io.EOF, err)
+			}
+		})
+	}
+}
+
+func TestLazyHandshake(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, cb := dpipe.Pipe()
+	client, err := NewClient(ca, &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServer(cb, &Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	// Deadlines can be set before the handshake runs
+	if err = server.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first Read and Write run the handshake
+	message := []byte("Hello")
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, err := server.Read(buf)
+		if err == nil && !bytes.Equal(buf[:n], message) {
+			err = fmt.Errorf("Data mismatch: expected(%s) actual(%s)", message, buf[:n])
+		}
+		readErr <- err
+	}()
+	if _, err = client.Write(message); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-readErr; err != nil {
+		t.Fatal(err)
+	}
+	if err = server.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Handshake is a no-op once the handshake has completed
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = client.Handshake(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandshakeContext(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, _ := dpipe.Pipe()
+	client, err := NewClient(ca, &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote never responds
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err = client.Handshake(ctx); err != errConnectTimeout {
+		t.Fatalf("Handshake: expected(%v) actual(%v)", errConnectTimeout, err)
+	}
+
+	// The error of a failed handshake is returned by later calls
+	if err = client.Handshake(context.Background()); err != errConnectTimeout {
+		t.Errorf("Handshake after failure: expected(%v) actual(%v)", errConnectTimeout, err)
+	}
+	if _, err = client.Write([]byte("Hello")); err != errConnectTimeout {
+		t.Errorf("Write after failure: expected(%v) actual(%v)", errConnectTimeout, err)
+	}
+}
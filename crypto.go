@@ -3,15 +3,18 @@ package dtls
 import (
 	"crypto"
 	"crypto/ecdsa"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/binary"
+	"io"
 	"math/big"
 	"time"
 
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 	"golang.org/x/crypto/ed25519"
 )
 
@@ -19,7 +22,7 @@ type ecdsaSignature struct {
 	R, S *big.Int
 }
 
-func valueKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve namedCurve, hashAlgorithm hashAlgorithm) []byte {
+func valueKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve elliptic.Curve, hashAlgorithm hash.Algorithm) []byte {
 	serverECDHParams := make([]byte, 4)
 	serverECDHParams[0] = 3 // named curve
 	binary.BigEndian.PutUint16(serverECDHParams[1:], uint16(namedCurve))
@@ -30,7 +33,7 @@ func valueKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve
 	plaintext = append(plaintext, serverRandom...)
 	plaintext = append(plaintext, serverECDHParams...)
 	plaintext = append(plaintext, publicKey...)
-	return hashAlgorithm.digest(plaintext)
+	return hashAlgorithm.Digest(plaintext)
 }
 
 // If the client provided a "signature_algorithms" extension, then all
@@ -38,22 +41,22 @@ func valueKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve
 // hash/signature algorithm pair that appears in that extension
 //
 // https://tools.ietf.org/html/rfc5246#section-7.4.2
-func generateKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve namedCurve, privateKey crypto.PrivateKey, hashAlgorithm hashAlgorithm) ([]byte, error) {
+func generateKeySignature(clientRandom, serverRandom, publicKey []byte, namedCurve elliptic.Curve, privateKey crypto.PrivateKey, hashAlgorithm hash.Algorithm, rand io.Reader) ([]byte, error) {
 	hashed := valueKeySignature(clientRandom, serverRandom, publicKey, namedCurve, hashAlgorithm)
 	switch p := privateKey.(type) {
 	case ed25519.PrivateKey:
 		// https://crypto.stackexchange.com/a/55483
-		return p.Sign(rand.Reader, hashed, crypto.Hash(0))
+		return p.Sign(rand, hashed, crypto.Hash(0))
 	case *ecdsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, crypto.SHA256)
+		return p.Sign(rand, hashed, crypto.SHA256)
 	case *rsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, crypto.SHA256)
+		return p.Sign(rand, hashed, crypto.SHA256)
 	}
 
 	return nil, errKeySignatureGenerateUnimplemented
 }
 
-func verifyKeySignature(hash, remoteKeySignature []byte, hashAlgorithm hashAlgorithm, rawCertificates [][]byte) error {
+func verifyKeySignature(hash, remoteKeySignature []byte, hashAlgorithm hash.Algorithm, rawCertificates [][]byte) error {
 	if len(rawCertificates) == 0 {
 		return errLengthMismatch
 	}
@@ -83,7 +86,7 @@ func verifyKeySignature(hash, remoteKeySignature []byte, hashAlgorithm hashAlgor
 	case *rsa.PublicKey:
 		switch certificate.SignatureAlgorithm {
 		case x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA:
-			return rsa.VerifyPKCS1v15(p, hashAlgorithm.cryptoHash(), hash, remoteKeySignature)
+			return rsa.VerifyPKCS1v15(p, hashAlgorithm.CryptoHash(), hash, remoteKeySignature)
 		}
 	}
 
@@ -98,7 +101,7 @@ func verifyKeySignature(hash, remoteKeySignature []byte, hashAlgorithm hashAlgor
 // CertificateVerify message is sent to explicitly verify possession of
 // the private key in the certificate.
 // https://tools.ietf.org/html/rfc5246#section-7.3
-func generateCertificateVerify(handshakeBodies []byte, privateKey crypto.PrivateKey) ([]byte, error) {
+func generateCertificateVerify(handshakeBodies []byte, privateKey crypto.PrivateKey, rand io.Reader) ([]byte, error) {
 	h := sha256.New()
 	if _, err := h.Write(handshakeBodies); err != nil {
 		return nil, err
@@ -108,17 +111,17 @@ func generateCertificateVerify(handshakeBodies []byte, privateKey crypto.Private
 	switch p := privateKey.(type) {
 	case ed25519.PrivateKey:
 		// https://crypto.stackexchange.com/a/55483
-		return p.Sign(rand.Reader, hashed, crypto.Hash(0))
+		return p.Sign(rand, hashed, crypto.Hash(0))
 	case *ecdsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, crypto.SHA256)
+		return p.Sign(rand, hashed, crypto.SHA256)
 	case *rsa.PrivateKey:
-		return p.Sign(rand.Reader, hashed, crypto.SHA256)
+		return p.Sign(rand, hashed, crypto.SHA256)
 	}
 
 	return nil, errInvalidSignatureAlgorithm
 }
 
-func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hashAlgorithm, remoteKeySignature []byte, rawCertificates [][]byte) error {
+func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hash.Algorithm, remoteKeySignature []byte, rawCertificates [][]byte) error {
 	if len(rawCertificates) == 0 {
 		return errLengthMismatch
 	}
@@ -127,7 +130,7 @@ func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hashAlgorithm
 		return err
 	}
 
-	hash := hashAlgorithm.digest(handshakeBodies)
+	hash := hashAlgorithm.Digest(handshakeBodies)
 	switch p := certificate.PublicKey.(type) {
 	case ed25519.PublicKey:
 		if ok := ed25519.Verify(p, hash, remoteKeySignature); !ok {
@@ -149,7 +152,7 @@ func verifyCertificateVerify(handshakeBodies []byte, hashAlgorithm hashAlgorithm
 	case *rsa.PublicKey:
 		switch certificate.SignatureAlgorithm {
 		case x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA:
-			return rsa.VerifyPKCS1v15(p, hashAlgorithm.cryptoHash(), hash, remoteKeySignature)
+			return rsa.VerifyPKCS1v15(p, hashAlgorithm.CryptoHash(), hash, remoteKeySignature)
 		}
 	}
 
@@ -172,7 +175,7 @@ func loadCerts(rawCertificates [][]byte) ([]*x509.Certificate, error) {
 	return certs, nil
 }
 
-func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool) (chains [][]*x509.Certificate, err error) {
+func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool, currentTime time.Time) (chains [][]*x509.Certificate, err error) {
 	certificate, err := loadCerts(rawCertificates)
 	if err != nil {
 		return nil, err
@@ -183,14 +186,14 @@ func verifyClientCert(rawCertificates [][]byte, roots *x509.CertPool) (chains []
 	}
 	opts := x509.VerifyOptions{
 		Roots:         roots,
-		CurrentTime:   time.Now(),
+		CurrentTime:   currentTime,
 		Intermediates: intermediateCAPool,
 		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 	}
 	return certificate[0].Verify(opts)
 }
 
-func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName string) (chains [][]*x509.Certificate, err error) {
+func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName string, currentTime time.Time) (chains [][]*x509.Certificate, err error) {
 	certificate, err := loadCerts(rawCertificates)
 	if err != nil {
 		return nil, err
@@ -201,23 +204,30 @@ func verifyServerCert(rawCertificates [][]byte, roots *x509.CertPool, serverName
 	}
 	opts := x509.VerifyOptions{
 		Roots:         roots,
-		CurrentTime:   time.Now(),
+		CurrentTime:   currentTime,
 		DNSName:       serverName,
 		Intermediates: intermediateCAPool,
 	}
 	return certificate[0].Verify(opts)
 }
 
-func generateAEADAdditionalData(h *recordLayerHeader, payloadLen int) []byte {
-	var additionalData [13]byte
+// aeadAdditionalDataLength is the length of the additional data of AEAD ciphers
+const aeadAdditionalDataLength = 13
+
+func generateAEADAdditionalData(h *recordlayer.Header, payloadLen int) []byte {
+	var additionalData [aeadAdditionalDataLength]byte
+	putAEADAdditionalData(&additionalData, h, payloadLen)
+	return additionalData[:]
+}
+
+// putAEADAdditionalData is generateAEADAdditionalData without allocating
+func putAEADAdditionalData(additionalData *[aeadAdditionalDataLength]byte, h *recordlayer.Header, payloadLen int) {
 	// SequenceNumber MUST be set first
 	// we only want uint48, clobbering an extra 2 (using uint64, Golang doesn't have uint48)
-	binary.BigEndian.PutUint64(additionalData[:], h.sequenceNumber)
-	binary.BigEndian.PutUint16(additionalData[:], h.epoch)
-	additionalData[8] = byte(h.contentType)
-	additionalData[9] = h.protocolVersion.major
-	additionalData[10] = h.protocolVersion.minor
+	binary.BigEndian.PutUint64(additionalData[:], h.SequenceNumber)
+	binary.BigEndian.PutUint16(additionalData[:], h.Epoch)
+	additionalData[8] = byte(h.ContentType)
+	additionalData[9] = h.Version.Major
+	additionalData[10] = h.Version.Minor
 	binary.BigEndian.PutUint16(additionalData[len(additionalData)-2:], uint16(payloadLen))
-
-	return additionalData[:]
 }
@@ -4,9 +4,13 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/sha1" // #nosec
+	"crypto/subtle"
 	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 // block ciphers using cipher block chaining.
@@ -19,12 +23,20 @@ type cbcMode interface {
 type cryptoCBC struct {
 	writeCBC, readCBC cbcMode
 	writeMac, readMac []byte
+
+	// MAC the ciphertext instead of the plaintext https://tools.ietf.org/html/rfc7366
+	encryptThenMAC bool
+
+	// State of remoteMAC, reused as records are received by a single goroutine
+	readHMAC      hash.Hash
+	readMACHeader [aeadAdditionalDataLength]byte
+	readMACSum    [sha1.Size]byte
 }
 
 // Currently hardcoded to be SHA1 only
 var cryptoCBCMacFunc = sha1.New
 
-func newCryptoCBC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, remoteMac []byte) (*cryptoCBC, error) {
+func newCryptoCBC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, remoteMac []byte, encryptThenMAC bool) (*cryptoCBC, error) {
 	writeBlock, err := aes.NewCipher(localKey)
 	if err != nil {
 		return nil, err
@@ -41,22 +53,26 @@ func newCryptoCBC(localKey, localWriteIV, localMac, remoteKey, remoteWriteIV, re
 
 		readCBC: cipher.NewCBCDecrypter(readBlock, remoteWriteIV).(cbcMode),
 		readMac: remoteMac,
+
+		encryptThenMAC: encryptThenMAC,
 	}, nil
 }
 
-func (c *cryptoCBC) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
-	payload := raw[recordLayerHeaderSize:]
-	raw = raw[:recordLayerHeaderSize]
+func (c *cryptoCBC) encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error) {
+	payload := raw[recordlayer.HeaderSize:]
+	raw = raw[:recordlayer.HeaderSize]
 	blockSize := c.writeCBC.BlockSize()
 
 	// Generate + Append MAC
-	h := pkt.recordLayerHeader
-
-	MAC, err := prfMac(h.epoch, h.sequenceNumber, h.contentType, h.protocolVersion, payload, c.writeMac)
-	if err != nil {
-		return nil, err
+	h := pkt.Header
+
+	if !c.encryptThenMAC {
+		MAC, err := prfMac(h.Epoch, h.SequenceNumber, h.ContentType, h.Version, payload, c.writeMac)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, MAC...)
 	}
-	payload = append(payload, MAC...)
 
 	// Generate + Append padding
 	padding := make([]byte, blockSize-len(payload)%blockSize)
@@ -68,7 +84,7 @@ func (c *cryptoCBC) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
 
 	// Generate IV
 	iv := make([]byte, blockSize)
-	if _, err := rand.Read(iv); err != nil {
+	if _, err := io.ReadFull(rand, iv); err != nil {
 		return nil, err
 	}
 
@@ -77,28 +93,38 @@ func (c *cryptoCBC) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
 	c.writeCBC.CryptBlocks(payload, payload)
 	payload = append(iv, payload...)
 
+	// The MAC covers the IV and ciphertext, with the length of both
+	if c.encryptThenMAC {
+		MAC, err := prfMac(h.Epoch, h.SequenceNumber, h.ContentType, h.Version, payload, c.writeMac)
+		if err != nil {
+			return nil, err
+		}
+		payload = append(payload, MAC...)
+	}
+
 	// Prepend unencrypte header with encrypted payload
 	raw = append(raw, payload...)
 
 	// Update recordLayer size to include IV+MAC+Padding
-	binary.BigEndian.PutUint16(raw[recordLayerHeaderSize-2:], uint16(len(raw)-recordLayerHeaderSize))
+	binary.BigEndian.PutUint16(raw[recordlayer.HeaderSize-2:], uint16(len(raw)-recordlayer.HeaderSize))
 
 	return raw, nil
 }
 
 func (c *cryptoCBC) decrypt(in []byte) ([]byte, error) {
-	body := in[recordLayerHeaderSize:]
+	if c.encryptThenMAC {
+		return c.decryptThenMAC(in)
+	}
+
+	body := in[recordlayer.HeaderSize:]
 	blockSize := c.readCBC.BlockSize()
 	mac := cryptoCBCMacFunc()
 
-	var h recordLayerHeader
+	var h recordlayer.Header
 	err := h.Unmarshal(in)
 	switch {
 	case err != nil:
 		return nil, err
-	case h.contentType == contentTypeChangeCipherSpec:
-		// Nothing to encrypt with ChangeCipherSpec
-		return in, nil
 	case len(body)%blockSize != 0 || len(body) < blockSize+max(mac.Size()+1, blockSize):
 		return nil, errNotEnoughRoomForNonce
 	}
@@ -115,19 +141,89 @@ func (c *cryptoCBC) decrypt(in []byte) ([]byte, error) {
 	paddingLen, paddingGood := examinePadding(body)
 
 	macSize := mac.Size()
-	if len(body) < macSize {
-		return nil, errInvalidMAC
-	}
-
 	dataEnd := len(body) - macSize - paddingLen
+	// if dataEnd < 0 { dataEnd = 0 }, bad padding is reported with the MAC
+	dataEnd = subtle.ConstantTimeSelect(int(uint32(dataEnd)>>31), 0, dataEnd)
 
 	expectedMAC := body[dataEnd : dataEnd+macSize]
-	actualMAC, err := prfMac(h.epoch, h.sequenceNumber, h.contentType, h.protocolVersion, body[:dataEnd], c.readMac)
+
+	// Hash the padding after the MAC is computed, so the time taken
+	// does not depend on the length of the padding (Lucky Thirteen)
+	actualMAC, err := c.remoteMAC(&h, body[:dataEnd], body[dataEnd+macSize:])
 
 	// Compute Local MAC and compare
-	if paddingGood != 255 || err != nil || !hmac.Equal(actualMAC, expectedMAC) {
+	if err != nil || subtle.ConstantTimeCompare(actualMAC, expectedMAC) != 1 || paddingGood != 255 {
+		return nil, errInvalidMAC
+	}
+
+	return append(in[:recordlayer.HeaderSize], body[:dataEnd]...), nil
+}
+
+// decryptThenMAC authenticates the record before it is decrypted, as
+// negotiated by the encrypt_then_mac extension
+func (c *cryptoCBC) decryptThenMAC(in []byte) ([]byte, error) {
+	body := in[recordlayer.HeaderSize:]
+	blockSize := c.readCBC.BlockSize()
+	macSize := cryptoCBCMacFunc().Size()
+
+	var h recordlayer.Header
+	err := h.Unmarshal(in)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(body) < 2*blockSize+macSize || (len(body)-macSize)%blockSize != 0:
+		return nil, errNotEnoughRoomForNonce
+	}
+
+	expectedMAC := body[len(body)-macSize:]
+	body = body[:len(body)-macSize]
+
+	actualMAC, err := c.remoteMAC(&h, body, nil)
+	if err != nil || !hmac.Equal(actualMAC, expectedMAC) {
 		return nil, errInvalidMAC
 	}
 
-	return append(in[:recordLayerHeaderSize], body[:dataEnd]...), nil
+	// Set + remove per record IV
+	c.readCBC.SetIV(body[:blockSize])
+	body = body[blockSize:]
+
+	// Decrypt, the padding can be checked in variable time as the record is authentic
+	c.readCBC.CryptBlocks(body, body)
+
+	paddingLen := int(body[len(body)-1]) + 1
+	if paddingLen > len(body) {
+		return nil, errInvalidPadding
+	}
+	for _, b := range body[len(body)-paddingLen:] {
+		if int(b) != paddingLen-1 {
+			return nil, errInvalidPadding
+		}
+	}
+
+	return append(in[:recordlayer.HeaderSize], body[:len(body)-paddingLen]...), nil
+}
+
+// remoteMAC computes the MAC of a received record over payload, and then
+// feeds extra to the hash so the work done is independent of the payload
+// length
+func (c *cryptoCBC) remoteMAC(h *recordlayer.Header, payload, extra []byte) ([]byte, error) {
+	if c.readHMAC == nil {
+		c.readHMAC = hmac.New(cryptoCBCMacFunc, c.readMac)
+	}
+	c.readHMAC.Reset()
+
+	// The MAC covers the same fields as the additional data of AEAD ciphers
+	putAEADAdditionalData(&c.readMACHeader, h, len(payload))
+	if _, err := c.readHMAC.Write(c.readMACHeader[:]); err != nil {
+		return nil, err
+	} else if _, err := c.readHMAC.Write(payload); err != nil {
+		return nil, err
+	}
+
+	out := c.readHMAC.Sum(c.readMACSum[:0])
+	if _, err := c.readHMAC.Write(extra); err != nil {
+		return nil, err
+	}
+
+	return out, nil
 }
@@ -0,0 +1,87 @@
+package dtls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+func TestCryptoCBC(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, 32)
+	iv := bytes.Repeat([]byte{0x02}, 16)
+	mac := bytes.Repeat([]byte{0x03}, 20)
+
+	for _, encryptThenMAC := range []bool{false, true} {
+		local, err := newCryptoCBC(key, iv, mac, key, iv, mac, encryptThenMAC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		remote, err := newCryptoCBC(key, iv, mac, key, iv, mac, encryptThenMAC)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, size := range []int{0, 1, 15, 16, 17, 255} {
+			pkt := &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					ContentType:    protocol.ContentTypeApplicationData,
+					Version:        protocol.Version1_2,
+					Epoch:          1,
+					SequenceNumber: uint64(size),
+				},
+				Content: &protocol.ApplicationData{Data: bytes.Repeat([]byte{0xAA}, size)},
+			}
+			raw, err := pkt.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := append([]byte{}, raw...)
+
+			encrypted, err := local.encrypt(pkt, raw, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tampered := append([]byte{}, encrypted...)
+			tampered[len(tampered)-1] ^= 0x01
+			if _, err := remote.decrypt(tampered); err == nil {
+				t.Errorf("encryptThenMAC(%v) size(%d): tampered record was accepted", encryptThenMAC, size)
+			}
+
+			decrypted, err := remote.decrypt(encrypted)
+			if err != nil {
+				t.Fatalf("encryptThenMAC(%v) size(%d): %v", encryptThenMAC, size, err)
+			}
+			// Only the length in the header is changed by encryption
+			if !bytes.Equal(decrypted[recordlayer.HeaderSize:], expected[recordlayer.HeaderSize:]) {
+				t.Errorf("encryptThenMAC(%v) size(%d): got %#v, want %#v", encryptThenMAC, size, decrypted, expected)
+			}
+		}
+	}
+}
+
+func TestCryptoCBCInvalidPadding(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, 32)
+	iv := bytes.Repeat([]byte{0x02}, 16)
+	mac := bytes.Repeat([]byte{0x03}, 20)
+
+	c, err := newCryptoCBC(key, iv, mac, key, iv, mac, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A padding length longer than the record must not panic
+	body := bytes.Repeat([]byte{0xFF}, 48)
+	c.writeCBC.SetIV(iv)
+	c.writeCBC.CryptBlocks(body[16:], body[16:])
+
+	raw := append([]byte{
+		byte(protocol.ContentTypeApplicationData), 0xfe, 0xfd, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x30,
+	}, append(iv, body[16:]...)...)
+	if _, err := c.decrypt(raw); err != errInvalidMAC {
+		t.Errorf("expected %v, got %v", errInvalidMAC, err)
+	}
+}
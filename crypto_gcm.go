@@ -3,9 +3,11 @@ package dtls
 import (
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"io"
+
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 const cryptoGCMTagLength = 16
@@ -15,6 +17,10 @@ const cryptoGCMNonceLength = 12
 type cryptoGCM struct {
 	localGCM, remoteGCM         cipher.AEAD
 	localWriteIV, remoteWriteIV []byte
+
+	// Scratch space of decrypt, records are received by a single goroutine
+	remoteNonce          [cryptoGCMNonceLength]byte
+	remoteAdditionalData [aeadAdditionalDataLength]byte
 }
 
 func newCryptoGCM(localKey, localWriteIV, remoteKey, remoteWriteIV []byte) (*cryptoGCM, error) {
@@ -44,17 +50,17 @@ func newCryptoGCM(localKey, localWriteIV, remoteKey, remoteWriteIV []byte) (*cry
 	}, nil
 }
 
-func (c *cryptoGCM) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
-	payload := raw[recordLayerHeaderSize:]
-	raw = raw[:recordLayerHeaderSize]
+func (c *cryptoGCM) encrypt(pkt *recordlayer.RecordLayer, raw []byte, rand io.Reader) ([]byte, error) {
+	payload := raw[recordlayer.HeaderSize:]
+	raw = raw[:recordlayer.HeaderSize]
 
 	nonce := make([]byte, cryptoGCMNonceLength)
 	copy(nonce, c.localWriteIV[:4])
-	if _, err := rand.Read(nonce[4:]); err != nil {
+	if _, err := io.ReadFull(rand, nonce[4:]); err != nil {
 		return nil, err
 	}
 
-	additionalData := generateAEADAdditionalData(&pkt.recordLayerHeader, len(payload))
+	additionalData := generateAEADAdditionalData(&pkt.Header, len(payload))
 	encryptedPayload := c.localGCM.Seal(nil, nonce, payload, additionalData)
 	r := make([]byte, len(raw)+len(nonce[4:])+len(encryptedPayload))
 	copy(r, raw)
@@ -62,31 +68,29 @@ func (c *cryptoGCM) encrypt(pkt *recordLayer, raw []byte) ([]byte, error) {
 	copy(r[len(raw)+len(nonce[4:]):], encryptedPayload)
 
 	// Update recordLayer size to include explicit nonce
-	binary.BigEndian.PutUint16(r[recordLayerHeaderSize-2:], uint16(len(r)-recordLayerHeaderSize))
+	binary.BigEndian.PutUint16(r[recordlayer.HeaderSize-2:], uint16(len(r)-recordlayer.HeaderSize))
 	return r, nil
 }
 
 func (c *cryptoGCM) decrypt(in []byte) ([]byte, error) {
-	var h recordLayerHeader
+	var h recordlayer.Header
 	err := h.Unmarshal(in)
 	switch {
 	case err != nil:
 		return nil, err
-	case h.contentType == contentTypeChangeCipherSpec:
-		// Nothing to encrypt with ChangeCipherSpec
-		return in, nil
-	case len(in) <= (8 + recordLayerHeaderSize):
+	case len(in) <= (8 + recordlayer.HeaderSize):
 		return nil, errNotEnoughRoomForNonce
 	}
 
-	nonce := make([]byte, 0, cryptoGCMNonceLength)
-	nonce = append(append(nonce, c.remoteWriteIV[:4]...), in[recordLayerHeaderSize:recordLayerHeaderSize+8]...)
-	out := in[recordLayerHeaderSize+8:]
+	nonce := c.remoteNonce[:]
+	copy(nonce, c.remoteWriteIV[:4])
+	copy(nonce[4:], in[recordlayer.HeaderSize:recordlayer.HeaderSize+8])
+	out := in[recordlayer.HeaderSize+8:]
 
-	additionalData := generateAEADAdditionalData(&h, len(out)-cryptoGCMTagLength)
-	out, err = c.remoteGCM.Open(out[:0], nonce, out, additionalData)
+	putAEADAdditionalData(&c.remoteAdditionalData, &h, len(out)-cryptoGCMTagLength)
+	out, err = c.remoteGCM.Open(out[:0], nonce, out, c.remoteAdditionalData[:])
 	if err != nil {
 		return nil, fmt.Errorf("decryptPacket: %v", err)
 	}
-	return append(in[:recordLayerHeaderSize], out...), nil
+	return append(in[:recordlayer.HeaderSize], out...), nil
 }
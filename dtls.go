@@ -1,2 +1,6 @@
 // Package dtls implements Datagram Transport Layer Security (DTLS) 1.2
 package dtls
+
+// VersionDTLS12 is the DTLS version in the same style as
+// VersionTLSXX from crypto/tls
+const VersionDTLS12 = 0xfefd
@@ -19,6 +19,7 @@ const (
 
 /*
   DTLS Client/Server over a lossy transport, just asserts it can handle at increasing increments
+  of loss, and packets which are reordered
 */
 func TestPionE2ELossy(t *testing.T) {
 	type runResult struct {
@@ -38,6 +39,7 @@ func TestPionE2ELossy(t *testing.T) {
 
 	for _, test := range []struct {
 		LossChanceRange int
+		ReorderChance   int
 		DoClientAuth    bool
 		CipherSuites    []dtls.CipherSuiteID
 		MTU             int
@@ -101,8 +103,37 @@ func TestPionE2ELossy(t *testing.T) {
 			MTU:             100,
 			DoClientAuth:    true,
 		},
+		{
+			LossChanceRange: 0,
+			ReorderChance:   50,
+		},
+		{
+			LossChanceRange: 10,
+			ReorderChance:   50,
+			DoClientAuth:    true,
+		},
+		{
+			LossChanceRange: 10,
+			ReorderChance:   50,
+			CipherSuites:    []dtls.CipherSuiteID{dtls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA},
+		},
+		{
+			LossChanceRange: 0,
+			ReorderChance:   50,
+			MTU:             100,
+			DoClientAuth:    true,
+		},
+		{
+			LossChanceRange: 20,
+			ReorderChance:   50,
+			MTU:             100,
+			DoClientAuth:    true,
+		},
 	} {
 		name := fmt.Sprintf("Loss%d_MTU%d", test.LossChanceRange, test.MTU)
+		if test.ReorderChance > 0 {
+			name += fmt.Sprintf("_Reorder%d", test.ReorderChance)
+		}
 		if test.DoClientAuth {
 			name += "_WithCliAuth"
 		}
@@ -159,6 +190,12 @@ func TestPionE2ELossy(t *testing.T) {
 					break
 				}
 
+				// Reverse the packets in flight, queues of less than two packets are left alone
+				if test.ReorderChance > 0 && rand.Intn(100) < test.ReorderChance {
+					_ = br.Reorder(0)
+					_ = br.Reorder(1)
+				}
+
 				br.Tick()
 				select {
 				case serverResult := <-serverDone:
@@ -3,6 +3,9 @@
 package e2e
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
@@ -16,6 +19,8 @@ import (
 	"time"
 
 	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/transport/test"
 )
 
 func serverOpenSSL(c *comm) {
@@ -244,3 +249,97 @@ func TestPionOpenSSLE2EMTUs(t *testing.T) {
 		testPionE2EMTUs(t, serverPion, clientOpenSSL)
 	})
 }
+
+// Keying material exported with and without context must match OpenSSL
+func TestPionOpenSSLE2EExportKeyingMaterial(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	const label = "EXPERIMENTAL_pion"
+	const length = 32
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cert, err := selfsign.GenerateSelfSignedWithDNS("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &dtls.Config{Certificates: []tls.Certificate{cert}}
+	certPEM, keyPEM, err := writeTempPEM(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(certPEM)
+		_ = os.Remove(keyPEM)
+	}()
+
+	// OpenSSL prints the keying material it exports after the handshake
+	port := randomPort(t)
+	// #nosec G204
+	cmd := exec.CommandContext(ctx, "openssl", "s_server",
+		"-dtls1_2",
+		fmt.Sprintf("-accept=%d", port),
+		fmt.Sprintf("-cert=%s", certPEM),
+		fmt.Sprintf("-key=%s", keyPEM),
+		fmt.Sprintf("-keymatexport=%s", label),
+		fmt.Sprintf("-keymatexportlen=%d", length),
+	)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	}()
+	opensslKeyingMaterial := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "Keying material: ") {
+				opensslKeyingMaterial <- strings.TrimPrefix(line, "Keying material: ")
+			}
+		}
+	}()
+
+	// Ensure that server has started
+	time.Sleep(500 * time.Millisecond)
+
+	client, err := dtls.DialWithContext(ctx, "udp",
+		&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: port},
+		&dtls.Config{
+			CipherSuites:       []dtls.CipherSuiteID{dtls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			InsecureSkipVerify: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	keyingMaterial, err := client.ExportKeyingMaterial(label, nil, length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case expected := <-opensslKeyingMaterial:
+		if actual := fmt.Sprintf("%X", keyingMaterial); actual != expected {
+			t.Errorf("ExportKeyingMaterial without context: expected(%s) actual(%s)", expected, actual)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for OpenSSL keying material")
+	}
+
+}
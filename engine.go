@@ -0,0 +1,224 @@
+package dtls
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Engine is the sans-IO core of a DTLS connection, the same handshake and
+// record processing Conn runs with goroutines reading from and writing to
+// a net.Conn. An Engine starts no goroutines and owns no timers, it is
+// driven by passing in received datagrams and the current time. Datagrams
+// to send and decrypted application data are collected until they are
+// taken with Outgoing and Received, NextTimeout returns when HandleTimeout
+// has to be called next to retransmit. This allows an event loop to serve
+// many connections without a goroutine per connection.
+//
+// An Engine is not safe for concurrent use. The handshake is not limited
+// by ConnectContextMaker, the caller has to give up on a remote which
+// does not finish it.
+type Engine struct {
+	conn  *Conn
+	clock *engineClock
+
+	outgoing [][]byte
+	received [][]byte
+	err      error
+	started  bool
+}
+
+// engineClock is the Clock of an Engine, the time is passed in by the caller
+type engineClock struct {
+	Clock
+	now time.Time
+}
+
+func (c *engineClock) Now() time.Time {
+	return c.now
+}
+
+// NewClientEngine creates an Engine for the client side of a connection
+func NewClientEngine(config *Config) (*Engine, error) {
+	switch {
+	case config == nil:
+		return nil, errNoConfigProvided
+	case config.PSK != nil && config.PSKIdentityHint == nil:
+		return nil, errPSKAndIdentityMustBeSetForClient
+	}
+
+	return newEngine(clientFlightHandler, clientHandshakeHandler, config, true)
+}
+
+// NewServerEngine creates an Engine for the server side of a connection
+func NewServerEngine(config *Config) (*Engine, error) {
+	switch {
+	case config == nil:
+		return nil, errNoConfigProvided
+	case config.PSK == nil && len(config.Certificates) == 0:
+		return nil, errServerMustHaveCertificate
+	}
+
+	return newEngine(serverFlightHandler, serverHandshakeHandler, config, false)
+}
+
+func newEngine(flightHandler flightHandler, handshakeMessageHandler handshakeMessageHandler, config *Config, isClient bool) (*Engine, error) {
+	clock := &engineClock{Clock: config.clock(), now: config.clock().Now()}
+
+	// The core reads the time from the Engine instead of the Config
+	engineConfig := *config
+	engineConfig.Clock = clock
+
+	c, err := createCore(nil, flightHandler, handshakeMessageHandler, &engineConfig, isClient)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Engine{conn: c, clock: clock}
+	c.sansIO = true
+	c.sendDatagrams = func(datagrams [][]byte) error {
+		e.outgoing = append(e.outgoing, datagrams...)
+		return nil
+	}
+	c.deliverData = func(b []byte) {
+		// b may share memory with the datagram passed to HandleDatagram
+		e.received = append(e.received, append([]byte{}, b...))
+	}
+	return e, nil
+}
+
+// Start starts the handshake, a client sends its ClientHello. It is
+// called by the first HandleDatagram or HandleTimeout if needed.
+func (e *Engine) Start(now time.Time) error {
+	return e.handle(now, nil)
+}
+
+// HandleDatagram processes a datagram received from the remote. Invalid
+// records are dropped. io.EOF is returned once the remote closed the
+// connection, any other error means the connection failed.
+func (e *Engine) HandleDatagram(now time.Time, datagram []byte) error {
+	return e.handle(now, func() error {
+		return e.conn.handleDatagram(datagram, nil)
+	})
+}
+
+// HandleTimeout retransmits the last flight of the handshake if the
+// timeout returned by NextTimeout has passed.
+func (e *Engine) HandleTimeout(now time.Time) error {
+	return e.handle(now, nil)
+}
+
+// Write encrypts p as application data, the handshake must be complete.
+func (e *Engine) Write(now time.Time, p []byte) error {
+	if !e.HandshakeComplete() {
+		return errHandshakeInProgress
+	}
+	return e.handle(now, func() error {
+		if e.conn.connectionClosed.Err() != nil {
+			return ErrConnClosed
+		}
+		return e.conn.writeApplicationData(p)
+	})
+}
+
+// Close sends a close_notify to the remote and closes the Engine.
+func (e *Engine) Close(now time.Time) error {
+	e.clock.now = now
+	return e.conn.close()
+}
+
+// CancelHandshake aborts the handshake in progress, see
+// Conn.CancelHandshake. The user_canceled and close_notify alerts are
+// returned by Outgoing.
+func (e *Engine) CancelHandshake(now time.Time) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.clock.now = now
+	if err := e.conn.cancelHandshake(); err != nil {
+		return err
+	}
+	e.fail(ErrHandshakeCanceled)
+	return nil
+}
+
+// Outgoing returns the datagrams to send to the remote since the last call
+func (e *Engine) Outgoing() [][]byte {
+	out := e.outgoing
+	e.outgoing = nil
+	return out
+}
+
+// Received returns the application data received since the last call
+func (e *Engine) Received() [][]byte {
+	out := e.received
+	e.received = nil
+	return out
+}
+
+// NextTimeout returns when HandleTimeout has to be called to retransmit,
+// false is returned if no retransmission is pending.
+func (e *Engine) NextTimeout() (time.Time, bool) {
+	if !e.conn.flightsRunning || e.err != nil {
+		return time.Time{}, false
+	}
+	return e.conn.nextFlightTimeout, true
+}
+
+// HandshakeComplete returns true once the handshake finished successfully
+func (e *Engine) HandshakeComplete() bool {
+	return e.conn.isHandshakeCompletedSuccessfully()
+}
+
+// State returns a copy of the state of the connection, it can be passed
+// to Resume to continue the connection with a Conn
+func (e *Engine) State() (*State, error) {
+	return e.conn.state.clone()
+}
+
+// ExportKeyingMaterial exports keying material, see Conn.ExportKeyingMaterial
+func (e *Engine) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	return e.conn.ExportKeyingMaterial(label, context, length)
+}
+
+// handle runs f and then the handshake worker at time now, the error
+// that failed the connection is returned by all later calls
+func (e *Engine) handle(now time.Time, f func() error) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.clock.now = now
+
+	c := e.conn
+	if !e.started {
+		e.started = true
+		atomic.StoreInt32(&c.handshakeStarted, 1)
+		c.startHandshakeOutbound()
+	}
+
+	if f != nil {
+		if err := f(); err != nil {
+			e.fail(err)
+			return err
+		}
+	}
+	c.runFlights(now)
+
+	if err := c.handshakeErr.load(); err != nil {
+		e.fail(err)
+		return err
+	}
+	if !c.isHandshakeCompletedSuccessfully() && c.handshakeDoneSignal.Err() != nil {
+		c.setHandshakeCompletedSuccessfully()
+	}
+	if c.connectionClosed.Err() != nil {
+		e.err = io.EOF
+		return io.EOF
+	}
+	return nil
+}
+
+func (e *Engine) fail(err error) {
+	e.err = err
+	_ = e.conn.close()
+}
@@ -0,0 +1,356 @@
+package dtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/transport/test"
+)
+
+// pumpEngines delivers the datagrams of both engines to each other
+// until neither has anything left to send
+func pumpEngines(t *testing.T, now time.Time, a, b *Engine) {
+	for i := 0; i < 100; i++ {
+		outA, outB := a.Outgoing(), b.Outgoing()
+		if len(outA) == 0 && len(outB) == 0 {
+			return
+		}
+		for _, d := range outA {
+			if err := b.HandleDatagram(now, d); err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+		}
+		for _, d := range outB {
+			if err := a.HandleDatagram(now, d); err != nil && err != io.EOF {
+				t.Fatal(err)
+			}
+		}
+	}
+	t.Fatal("Engines did not settle")
+}
+
+func TestEngine(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true, SRTPProtectionProfiles: []SRTPProtectionProfile{SRTP_AES128_CM_HMAC_SHA1_80}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerEngine(&Config{Certificates: []tls.Certificate{cert}, SRTPProtectionProfiles: []SRTPProtectionProfile{SRTP_AES128_CM_HMAC_SHA1_80}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	if err = server.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	pumpEngines(t, now, client, server)
+
+	if !client.HandshakeComplete() || !server.HandshakeComplete() {
+		t.Fatal("Handshake did not complete")
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("Engines started %d goroutines", n-goroutines)
+	}
+	if _, ok := client.NextTimeout(); ok {
+		t.Error("NextTimeout: retransmission pending after the handshake")
+	}
+
+	clientKeys, err := client.ExportKeyingMaterial("EXTRACTOR-test", nil, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverKeys, err := server.ExportKeyingMaterial("EXTRACTOR-test", nil, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientKeys, serverKeys) {
+		t.Errorf("ExportKeyingMaterial mismatch: %x != %x", clientKeys, serverKeys)
+	}
+
+	for _, message := range [][]byte{[]byte("Hello"), []byte("World")} {
+		if err = client.Write(now, message); err != nil {
+			t.Fatal(err)
+		}
+		pumpEngines(t, now, client, server)
+		received := server.Received()
+		if len(received) != 1 || !bytes.Equal(received[0], message) {
+			t.Errorf("Received: expected(%s) actual(%s)", message, received)
+		}
+	}
+
+	// The close_notify of the client closes the server
+	if err = client.Close(now); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range client.Outgoing() {
+		err = server.HandleDatagram(now, d)
+	}
+	if err != io.EOF {
+		t.Errorf("HandleDatagram after close_notify: expected(%v) actual(%v)", io.EOF, err)
+	}
+}
+
+func TestEngineRetransmission(t *testing.T) {
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true, FlightInterval: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	clientHello := client.Outgoing()
+	if len(clientHello) != 1 {
+		t.Fatalf("Start: expected 1 datagram, got %d", len(clientHello))
+	}
+
+	timeout, ok := client.NextTimeout()
+	if !ok || !timeout.Equal(now.Add(time.Second)) {
+		t.Fatalf("NextTimeout: expected(%v) actual(%v, %v)", now.Add(time.Second), timeout, ok)
+	}
+	if err = client.HandleTimeout(timeout.Add(-time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if out := client.Outgoing(); len(out) != 0 {
+		t.Errorf("HandleTimeout before the timeout: expected no datagrams, got %d", len(out))
+	}
+
+	if err = client.HandleTimeout(timeout); err != nil {
+		t.Fatal(err)
+	}
+	retransmitted := client.Outgoing()
+	if len(retransmitted) != 1 || !bytes.Equal(retransmitted[0][recordlayer.HeaderSize:], clientHello[0][recordlayer.HeaderSize:]) {
+		t.Errorf("HandleTimeout: ClientHello was not retransmitted")
+	}
+	if next, _ := client.NextTimeout(); !next.Equal(timeout.Add(time.Second)) {
+		t.Errorf("NextTimeout: expected(%v) actual(%v)", timeout.Add(time.Second), next)
+	}
+}
+
+func TestEngineConn(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An Engine driven by a goroutine interoperates with a Conn
+	ca, cb := dpipe.Pipe()
+	message := []byte("Hello")
+	clientErr := make(chan error, 1)
+	go func() {
+		defer func() {
+			_ = ca.Close()
+		}()
+		write := func() error {
+			for _, d := range client.Outgoing() {
+				if _, err := ca.Write(d); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		if err := client.Start(time.Now()); err != nil {
+			clientErr <- err
+			return
+		}
+		buf := make([]byte, 8192)
+		for {
+			if err := write(); err != nil {
+				clientErr <- err
+				return
+			}
+			n, err := ca.Read(buf)
+			if err != nil {
+				clientErr <- err
+				return
+			}
+			if err = client.HandleDatagram(time.Now(), buf[:n]); err != nil {
+				clientErr <- err
+				return
+			}
+			if client.HandshakeComplete() {
+				break
+			}
+		}
+		if err := client.Write(time.Now(), message); err != nil {
+			clientErr <- err
+			return
+		}
+		clientErr <- write()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server, err := ServerWithContext(ctx, cb, &Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+
+	buf := make([]byte, 64)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], message) {
+		t.Errorf("Read: expected(%s) actual(%s)", message, buf[:n])
+	}
+	if err = <-clientErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngineWarningAlert(t *testing.T) {
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []AlertDescription
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerEngine(&Config{
+		Certificates: []tls.Certificate{cert},
+		OnWarningAlert: func(desc AlertDescription) {
+			warnings = append(warnings, desc)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	pumpEngines(t, now, client, server)
+	if !client.HandshakeComplete() || !server.HandshakeComplete() {
+		t.Fatal("Handshake did not complete")
+	}
+
+	for _, desc := range []AlertDescription{AlertUnsupportedCertificate, AlertUserCanceled} {
+		if err = client.conn.notify(AlertLevelWarning, desc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pumpEngines(t, now, client, server)
+	if fmt.Sprint(warnings) != fmt.Sprint([]AlertDescription{AlertUnsupportedCertificate, AlertUserCanceled}) {
+		t.Errorf("OnWarningAlert: unexpected alerts %v", warnings)
+	}
+
+	// Warning alerts keep the connection
+	message := []byte("Hello")
+	if err = client.Write(now, message); err != nil {
+		t.Fatal(err)
+	}
+	pumpEngines(t, now, client, server)
+	if received := server.Received(); len(received) != 1 || !bytes.Equal(received[0], message) {
+		t.Errorf("Received: expected(%s) actual(%s)", message, received)
+	}
+}
+
+func TestEngineCancelHandshake(t *testing.T) {
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []AlertDescription
+	client, err := NewClientEngine(&Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := NewServerEngine(&Config{
+		Certificates: []tls.Certificate{cert},
+		OnWarningAlert: func(desc AlertDescription) {
+			warnings = append(warnings, desc)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cancel once the server answered the ClientHello
+	now := time.Now()
+	if err = client.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range client.Outgoing() {
+		if err = server.HandleDatagram(now, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = client.CancelHandshake(now); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.HandleTimeout(now); err != ErrHandshakeCanceled {
+		t.Errorf("HandleTimeout after CancelHandshake: expected(%v) actual(%v)", ErrHandshakeCanceled, err)
+	}
+
+	// user_canceled is followed by a close_notify
+	outgoing := client.Outgoing()
+	var alerts []AlertDescription
+	for _, d := range outgoing {
+		r := &recordlayer.RecordLayer{}
+		if err = r.Unmarshal(d); err != nil {
+			t.Fatal(err)
+		}
+		if a, ok := r.Content.(*alert.Alert); ok && a.Level == AlertLevelWarning {
+			alerts = append(alerts, a.Description)
+		}
+	}
+	if fmt.Sprint(alerts) != fmt.Sprint([]AlertDescription{AlertUserCanceled, AlertCloseNotify}) {
+		t.Errorf("Client sent unexpected alerts %v", alerts)
+	}
+
+	for _, d := range outgoing {
+		if err = server.HandleDatagram(now, d); err != nil {
+			break
+		}
+	}
+	var alertErr *AlertError
+	if !errors.As(err, &alertErr) || !alertErr.Remote || alertErr.Description != AlertUserCanceled {
+		t.Errorf("Server error: expected user_canceled, got %v", err)
+	}
+	if fmt.Sprint(warnings) != fmt.Sprint([]AlertDescription{AlertUserCanceled}) {
+		t.Errorf("OnWarningAlert: unexpected alerts %v", warnings)
+	}
+	if server.HandshakeComplete() {
+		t.Error("Server completed a canceled handshake")
+	}
+}
@@ -9,7 +9,20 @@ import (
 
 // Typed errors
 var (
-	ErrConnClosed = errors.New("dtls: conn is closed")
+	ErrConnClosed    = errors.New("dtls: conn is closed")
+	ErrRekeyRequired = errors.New("dtls: record limit of the current keys reached and rekeying is not possible")
+
+	// ErrHandshakeCanceled is returned by Handshake after CancelHandshake
+	ErrHandshakeCanceled = errors.New("dtls: handshake was canceled")
+
+	// ErrListenerClosed is returned by Accept once the listener is closed
+	ErrListenerClosed = errors.New("dtls: listener closed")
+
+	// Reasons for a listener to reject new remotes, see ListenConfig.OnReject
+	ErrListenerBacklogFull      = errors.New("dtls: listener backlog is full")
+	ErrTooManyConns             = errors.New("dtls: listener has reached the maximum number of connections")
+	ErrTooManyPendingHandshakes = errors.New("dtls: listener has reached the maximum number of pending handshakes")
+	ErrConnRateLimited          = errors.New("dtls: rate of new connections from the remote IP exceeded")
 
 	errBufferTooSmall                    = errors.New("dtls: buffer is too small")
 	errClientCertificateRequired         = errors.New("dtls: server required client verification, but got none")
@@ -17,24 +30,20 @@ var (
 	errCertificateVerifyNoCertificate    = errors.New("dtls: client sent certificate verify but we have no certificate to verify")
 	errNoCertificates                    = errors.New("dtls: no certificates configured")
 	errCipherSuiteNoIntersection         = errors.New("dtls: Client+Server do not support any shared cipher suites")
-	errCipherSuiteUnset                  = errors.New("dtls: server hello can not be created without a cipher suite")
-	errCompressionMethodUnset            = errors.New("dtls: server hello can not be created without a compression method")
-	errContextUnsupported                = errors.New("dtls: context is not supported for ExportKeyingMaterial")
+	errContextTooLong                    = errors.New("dtls: context for ExportKeyingMaterial must not be longer than 65535 bytes")
 	errCookieMismatch                    = errors.New("dtls: Client+Server cookie does not match")
-	errCookieTooLong                     = errors.New("dtls: cookie must not be longer then 255 bytes")
-	errDTLSPacketInvalidLength           = errors.New("dtls: packet is too short")
 	errHandshakeInProgress               = errors.New("dtls: Handshake is in progress")
-	errHandshakeMessageUnset             = errors.New("dtls: handshake message unset, unable to marshal")
-	errInvalidCipherSpec                 = errors.New("dtls: cipher spec invalid")
+	errHandshakeComplete                 = errors.New("dtls: handshake is already complete, use Close")
 	errInvalidCipherSuite                = errors.New("dtls: invalid or unknown cipher suite")
-	errInvalidCompressionMethod          = errors.New("dtls: invalid or unknown compression method")
+	errInvalidSequenceNumber             = errors.New("dtls: sequence number exceeds the maximum of its epoch")
 	errInvalidContentType                = errors.New("dtls: invalid content type")
 	errInvalidECDSASignature             = errors.New("dtls: ECDSA signature contained zero or negative values")
-	errInvalidEllipticCurveType          = errors.New("dtls: invalid or unknown elliptic curve type")
-	errInvalidExtensionType              = errors.New("dtls: invalid extension type")
-	errInvalidSNIFormat                  = errors.New("dtls: invalid server name format")
-	errInvalidHashAlgorithm              = errors.New("dtls: invalid hash algorithm")
 	errInvalidMAC                        = errors.New("dtls: invalid mac")
+	errInvalidPadding                    = errors.New("dtls: invalid padding")
+	errALPNInvalidFormat                 = errors.New("dtls: invalid alpn format")
+	errALPNNoAppProto                    = errors.New("dtls: no application protocol")
+	errALPNNotOffered                    = errors.New("dtls: server selected an application protocol the client did not offer")
+	errInvalidNextProto                  = errors.New("dtls: NextProtos must be between 1 and 255 bytes long")
 	errInvalidNamedCurve                 = errors.New("dtls: invalid named curve")
 	errInvalidPrivateKey                 = errors.New("dtls: invalid private key type")
 	errInvalidSignatureAlgorithm         = errors.New("dtls: invalid signature algorithm")
@@ -44,25 +53,41 @@ var (
 	errLengthMismatch                    = errors.New("dtls: data length and declared length do not match")
 	errNilNextConn                       = errors.New("dtls: Conn can not be created with a nil nextConn")
 	errNotEnoughRoomForNonce             = errors.New("dtls: Buffer not long enough to contain nonce")
-	errNotImplemented                    = errors.New("dtls: feature has not been implemented yet")
 	errReservedExportKeyingMaterial      = errors.New("dtls: ExportKeyingMaterial can not be used with a reserved label")
-	errSequenceNumberOverflow            = errors.New("dtls: sequence number overflow")
 	errServerMustHaveCertificate         = errors.New("dtls: Certificate is mandatory for server")
-	errUnableToMarshalFragmented         = errors.New("dtls: unable to marshal fragmented handshakes")
 	errVerifyDataMismatch                = errors.New("dtls: Expected and actual verify data does not match")
 	errNoConfigProvided                  = errors.New("dtls: No config provided")
 	errPSKAndCertificate                 = errors.New("dtls: Certificate and PSK provided")
 	errPSKAndIdentityMustBeSetForClient  = errors.New("dtls: PSK and PSK Identity Hint must both be set for client")
 	errIdentityNoPSK                     = errors.New("dtls: Identity Hint provided but PSK is nil")
 	errNoAvailableCipherSuites           = errors.New("dtls: Connection can not be created, no CipherSuites satisfy this Config")
-	errInvalidClientKeyExchange          = errors.New("dtls: Unable to determine if ClientKeyExchange is a public key or PSK Identity")
 	errNoSupportedEllipticCurves         = errors.New("dtls: Client requested zero or more elliptic curves that are not supported by the server")
 	errRequestedButNoSRTPExtension       = errors.New("dtls: SRTP support was requested but server did not respond with use_srtp extension")
 	errClientNoMatchingSRTPProfile       = errors.New("dtls: Server responded with SRTP Profile we do not support")
 	errServerNoMatchingSRTPProfile       = errors.New("dtls: Client requested SRTP but we have no matching profiles")
+	errSRTPMasterKeyIdentifierTooLong    = errors.New("dtls: SRTP MKI must not be longer than 255 bytes")
+	errSRTPMasterKeyIdentifierMismatch   = errors.New("dtls: Server responded with an SRTP MKI we did not offer")
+	errNoSRTPProtectionProfile           = errors.New("dtls: no SRTPProtectionProfile was negotiated")
 	errServerRequiredButNoClientEMS      = errors.New("dtls: Server requires the Extended Master Secret extension, but the client does not support it")
 	errClientRequiredButNoServerEMS      = errors.New("dtls: Client required Extended Master Secret extension, but server does not support it")
 	errInvalidCertificate                = errors.New("dtls: No certificate provided")
+	errHelloExtensionNotOffered          = errors.New("dtls: server responded with an extension we did not offer")
+	errHelloExtensionImplemented         = errors.New("dtls: HelloExtensions returned an extension type this package implements")
+	errRenegotiationInfoMismatch         = errors.New("dtls: renegotiation_info does not match the previous handshake")
+	errRenegotiationDisabled             = errors.New("dtls: renegotiation is disabled by the Config")
+	errRenegotiationUnsupported          = errors.New("dtls: remote does not support secure renegotiation")
+	errRenegotiationInProgress           = errors.New("dtls: renegotiation is already in progress")
+	errRenegotiationRefused              = errors.New("dtls: remote refused to renegotiate")
+	errApplicationDataEpochZero          = errors.New("dtls: ApplicationData with epoch of 0")
+	errUnexpectedEpoch                   = errors.New("dtls: record epoch is neither the current nor the next epoch")
+	errHandshakeMessageTooLarge          = errors.New("dtls: handshake message is too large")
+	errInvalidFragment                   = errors.New("dtls: handshake fragment exceeds the length of its message")
+	errFragmentMismatch                  = errors.New("dtls: handshake fragment does not match the previous fragments of its message")
+	errFragmentTooFarAhead               = errors.New("dtls: handshake fragment is too far ahead of the expected message")
+	errFragmentBufferFull                = errors.New("dtls: too many handshake messages are being reassembled")
+	errInvalidFlightTransition           = errors.New("dtls: invalid handshake state transition")
+	errUnexpectedHandshakeMessage        = errors.New("dtls: unexpected handshake message")
+	errServerHelloDoneNotSent            = errors.New("dtls: client finished the handshake before the ServerHelloDone was sent")
 
 	// Wrapped errors
 	errConnectTimeout = xerrors.Errorf("dtls: The connection timed out during the handshake: %w", context.DeadlineExceeded)
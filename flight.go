@@ -2,8 +2,11 @@ package dtls
 
 import (
 	"sync"
+	"time"
 
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
 	"github.com/pion/logging"
+	"golang.org/x/xerrors"
 )
 
 /*
@@ -39,74 +42,187 @@ import (
 
 */
 
-type flightVal uint8
+// HandshakeState is the step of the handshake a Conn is in, named after the
+// flight it sends and retransmits until the remote answers, see the diagram
+// above. Clients go through the odd flights and servers through the even
+// ones. Once the handshake is complete a Conn stays in its last state until
+// a renegotiation starts over.
+type HandshakeState uint8
 
+// HandshakeState enums
 const (
-	flight0 flightVal = iota + 1
-	flight1
-	flight2
-	flight3
-	flight4
-	flight5
-	flight6
+	HandshakeStateWaitClientHello    HandshakeState = iota + 1 // Flight 0
+	HandshakeStateClientHello                                  // Flight 1
+	HandshakeStateHelloVerifyRequest                           // Flight 2
+	HandshakeStateClientHelloCookie                            // Flight 3
+	HandshakeStateServerHello                                  // Flight 4
+	HandshakeStateClientKeyExchange                            // Flight 5
+	HandshakeStateServerFinished                               // Flight 6
 )
 
-func (f flightVal) String() string {
-	switch f {
+const (
+	flight0 = HandshakeStateWaitClientHello
+	flight1 = HandshakeStateClientHello
+	flight2 = HandshakeStateHelloVerifyRequest
+	flight3 = HandshakeStateClientHelloCookie
+	flight4 = HandshakeStateServerHello
+	flight5 = HandshakeStateClientKeyExchange
+	flight6 = HandshakeStateServerFinished
+)
+
+func (s HandshakeState) String() string {
+	switch s {
 	case flight0:
-		return "Flight 0"
+		return "Flight 0 (WaitClientHello)"
 	case flight1:
-		return "Flight 1"
+		return "Flight 1 (ClientHello)"
 	case flight2:
-		return "Flight 2"
+		return "Flight 2 (HelloVerifyRequest)"
 	case flight3:
-		return "Flight 3"
+		return "Flight 3 (ClientHelloCookie)"
 	case flight4:
-		return "Flight 4"
+		return "Flight 4 (ServerHello)"
 	case flight5:
-		return "Flight 5"
+		return "Flight 5 (ClientKeyExchange)"
 	case flight6:
-		return "Flight 6"
+		return "Flight 6 (ServerFinished)"
 	default:
 		return "Invalid Flight"
 	}
 }
 
+// flightTransitions are the legal transitions between handshake states
+var flightTransitions = map[HandshakeState][]HandshakeState{
+	// The server sends a HelloVerifyRequest, unless it is renegotiating
+	flight0: {flight2, flight4},
+	// The server may skip the HelloVerifyRequest, a refused renegotiation
+	// returns to the completed handshake
+	flight1: {flight3, flight5},
+	flight2: {flight4},
+	flight3: {flight5},
+	flight4: {flight6},
+	// Renegotiation starts over once the handshake is complete
+	flight5: {flight1},
+	flight6: {flight0},
+}
+
+// flightExpectedMessages are the handshake messages the remote may send
+// in each handshake state, anything else is answered with an
+// unexpected_message alert. Retransmissions of messages that were already
+// handled never get here, see fragmentBuffer.
+var flightExpectedMessages = map[HandshakeState][]handshake.Type{
+	flight0: {handshake.TypeClientHello},
+	flight1: {
+		handshake.TypeHelloRequest, handshake.TypeHelloVerifyRequest, handshake.TypeServerHello,
+		handshake.TypeCertificate, handshake.TypeServerKeyExchange, handshake.TypeCertificateRequest,
+		handshake.TypeServerHelloDone,
+	},
+	flight2: {handshake.TypeClientHello},
+	flight3: {
+		handshake.TypeHelloRequest, handshake.TypeServerHello, handshake.TypeCertificate,
+		handshake.TypeServerKeyExchange, handshake.TypeCertificateRequest, handshake.TypeServerHelloDone,
+	},
+	flight4: {
+		handshake.TypeCertificate, handshake.TypeClientKeyExchange, handshake.TypeCertificateVerify,
+		handshake.TypeFinished,
+	},
+	// Clients ignore a HelloRequest while negotiating (RFC 5246 Section 7.4.1.1)
+	flight5: {handshake.TypeHelloRequest, handshake.TypeFinished},
+	flight6: {},
+}
+
+// HandshakeStateChange describes a transition of the handshake, see
+// Config.OnHandshakeStateChange
+type HandshakeStateChange struct {
+	From, To HandshakeState
+	// Time is when the handshake entered To
+	Time time.Time
+	// Duration is how long the handshake was in From
+	Duration time.Duration
+}
+
 type flight struct {
 	sync.RWMutex
-	val           flightVal
+	val           HandshakeState
+	since         time.Time     // When val was entered
 	workerTrigger chan struct{} // Temporary way to trigger next flight
 
-	log logging.LeveledLogger
+	clock    Clock
+	onChange func(HandshakeStateChange)
+	log      logging.LeveledLogger
 }
 
-func newFlight(isClient bool, logger logging.LeveledLogger) *flight {
+func newFlight(isClient bool, clock Clock, onChange func(HandshakeStateChange), logger logging.LeveledLogger) *flight {
 	val := flight0
 	if isClient {
 		val = flight1
 	}
 	return &flight{
 		val:           val,
+		since:         clock.Now(),
 		workerTrigger: make(chan struct{}, 1),
 
-		log: logger,
+		clock:    clock,
+		onChange: onChange,
+		log:      logger,
 	}
 }
 
-func (f *flight) get() flightVal {
+func (f *flight) get() HandshakeState {
 	f.RLock()
 	defer f.RUnlock()
 	return f.val
 }
 
-func (f *flight) set(val flightVal) {
+// set moves the handshake to val and triggers the flight worker, it fails
+// if the transition is not in flightTransitions
+func (f *flight) set(val HandshakeState) error {
 	f.Lock()
-	f.log.Tracef("[handshake] Moving from %s to %s", f.val.String(), val.String())
-	f.val = val // TODO ensure no invalid transitions
+	from := f.val
+	if from != val && !containsHandshakeState(flightTransitions[from], val) {
+		f.Unlock()
+		return xerrors.Errorf("%w: %s to %s", errInvalidFlightTransition, from, val)
+	}
+
+	var change HandshakeStateChange
+	if from != val {
+		f.log.Tracef("[handshake] Moving from %s to %s", from, val)
+		now := f.clock.Now()
+		change = HandshakeStateChange{From: from, To: val, Time: now, Duration: now.Sub(f.since)}
+		f.val = val
+		f.since = now
+	}
 	f.Unlock()
 
+	if from != val && f.onChange != nil {
+		f.onChange(change)
+	}
+
 	select {
 	case f.workerTrigger <- struct{}{}:
 	default:
 	}
+	return nil
+}
+
+// expects returns true if the remote may send a message of type typ in the
+// current handshake state
+func (f *flight) expects(typ handshake.Type) bool {
+	f.RLock()
+	defer f.RUnlock()
+	for _, t := range flightExpectedMessages[f.val] {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHandshakeState(states []HandshakeState, s HandshakeState) bool {
+	for _, state := range states {
+		if state == s {
+			return true
+		}
+	}
+	return false
 }
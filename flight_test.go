@@ -0,0 +1,83 @@
+package dtls
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/logging"
+)
+
+func TestFlightTransitions(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	var changes []HandshakeStateChange
+	f := newFlight(true, clock, func(change HandshakeStateChange) {
+		changes = append(changes, change)
+	}, logging.NewDefaultLoggerFactory().NewLogger("dtls"))
+
+	if f.get() != flight1 {
+		t.Fatalf("Initial state: expected(%s) actual(%s)", flight1, f.get())
+	}
+
+	clock.Advance(time.Second)
+	if err := f.set(flight3); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Second)
+	if err := f.set(flight5); err != nil {
+		t.Fatal(err)
+	}
+	// Staying in a state is not a change
+	if err := f.set(flight5); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, val := range []HandshakeState{flight3, flight0, flight6} {
+		if err := f.set(val); !errors.Is(err, errInvalidFlightTransition) {
+			t.Errorf("%s to %s: expected(%v) actual(%v)", flight5, val, errInvalidFlightTransition, err)
+		}
+	}
+	if f.get() != flight5 {
+		t.Errorf("Invalid transitions changed the state to %s", f.get())
+	}
+
+	expected := []HandshakeStateChange{
+		{From: flight1, To: flight3, Time: start.Add(time.Second), Duration: time.Second},
+		{From: flight3, To: flight5, Time: start.Add(3 * time.Second), Duration: 2 * time.Second},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Changes: expected(%v) actual(%v)", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Change %d: expected(%v) actual(%v)", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestFlightExpects(t *testing.T) {
+	for _, test := range []struct {
+		State    HandshakeState
+		Type     handshake.Type
+		Expected bool
+	}{
+		{flight0, handshake.TypeClientHello, true},
+		{flight0, handshake.TypeFinished, false},
+		{flight1, handshake.TypeServerHello, true},
+		{flight1, handshake.TypeClientHello, false},
+		{flight2, handshake.TypeClientKeyExchange, false},
+		{flight3, handshake.TypeHelloVerifyRequest, false},
+		{flight4, handshake.TypeFinished, true},
+		{flight4, handshake.TypeServerHello, false},
+		{flight5, handshake.TypeHelloRequest, true},
+		{flight5, handshake.TypeServerHelloDone, false},
+		{flight6, handshake.TypeClientHello, false},
+	} {
+		f := &flight{val: test.State}
+		if actual := f.expects(test.Type); actual != test.Expected {
+			t.Errorf("%s in %s: expected(%t) actual(%t)", test.Type, test.State, test.Expected, actual)
+		}
+	}
+}
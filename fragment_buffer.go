@@ -1,93 +1,159 @@
 package dtls
 
-type fragment struct {
-	recordLayerHeader recordLayerHeader
-	handshakeHeader   handshakeHeader
-	data              []byte
+import (
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+const (
+	// fragmentBufferMaxMessageSize is the maximum declared length of a
+	// handshake message, large enough for certificate chains
+	fragmentBufferMaxMessageSize = 1 << 16
+	// fragmentBufferMaxSize is the maximum number of bytes reserved for
+	// all messages being reassembled
+	fragmentBufferMaxSize = 4 * fragmentBufferMaxMessageSize
+	// fragmentBufferMaxWindow is how far ahead of the next expected
+	// message sequence fragments are accepted, a flight is much shorter
+	fragmentBufferMaxWindow = 16
+)
+
+// fragmentRange is the range [start, end) of a message that was received
+type fragmentRange struct {
+	start, end uint32
 }
 
+// fragmentedMessage is a handshake message being reassembled
+type fragmentedMessage struct {
+	handshakeHeader handshake.Header
+	data            []byte
+	received        []fragmentRange // Sorted and merged
+}
+
+// add marks the range [start, end) as received, merging it with the
+// ranges it overlaps or touches
+func (m *fragmentedMessage) add(start, end uint32) {
+	if start == end {
+		return
+	}
+
+	merged := make([]fragmentRange, 0, len(m.received)+1)
+	i := 0
+	for ; i < len(m.received) && m.received[i].end < start; i++ {
+		merged = append(merged, m.received[i])
+	}
+	for ; i < len(m.received) && m.received[i].start <= end; i++ {
+		if m.received[i].start < start {
+			start = m.received[i].start
+		}
+		if m.received[i].end > end {
+			end = m.received[i].end
+		}
+	}
+	merged = append(merged, fragmentRange{start, end})
+	m.received = append(merged, m.received[i:]...)
+}
+
+func (m *fragmentedMessage) complete() bool {
+	if m.handshakeHeader.Length == 0 {
+		return true
+	}
+	return len(m.received) == 1 && m.received[0] == fragmentRange{0, m.handshakeHeader.Length}
+}
+
+// fragmentBuffer reassembles handshake messages from their fragments.
+// Fragments may arrive in any order, overlap, be duplicated or be
+// fragmented differently when retransmitted. The memory used is bounded,
+// fragments of messages which are too large or too far ahead of the next
+// expected message are rejected.
 type fragmentBuffer struct {
-	// map of MessageSequenceNumbers that hold slices of fragments
-	cache map[uint16][]*fragment
+	// messages being reassembled by MessageSequenceNumber
+	cache map[uint16]*fragmentedMessage
+	size  int // Bytes reserved by the messages in cache
 
 	currentMessageSequenceNumber uint16
 }
 
 func newFragmentBuffer() *fragmentBuffer {
-	return &fragmentBuffer{cache: map[uint16][]*fragment{}}
+	return &fragmentBuffer{cache: map[uint16]*fragmentedMessage{}}
 }
 
 // Attempts to push a DTLS packet to the fragmentBuffer
 // when it returns true it means the fragmentBuffer has inserted and the buffer shouldn't be handled
-// when an error returns it is fatal, and the DTLS connection should be stopped
+// when an error returns the packet is invalid, see fragmentAlert
 func (f *fragmentBuffer) push(buf []byte) (bool, error) {
-	frag := new(fragment)
-	if err := frag.recordLayerHeader.Unmarshal(buf); err != nil {
+	var recordLayerHeader recordlayer.Header
+	if err := recordLayerHeader.Unmarshal(buf); err != nil {
 		return false, err
 	}
 
 	// fragment isn't a handshake, we don't need to handle it
-	if frag.recordLayerHeader.contentType != contentTypeHandshake {
+	if recordLayerHeader.ContentType != protocol.ContentTypeHandshake {
 		return false, nil
 	}
 
-	if err := frag.handshakeHeader.Unmarshal(buf[recordLayerHeaderSize:]); err != nil {
+	var header handshake.Header
+	if err := header.Unmarshal(buf[recordlayer.HeaderSize:]); err != nil {
 		return false, err
 	}
 
-	if _, ok := f.cache[frag.handshakeHeader.messageSequence]; !ok {
-		f.cache[frag.handshakeHeader.messageSequence] = []*fragment{}
+	body := buf[recordlayer.HeaderSize+handshake.HeaderLength:]
+	switch {
+	case uint32(len(body)) < header.FragmentLength:
+		return false, errBufferTooSmall
+	case header.Length > fragmentBufferMaxMessageSize:
+		return false, errHandshakeMessageTooLarge
+	case header.FragmentOffset > header.Length || header.FragmentLength > header.Length-header.FragmentOffset:
+		return false, errInvalidFragment
 	}
 
-	// Discard all headers, when rebuilding the packet we will re-build
-	frag.data = append([]byte{}, buf[recordLayerHeaderSize+handshakeHeaderLength:]...)
-	f.cache[frag.handshakeHeader.messageSequence] = append(f.cache[frag.handshakeHeader.messageSequence], frag)
-
-	return true, nil
-}
-
-func (f *fragmentBuffer) pop() []byte {
-	frags, ok := f.cache[f.currentMessageSequenceNumber]
-	if !ok {
-		return nil
+	// Fragments of messages which were already reassembled are retransmissions
+	if header.MessageSequence < f.currentMessageSequenceNumber {
+		return true, nil
+	} else if header.MessageSequence-f.currentMessageSequenceNumber >= fragmentBufferMaxWindow {
+		return false, errFragmentTooFarAhead
 	}
 
-	// Go doesn't support recursive lambdas
-	var appendMessage func(targetOffset uint32) bool
-
-	rawMessage := []byte{}
-	appendMessage = func(targetOffset uint32) bool {
-		for _, f := range frags {
-			if f.handshakeHeader.fragmentOffset == targetOffset {
-				fragmentEnd := (f.handshakeHeader.fragmentOffset + f.handshakeHeader.fragmentLength)
-				if fragmentEnd != f.handshakeHeader.length {
-					if !appendMessage(fragmentEnd) {
-						return false
-					}
-				}
-
-				rawMessage = append(f.data, rawMessage...)
-				return true
-			}
+	m, ok := f.cache[header.MessageSequence]
+	switch {
+	case !ok:
+		if f.size+int(header.Length) > fragmentBufferMaxSize {
+			return false, errFragmentBufferFull
 		}
-		return false
+		m = &fragmentedMessage{handshakeHeader: header, data: make([]byte, header.Length)}
+		f.cache[header.MessageSequence] = m
+		f.size += int(header.Length)
+	case m.handshakeHeader.Type != header.Type || m.handshakeHeader.Length != header.Length:
+		return false, errFragmentMismatch
 	}
 
-	// Recursively collect up
-	if !appendMessage(0) {
+	// Overlapping fragments overwrite what was received before, a remote
+	// sending different contents is detected by the Finished message
+	end := header.FragmentOffset + header.FragmentLength
+	copy(m.data[header.FragmentOffset:end], body)
+	m.add(header.FragmentOffset, end)
+
+	return true, nil
+}
+
+// pop returns the next message if all of its fragments were received
+func (f *fragmentBuffer) pop() []byte {
+	m, ok := f.cache[f.currentMessageSequenceNumber]
+	if !ok || !m.complete() {
 		return nil
 	}
 
-	firstHeader := frags[0].handshakeHeader
-	firstHeader.fragmentOffset = 0
-	firstHeader.fragmentLength = firstHeader.length
+	header := m.handshakeHeader
+	header.FragmentOffset = 0
+	header.FragmentLength = header.Length
 
-	rawHeader, err := firstHeader.Marshal()
+	rawHeader, err := header.Marshal()
 	if err != nil {
 		return nil
 	}
 
 	delete(f.cache, f.currentMessageSequenceNumber)
+	f.size -= int(header.Length)
 	f.currentMessageSequenceNumber++
-	return append(rawHeader, rawMessage...)
+	return append(rawHeader, m.data...)
 }
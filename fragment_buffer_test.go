@@ -1,8 +1,16 @@
 package dtls
 
 import (
+	"encoding/binary"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/util"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 func TestFragmentBuffer(t *testing.T) {
@@ -57,3 +65,192 @@ func TestFragmentBuffer(t *testing.T) {
 		}
 	}
 }
+
+// handshakeFragment returns a record of the fragment [offset, offset+len(data))
+// of a ClientHello with the given message sequence and length
+func handshakeFragment(messageSequence uint16, length, offset uint32, data []byte) []byte {
+	out := []byte{0x16, 0xfe, 0xfd, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	binary.BigEndian.PutUint16(out[11:], uint16(handshake.HeaderLength+len(data)))
+
+	header := make([]byte, handshake.HeaderLength)
+	header[0] = byte(handshake.TypeClientHello)
+	util.PutBigEndianUint24(header[1:], length)
+	binary.BigEndian.PutUint16(header[4:], messageSequence)
+	util.PutBigEndianUint24(header[6:], offset)
+	util.PutBigEndianUint24(header[9:], uint32(len(data)))
+	return append(append(out, header...), data...)
+}
+
+func TestFragmentBufferReassembly(t *testing.T) {
+	message := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}
+	fragment := func(start, end uint32) []byte {
+		return handshakeFragment(0, uint32(len(message)), start, message[start:end])
+	}
+
+	for _, test := range []struct {
+		Name string
+		In   [][]byte
+	}{
+		{
+			Name: "Overlapping Fragments",
+			In:   [][]byte{fragment(0, 6), fragment(4, 10)},
+		},
+		{
+			Name: "Contained Fragment",
+			In:   [][]byte{fragment(2, 4), fragment(0, 10)},
+		},
+		{
+			Name: "Duplicated Fragments",
+			In:   [][]byte{fragment(0, 5), fragment(0, 5), fragment(5, 10)},
+		},
+		{
+			Name: "Refragmented Retransmission",
+			In:   [][]byte{fragment(0, 3), fragment(6, 9), fragment(2, 7), fragment(8, 10)},
+		},
+		{
+			Name: "Reversed Fragments",
+			In:   [][]byte{fragment(8, 10), fragment(4, 8), fragment(0, 4)},
+		},
+		{
+			Name: "Empty Fragment",
+			In:   [][]byte{fragment(0, 5), fragment(5, 5), fragment(5, 10)},
+		},
+	} {
+		fragmentBuffer := newFragmentBuffer()
+		for i, frag := range test.In {
+			if out := fragmentBuffer.pop(); out != nil {
+				t.Errorf("fragmentBuffer '%s' popped incomplete message after %d fragments", test.Name, i)
+			}
+			if status, err := fragmentBuffer.push(frag); err != nil {
+				t.Errorf("fragmentBuffer '%s' push: %v", test.Name, err)
+			} else if !status {
+				t.Errorf("fragmentBuffer didn't accept fragments for '%s'", test.Name)
+			}
+		}
+
+		out := fragmentBuffer.pop()
+		expected := handshakeFragment(0, uint32(len(message)), 0, message)[recordlayer.HeaderSize:]
+		if !reflect.DeepEqual(out, expected) {
+			t.Errorf("fragmentBuffer '%s' push/pop: got % 02x, want % 02x", test.Name, out, expected)
+		}
+		if fragmentBuffer.size != 0 || len(fragmentBuffer.cache) != 0 {
+			t.Errorf("fragmentBuffer '%s' kept %d bytes after pop", test.Name, fragmentBuffer.size)
+		}
+	}
+}
+
+func TestFragmentBufferInvalid(t *testing.T) {
+	for _, test := range []struct {
+		Name     string
+		Previous [][]byte
+		In       []byte
+		Err      error
+	}{
+		{
+			Name: "Truncated Fragment",
+			In:   handshakeFragment(0, 10, 0, make([]byte, 10))[:recordlayer.HeaderSize+handshake.HeaderLength+5],
+			Err:  errBufferTooSmall,
+		},
+		{
+			Name: "Message Too Large",
+			In:   handshakeFragment(0, fragmentBufferMaxMessageSize+1, 0, make([]byte, 10)),
+			Err:  errHandshakeMessageTooLarge,
+		},
+		{
+			Name: "Fragment Beyond Length",
+			In:   handshakeFragment(0, 10, 8, make([]byte, 4)),
+			Err:  errInvalidFragment,
+		},
+		{
+			Name: "Offset Beyond Length",
+			In:   handshakeFragment(0, 10, 11, nil),
+			Err:  errInvalidFragment,
+		},
+		{
+			Name:     "Length Changed",
+			Previous: [][]byte{handshakeFragment(0, 10, 0, make([]byte, 5))},
+			In:       handshakeFragment(0, 20, 5, make([]byte, 5)),
+			Err:      errFragmentMismatch,
+		},
+		{
+			Name: "Too Far Ahead",
+			In:   handshakeFragment(fragmentBufferMaxWindow, 10, 0, make([]byte, 10)),
+			Err:  errFragmentTooFarAhead,
+		},
+		{
+			Name: "Buffer Full",
+			Previous: [][]byte{
+				handshakeFragment(1, fragmentBufferMaxMessageSize, 0, nil),
+				handshakeFragment(2, fragmentBufferMaxMessageSize, 0, nil),
+				handshakeFragment(3, fragmentBufferMaxMessageSize, 0, nil),
+				handshakeFragment(4, fragmentBufferMaxMessageSize, 0, nil),
+			},
+			In:  handshakeFragment(5, 1, 0, nil),
+			Err: errFragmentBufferFull,
+		},
+	} {
+		fragmentBuffer := newFragmentBuffer()
+		for _, frag := range test.Previous {
+			if _, err := fragmentBuffer.push(frag); err != nil {
+				t.Fatalf("fragmentBuffer '%s' push: %v", test.Name, err)
+			}
+		}
+		if _, err := fragmentBuffer.push(test.In); err != test.Err {
+			t.Errorf("fragmentBuffer '%s' push: got error %v, want %v", test.Name, err, test.Err)
+		}
+	}
+}
+
+func TestFragmentBufferRetransmission(t *testing.T) {
+	fragmentBuffer := newFragmentBuffer()
+	message := handshakeFragment(0, 3, 0, []byte{0x01, 0x02, 0x03})
+	if _, err := fragmentBuffer.push(message); err != nil {
+		t.Fatal(err)
+	}
+	if out := fragmentBuffer.pop(); out == nil {
+		t.Fatal("fragmentBuffer did not pop complete message")
+	}
+
+	// Messages which were already reassembled are accepted but not kept
+	if status, err := fragmentBuffer.push(message); err != nil || !status {
+		t.Fatalf("fragmentBuffer push of retransmission: got (%v, %v), want (true, nil)", status, err)
+	}
+	if len(fragmentBuffer.cache) != 0 {
+		t.Error("fragmentBuffer kept a retransmitted message")
+	}
+	if out := fragmentBuffer.pop(); out != nil {
+		t.Error("fragmentBuffer popped a retransmitted message")
+	}
+}
+
+func TestFragmentBufferAlert(t *testing.T) {
+	for _, test := range []struct {
+		Name          string
+		In            []byte
+		ExpectedAlert *alert.Alert
+	}{
+		{
+			Name:          "Message Too Large",
+			In:            handshakeFragment(0, fragmentBufferMaxMessageSize+1, 0, make([]byte, 10)),
+			ExpectedAlert: &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter},
+		},
+		{
+			Name: "Too Far Ahead",
+			In:   handshakeFragment(fragmentBufferMaxWindow, 10, 0, make([]byte, 10)),
+		},
+	} {
+		server, err := NewServerEngine(&Config{PSK: func([]byte) ([]byte, error) { return []byte{0x00}, nil }, CipherSuites: []CipherSuiteID{TLS_PSK_WITH_AES_128_CCM}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = server.HandleDatagram(time.Now(), test.In)
+		var alertErr *AlertError
+		switch {
+		case test.ExpectedAlert == nil && (err != nil || server.conn.InvalidRecords() != 1):
+			t.Errorf("%s: expected the fragment to be dropped, got %v", test.Name, err)
+		case test.ExpectedAlert != nil && (!errors.As(err, &alertErr) || alertErr.Description != test.ExpectedAlert.Description):
+			t.Errorf("%s: expected alert %v, got %v", test.Name, test.ExpectedAlert, err)
+		}
+	}
+}
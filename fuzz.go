@@ -2,16 +2,20 @@
 
 package dtls
 
-import "fmt"
+import (
+	"fmt"
 
-func partialHeaderMismatch(a, b recordLayerHeader) bool {
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+func partialHeaderMismatch(a, b recordlayer.Header) bool {
 	// Ignoring content length for now.
-	a.contentLen = b.contentLen
+	a.ContentLen = b.ContentLen
 	return a != b
 }
 
 func FuzzRecordLayer(data []byte) int {
-	var r recordLayer
+	var r recordlayer.RecordLayer
 	if err := r.Unmarshal(data); err != nil {
 		return 0
 	}
@@ -22,14 +26,14 @@ func FuzzRecordLayer(data []byte) int {
 	if len(buf) == 0 {
 		panic("zero buff") // nolint
 	}
-	var nr recordLayer
+	var nr recordlayer.RecordLayer
 	if err = nr.Unmarshal(data); err != nil {
 		panic(err) // nolint
 	}
-	if partialHeaderMismatch(nr.recordLayerHeader, r.recordLayerHeader) {
+	if partialHeaderMismatch(nr.Header, r.Header) {
 		panic( // nolint
 			fmt.Sprintf("header mismatch: %+v != %+v",
-				nr.recordLayerHeader, r.recordLayerHeader,
+				nr.Header, r.Header,
 			),
 		)
 	}
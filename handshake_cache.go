@@ -1,16 +1,20 @@
 package dtls
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+)
 
 type handshakeCacheItem struct {
-	typ             handshakeType
+	typ             handshake.Type
 	isClient        bool
 	messageSequence uint16
 	data            []byte
 }
 
 type handshakeCachePullRule struct {
-	typ      handshakeType
+	typ      handshake.Type
 	isClient bool
 }
 
@@ -23,7 +27,7 @@ func newHandshakeCache() *handshakeCache {
 	return &handshakeCache{}
 }
 
-func (h *handshakeCache) push(data []byte, messageSequence uint16, typ handshakeType, isClient bool) bool {
+func (h *handshakeCache) push(data []byte, messageSequence uint16, typ handshake.Type, isClient bool) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -86,14 +90,14 @@ func (h *handshakeCache) sessionHash(hf hashFunc) ([]byte, error) {
 
 	// Order defined by https://tools.ietf.org/html/rfc5246#section-7.3
 	handshakeBuffer := h.pull(
-		handshakeCachePullRule{handshakeTypeClientHello, true},
-		handshakeCachePullRule{handshakeTypeServerHello, false},
-		handshakeCachePullRule{handshakeTypeCertificate, false},
-		handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-		handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-		handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-		handshakeCachePullRule{handshakeTypeCertificate, true},
-		handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
+		handshakeCachePullRule{handshake.TypeClientHello, true},
+		handshakeCachePullRule{handshake.TypeServerHello, false},
+		handshakeCachePullRule{handshake.TypeCertificate, false},
+		handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+		handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+		handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+		handshakeCachePullRule{handshake.TypeCertificate, true},
+		handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
 	)
 
 	for _, p := range handshakeBuffer {
@@ -3,6 +3,8 @@ package dtls
 import (
 	"bytes"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
 )
 
 func TestHandshakeCacheSinglePush(t *testing.T) {
@@ -114,41 +116,41 @@ func TestHandshakeCacheSessionHash(t *testing.T) {
 		{
 			Name: "Standard Handshake",
 			Input: []handshakeCacheItem{
-				{handshakeTypeClientHello, true, 0, []byte{0x00}},
-				{handshakeTypeServerHello, false, 1, []byte{0x01}},
-				{handshakeTypeCertificate, false, 2, []byte{0x02}},
-				{handshakeTypeServerKeyExchange, false, 3, []byte{0x03}},
-				{handshakeTypeServerHelloDone, false, 4, []byte{0x04}},
-				{handshakeTypeClientKeyExchange, true, 5, []byte{0x05}},
+				{handshake.TypeClientHello, true, 0, []byte{0x00}},
+				{handshake.TypeServerHello, false, 1, []byte{0x01}},
+				{handshake.TypeCertificate, false, 2, []byte{0x02}},
+				{handshake.TypeServerKeyExchange, false, 3, []byte{0x03}},
+				{handshake.TypeServerHelloDone, false, 4, []byte{0x04}},
+				{handshake.TypeClientKeyExchange, true, 5, []byte{0x05}},
 			},
 			Expected: []byte{0x17, 0xe8, 0x8d, 0xb1, 0x87, 0xaf, 0xd6, 0x2c, 0x16, 0xe5, 0xde, 0xbf, 0x3e, 0x65, 0x27, 0xcd, 0x00, 0x6b, 0xc0, 0x12, 0xbc, 0x90, 0xb5, 0x1a, 0x81, 0x0c, 0xd8, 0x0c, 0x2d, 0x51, 0x1f, 0x43},
 		},
 		{
 			Name: "Handshake With Client Cert Request",
 			Input: []handshakeCacheItem{
-				{handshakeTypeClientHello, true, 0, []byte{0x00}},
-				{handshakeTypeServerHello, false, 1, []byte{0x01}},
-				{handshakeTypeCertificate, false, 2, []byte{0x02}},
-				{handshakeTypeServerKeyExchange, false, 3, []byte{0x03}},
-				{handshakeTypeCertificateRequest, false, 4, []byte{0x04}},
-				{handshakeTypeServerHelloDone, false, 5, []byte{0x05}},
-				{handshakeTypeClientKeyExchange, true, 6, []byte{0x06}},
+				{handshake.TypeClientHello, true, 0, []byte{0x00}},
+				{handshake.TypeServerHello, false, 1, []byte{0x01}},
+				{handshake.TypeCertificate, false, 2, []byte{0x02}},
+				{handshake.TypeServerKeyExchange, false, 3, []byte{0x03}},
+				{handshake.TypeCertificateRequest, false, 4, []byte{0x04}},
+				{handshake.TypeServerHelloDone, false, 5, []byte{0x05}},
+				{handshake.TypeClientKeyExchange, true, 6, []byte{0x06}},
 			},
 			Expected: []byte{0x57, 0x35, 0x5a, 0xc3, 0x30, 0x3c, 0x14, 0x8f, 0x11, 0xae, 0xf7, 0xcb, 0x17, 0x94, 0x56, 0xb9, 0x23, 0x2c, 0xde, 0x33, 0xa8, 0x18, 0xdf, 0xda, 0x2c, 0x2f, 0xcb, 0x93, 0x25, 0x74, 0x9a, 0x6b},
 		},
 		{
 			Name: "Handshake Ignores after ClientKeyExchange",
 			Input: []handshakeCacheItem{
-				{handshakeTypeClientHello, true, 0, []byte{0x00}},
-				{handshakeTypeServerHello, false, 1, []byte{0x01}},
-				{handshakeTypeCertificate, false, 2, []byte{0x02}},
-				{handshakeTypeServerKeyExchange, false, 3, []byte{0x03}},
-				{handshakeTypeCertificateRequest, false, 4, []byte{0x04}},
-				{handshakeTypeServerHelloDone, false, 5, []byte{0x05}},
-				{handshakeTypeClientKeyExchange, true, 6, []byte{0x06}},
-				{handshakeTypeCertificateVerify, true, 7, []byte{0x07}},
-				{handshakeTypeFinished, true, 7, []byte{0x08}},
-				{handshakeTypeFinished, false, 7, []byte{0x09}},
+				{handshake.TypeClientHello, true, 0, []byte{0x00}},
+				{handshake.TypeServerHello, false, 1, []byte{0x01}},
+				{handshake.TypeCertificate, false, 2, []byte{0x02}},
+				{handshake.TypeServerKeyExchange, false, 3, []byte{0x03}},
+				{handshake.TypeCertificateRequest, false, 4, []byte{0x04}},
+				{handshake.TypeServerHelloDone, false, 5, []byte{0x05}},
+				{handshake.TypeClientKeyExchange, true, 6, []byte{0x06}},
+				{handshake.TypeCertificateVerify, true, 7, []byte{0x07}},
+				{handshake.TypeFinished, true, 7, []byte{0x08}},
+				{handshake.TypeFinished, false, 7, []byte{0x09}},
 			},
 			Expected: []byte{0x57, 0x35, 0x5a, 0xc3, 0x30, 0x3c, 0x14, 0x8f, 0x11, 0xae, 0xf7, 0xcb, 0x17, 0x94, 0x56, 0xb9, 0x23, 0x2c, 0xde, 0x33, 0xa8, 0x18, 0xdf, 0xda, 0x2c, 0x2f, 0xcb, 0x93, 0x25, 0x74, 0x9a, 0x6b},
 		},
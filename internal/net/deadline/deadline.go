@@ -7,20 +7,57 @@ import (
 	"time"
 )
 
+// Timer is a timer created by a Clock, see time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// Clock is the source of time of a Deadline.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
 // Deadline signals updatable deadline timer.
 type Deadline struct {
 	exceeded chan struct{}
 	stop     chan struct{}
 	stopped  chan bool
 	mu       sync.RWMutex
+	clock    Clock
 }
 
 // New creates new deadline timer.
 func New() *Deadline {
+	return NewWithClock(realClock{})
+}
+
+// NewWithClock creates new deadline timer using the given Clock.
+func NewWithClock(clock Clock) *Deadline {
 	d := &Deadline{
 		exceeded: make(chan struct{}),
 		stop:     make(chan struct{}),
 		stopped:  make(chan bool, 1),
+		clock:    clock,
 	}
 	d.stopped <- true
 	return d
@@ -50,15 +87,17 @@ func (d *Deadline) Set(t time.Time) {
 		return
 	}
 
-	if dur := time.Until(t); dur > 0 {
+	if dur := t.Sub(d.clock.Now()); dur > 0 {
 		exceeded := d.exceeded
 		stopped := d.stopped
+		timer := d.clock.NewTimer(dur)
 		go func() {
 			select {
-			case <-time.After(dur):
+			case <-timer.C():
 				close(exceeded)
 				stopped <- false
 			case <-d.stop:
+				timer.Stop()
 				stopped <- true
 			}
 		}()
@@ -0,0 +1,16 @@
+package udp
+
+import "net"
+
+// batchConn reads and writes several datagrams with a single syscall
+type batchConn interface {
+	// ReadBatch reads at least one datagram and calls f for each of them,
+	// f holds the Buffer and has to release it. It must not be called
+	// concurrently.
+	ReadBatch(f func(b *Buffer, raddr net.Addr)) error
+
+	// WriteBatch writes datagrams to raddr, returning how many were written.
+	// buf is reused across the calls of a Conn, which must not share it
+	// between concurrent calls.
+	WriteBatch(datagrams [][]byte, raddr net.Addr, buf *batchWriteBuffer) (int, error)
+}
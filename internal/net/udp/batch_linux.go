@@ -0,0 +1,94 @@
+// +build linux
+
+package udp
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// mmsgConn is a batchConn using recvmmsg and sendmmsg
+type mmsgConn struct {
+	conn interface {
+		ReadBatch(ms []ipv4.Message, flags int) (int, error)
+		WriteBatch(ms []ipv4.Message, flags int) (int, error)
+	}
+	readMessages []ipv4.Message
+	readBuffers  []*Buffer // Pooled buffers of readMessages
+}
+
+// batchWriteBuffer holds the messages passed to sendmmsg
+type batchWriteBuffer struct {
+	messages []ipv4.Message
+}
+
+// newBatchConn returns a batchConn reading up to batchSize datagrams at
+// once, or nil if conn is not a UDPConn
+func newBatchConn(conn net.PacketConn, batchSize int) batchConn {
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok || batchSize <= 1 {
+		return nil
+	}
+
+	c := &mmsgConn{
+		readMessages: make([]ipv4.Message, batchSize),
+		readBuffers:  make([]*Buffer, batchSize),
+	}
+	if addr, ok := udpConn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		c.conn = ipv4.NewPacketConn(udpConn)
+	} else {
+		c.conn = ipv6.NewPacketConn(udpConn)
+	}
+	for i := range c.readMessages {
+		c.readBuffers[i] = GetBuffer()
+		c.readMessages[i].Buffers = [][]byte{c.readBuffers[i].data}
+	}
+	return c
+}
+
+func (c *mmsgConn) ReadBatch(f func(b *Buffer, raddr net.Addr)) error {
+	n, err := c.conn.ReadBatch(c.readMessages, 0)
+	if err != nil {
+		return err
+	}
+	for i, m := range c.readMessages[:n] {
+		b := c.readBuffers[i]
+		b.n = m.N
+
+		// The buffer is handed to f, the message reads into a new one
+		c.readBuffers[i] = GetBuffer()
+		c.readMessages[i].Buffers[0] = c.readBuffers[i].data
+		f(b, m.Addr)
+	}
+	return nil
+}
+
+func (c *mmsgConn) WriteBatch(datagrams [][]byte, raddr net.Addr, buf *batchWriteBuffer) (int, error) {
+	if cap(buf.messages) < len(datagrams) {
+		buf.messages = make([]ipv4.Message, len(datagrams))
+	}
+	messages := buf.messages[:len(datagrams)]
+	for i, d := range datagrams {
+		messages[i].Buffers = append(messages[i].Buffers[:0], d)
+		messages[i].Addr = raddr
+	}
+	// The datagrams are not retained once written
+	defer func() {
+		for i := range messages {
+			messages[i].Buffers[0] = nil
+		}
+	}()
+
+	// sendmmsg may write only a part of the batch
+	written := 0
+	for written < len(messages) {
+		n, err := c.conn.WriteBatch(messages[written:], 0)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package udp
+
+import "net"
+
+// batchWriteBuffer is unused, datagrams are batched on Linux only
+type batchWriteBuffer struct{}
+
+// newBatchConn returns nil, datagrams are batched on Linux only
+func newBatchConn(conn net.PacketConn, batchSize int) batchConn {
+	return nil
+}
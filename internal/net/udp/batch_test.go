@@ -0,0 +1,194 @@
+// +build !js
+
+package udp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+)
+
+func TestListenerBatch(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	for _, batchSize := range []int{1, defaultBatchSize} {
+		batchSize := batchSize
+		t.Run(fmt.Sprintf("BatchSize%d", batchSize), func(t *testing.T) {
+			// Check for leaking routines
+			report := test.CheckRoutines(t)
+			defer report()
+
+			network, addr := getConfig()
+			listener, err := (&ListenConfig{BatchSize: batchSize}).Listen(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dConn, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Can't rely on UDP message order in CI
+			sent := []string{"a", "bb", "ccc"}
+			for _, s := range sent {
+				if _, err = dConn.Write([]byte(s)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lConn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readN(t, lConn, len(sent)); fmt.Sprint(got) != fmt.Sprint(sent) {
+				t.Errorf("Read: expected %v, got %v", sent, got)
+			}
+
+			datagrams := [][]byte{[]byte("d"), []byte("ee"), []byte("fff")}
+			n, err := lConn.WriteBatch(datagrams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(datagrams) {
+				t.Errorf("WriteBatch: expected %d datagrams written, got %d", len(datagrams), n)
+			}
+			if got := readN(t, dConn, len(datagrams)); fmt.Sprint(got) != "[d ee fff]" {
+				t.Errorf("WriteBatch: expected [d ee fff] to be received, got %v", got)
+			}
+
+			if err = lConn.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err = listener.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err = dConn.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// readN reads n datagrams from conn and returns them sorted
+func readN(t *testing.T, conn net.Conn, n int) []string {
+	var got []string
+	buf := make([]byte, receiveMTU)
+	for i := 0; i < n; i++ {
+		m, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:m]))
+	}
+	sort.Strings(got)
+	return got
+}
+
+// benchmarkListenerRead measures the datagrams per second a Listener
+// receives from a single remote over the loopback interface
+func benchmarkListenerRead(b *testing.B, batchSize int) {
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{BatchSize: batchSize}).Listen(network, addr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	dConn, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dConn.Close() // nolint
+
+	// Bursts fit into the socket and read buffers, so no datagram is dropped
+	const burst = 32
+	payload := make([]byte, 1200)
+	if _, err = dConn.Write(payload); err != nil {
+		b.Fatal(err)
+	}
+	lConn, err := listener.Accept()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer lConn.Close() // nolint
+
+	buf := make([]byte, receiveMTU)
+	if _, err = lConn.Read(buf); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i += burst {
+		for j := 0; j < burst; j++ {
+			if _, err = dConn.Write(payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for j := 0; j < burst; j++ {
+			if _, err = lConn.Read(buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkListenerRead(b *testing.B) {
+	for _, batchSize := range []int{1, defaultBatchSize} {
+		batchSize := batchSize
+		b.Run(fmt.Sprintf("BatchSize%d", batchSize), func(b *testing.B) {
+			benchmarkListenerRead(b, batchSize)
+		})
+	}
+}
+
+// benchmarkConnWrite measures the datagrams per second a Conn of a
+// Listener sends over the loopback interface in batches of batchSize
+func benchmarkConnWrite(b *testing.B, batchSize int) {
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{BatchSize: batchSize}).Listen(network, addr)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	dConn, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dConn.Close() // nolint
+	if _, err = dConn.Write([]byte("hello")); err != nil {
+		b.Fatal(err)
+	}
+	lConn, err := listener.Accept()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer lConn.Close() // nolint
+
+	datagrams := make([][]byte, batchSize)
+	for i := range datagrams {
+		datagrams[i] = make([]byte, 1200)
+	}
+	b.SetBytes(1200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i += batchSize {
+		if _, err := lConn.WriteBatch(datagrams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConnWrite(b *testing.B) {
+	for _, batchSize := range []int{1, defaultBatchSize} {
+		batchSize := batchSize
+		b.Run(fmt.Sprintf("BatchSize%d", batchSize), func(b *testing.B) {
+			benchmarkConnWrite(b, batchSize)
+		})
+	}
+}
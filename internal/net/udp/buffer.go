@@ -0,0 +1,53 @@
+package udp
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Buffer is a pooled buffer a single datagram is read in. It is reference
+// counted and returned to the pool once all of its holders released it.
+type Buffer struct {
+	data []byte
+	n    int
+	refs int32
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{data: make([]byte, receiveMTU)}
+	},
+}
+
+// GetBuffer returns an empty Buffer from the pool, held by the caller
+func GetBuffer() *Buffer {
+	b := bufferPool.Get().(*Buffer)
+	b.n = 0
+	b.refs = 1
+	return b
+}
+
+// Fill reads a single datagram from r into the buffer
+func (b *Buffer) Fill(r io.Reader) error {
+	n, err := r.Read(b.data)
+	b.n = n
+	return err
+}
+
+// Bytes returns the datagram, it must not be used once released
+func (b *Buffer) Bytes() []byte {
+	return b.data[:b.n]
+}
+
+// Retain adds a holder to the buffer, which has to call Release
+func (b *Buffer) Retain() {
+	atomic.AddInt32(&b.refs, 1)
+}
+
+// Release returns the buffer to the pool if it was the last holder
+func (b *Buffer) Release() {
+	if atomic.AddInt32(&b.refs, -1) == 0 {
+		bufferPool.Put(b)
+	}
+}
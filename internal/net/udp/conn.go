@@ -13,13 +13,89 @@ import (
 	"github.com/pion/dtls/v2/internal/net/deadline"
 )
 
-const receiveMTU = 8192
+const (
+	receiveMTU            = 8192
+	defaultBacklog        = 128 // Conns
+	defaultReadBufferSize = 64  // datagrams
+	defaultBatchSize      = 16  // datagrams
+)
 
 var errClosedListener = errors.New("udp: listener closed")
 
+// Reasons for rejecting datagrams from new remotes
+var (
+	ErrBacklogFull  = errors.New("udp: accept backlog is full")
+	ErrTooManyConns = errors.New("udp: maximum number of conns reached")
+	ErrRateLimited  = errors.New("udp: rate of new conns from the remote IP exceeded")
+)
+
+// ListenConfig stores options for listening to an address.
+type ListenConfig struct {
+	// Backlog is the maximum number of new Conns waiting to be accepted,
+	// datagrams from new remotes are dropped while it is full.
+	// If zero, defaultBacklog is used.
+	Backlog int
+
+	// ReadBufferSize is the maximum number of datagrams queued for each Conn,
+	// datagrams received for a Conn that is not read fast enough are dropped.
+	// If zero, defaultReadBufferSize is used.
+	ReadBufferSize int
+
+	// MaxConns is the maximum number of Conns, including the ones
+	// waiting to be accepted. If zero, the number is not limited.
+	MaxConns int
+
+	// ConnRateLimit is the number of new Conns per second allowed from a
+	// single remote IP, with bursts of up to ConnRateBurst Conns.
+	// If zero, the rate is not limited.
+	ConnRateLimit float64
+	ConnRateBurst int
+
+	// IdleTimeout is the duration after which an accepted Conn which has
+	// not received any datagram is closed. If zero, Conns are never reaped.
+	IdleTimeout time.Duration
+
+	// AcceptFilter, if not nil, is called before a Conn is created for a new
+	// remote. The datagram is dropped if it returns an error.
+	AcceptFilter func(raddr net.Addr) error
+
+	// OnReject, if not nil, is called with the reason whenever a datagram
+	// from a new remote is dropped instead of creating a Conn. It must not
+	// block as it is called from the read loop.
+	OnReject func(raddr net.Addr, reason error)
+
+	// BatchSize is the maximum number of datagrams read with a single
+	// syscall. Datagrams are read with recvmmsg and Conn.WriteBatch uses
+	// sendmmsg on Linux if the PacketConn is a UDPConn. If zero,
+	// defaultBatchSize is used, batching is disabled if it is one.
+	BatchSize int
+}
+
+// ListenerStats are the counters of datagrams a Listener could not deliver
+type ListenerStats struct {
+	// DroppedPackets is the number of datagrams dropped because the
+	// read buffer of their Conn was full
+	DroppedPackets uint64
+	// RejectedConns is the number of datagrams from new remotes dropped
+	// because of the limits of the ListenConfig
+	RejectedConns uint64
+}
+
 // Listener augments a connection-oriented Listener over a UDP PacketConn
 type Listener struct {
-	pConn *net.UDPConn
+	// 64-bit atomics first for alignment on 32-bit platforms
+	droppedPackets uint64
+	rejectedConns  uint64
+
+	pConn net.PacketConn
+	batch batchConn // nil if datagrams are not batched
+
+	readBufferSize int
+	maxConns       int
+	rateLimiter    *rateLimiter
+	idleTimeout    time.Duration
+	acceptFilter   func(net.Addr) error
+	onReject       func(net.Addr, error)
 
 	accepting atomic.Value // bool
 	acceptCh  chan *Conn
@@ -31,14 +107,24 @@ type Listener struct {
 	connWG   sync.WaitGroup
 
 	readWG   sync.WaitGroup
-	errClose atomic.Value // error
+	errClose atomic.Value  // error
+	closedCh chan struct{} // closed with pConn
 }
 
 // Accept waits for and returns the next connection to the listener.
 func (l *Listener) Accept() (*Conn, error) {
 	select {
 	case c := <-l.acceptCh:
+		l.connLock.Lock()
+		defer l.connLock.Unlock()
+		// Close may have run since the Conn was received, connWG must not
+		// be incremented once it could have reached zero
+		if !l.accepting.Load().(bool) {
+			delete(l.conns, c.rAddr.String())
+			return nil, errClosedListener
+		}
 		l.connWG.Add(1)
+		atomic.StoreInt32(&c.accepted, 1)
 		return c, nil
 
 	case <-l.doneCh:
@@ -51,11 +137,23 @@ func (l *Listener) Accept() (*Conn, error) {
 func (l *Listener) Close() error {
 	var err error
 	l.doneOnce.Do(func() {
-		l.connWG.Done()
+		l.connLock.Lock()
 		l.accepting.Store(false)
+		l.connLock.Unlock()
+		l.connWG.Done()
 		close(l.doneCh)
 
 		l.connLock.Lock()
+		// Conns waiting in the backlog will never be accepted
+	drain:
+		for {
+			select {
+			case c := <-l.acceptCh:
+				delete(l.conns, c.rAddr.String())
+			default:
+				break drain
+			}
+		}
 		nConns := len(l.conns)
 		l.connLock.Unlock()
 
@@ -78,18 +176,67 @@ func (l *Listener) Addr() net.Addr {
 	return l.pConn.LocalAddr()
 }
 
-// Listen creates a new listener
+// Stats returns the counters of datagrams the Listener could not deliver
+func (l *Listener) Stats() ListenerStats {
+	return ListenerStats{
+		DroppedPackets: atomic.LoadUint64(&l.droppedPackets),
+		RejectedConns:  atomic.LoadUint64(&l.rejectedConns),
+	}
+}
+
+// Listen creates a new listener using the default ListenConfig
 func Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
+	return (&ListenConfig{}).Listen(network, laddr)
+}
+
+// NewListener creates a new listener serving the remotes of an existing
+// PacketConn using the default ListenConfig
+func NewListener(conn net.PacketConn) *Listener {
+	return (&ListenConfig{}).NewListener(conn)
+}
+
+// Listen creates a new listener based on the ListenConfig
+func (lc *ListenConfig) Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
 	conn, err := net.ListenUDP(network, laddr)
 	if err != nil {
 		return nil, err
 	}
 
+	return lc.NewListener(conn), nil
+}
+
+// NewListener creates a new listener based on the ListenConfig serving the
+// remotes of an existing PacketConn. The Listener takes ownership of the
+// PacketConn, it is closed once the Listener and all of its Conns are closed.
+func (lc *ListenConfig) NewListener(conn net.PacketConn) *Listener {
+	backlog := lc.Backlog
+	if backlog <= 0 {
+		backlog = defaultBacklog
+	}
+	readBufferSize := lc.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = defaultReadBufferSize
+	}
+
 	l := &Listener{
-		pConn:    conn,
-		acceptCh: make(chan *Conn),
-		conns:    make(map[string]*Conn),
-		doneCh:   make(chan struct{}),
+		pConn:          conn,
+		readBufferSize: readBufferSize,
+		maxConns:       lc.MaxConns,
+		idleTimeout:    lc.IdleTimeout,
+		acceptFilter:   lc.AcceptFilter,
+		onReject:       lc.OnReject,
+		acceptCh:       make(chan *Conn, backlog),
+		conns:          make(map[string]*Conn),
+		doneCh:         make(chan struct{}),
+		closedCh:       make(chan struct{}),
+	}
+	batchSize := lc.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	l.batch = newBatchConn(conn, batchSize)
+	if lc.ConnRateLimit > 0 {
+		l.rateLimiter = newRateLimiter(lc.ConnRateLimit, lc.ConnRateBurst)
 	}
 	l.accepting.Store(true)
 	l.connWG.Add(1)
@@ -101,32 +248,67 @@ func Listen(network string, laddr *net.UDPAddr) (*Listener, error) {
 		if err := l.pConn.Close(); err != nil {
 			l.errClose.Store(err)
 		}
+		close(l.closedCh)
 		l.readWG.Done()
 	}()
+	if l.idleTimeout > 0 {
+		go l.reapLoop()
+	}
 
-	return l, nil
+	return l
 }
 
 // readLoop has to tasks:
 // 1. Dispatching incoming packets to the correct Conn.
 //    It can therefore not be ended until all Conns are closed.
 // 2. Creating a new Conn when receiving from a new remote.
+//
+// It never blocks on a Conn or on Accept, datagrams that can not be
+// queued are dropped and counted instead.
 func (l *Listener) readLoop() {
 	defer l.readWG.Done()
-	buf := make([]byte, receiveMTU)
+
+	if l.batch != nil {
+		for {
+			if err := l.batch.ReadBatch(l.dispatch); err != nil {
+				return
+			}
+		}
+	}
 
 	for {
-		n, raddr, err := l.pConn.ReadFrom(buf)
+		b := GetBuffer()
+		n, raddr, err := l.pConn.ReadFrom(b.data)
 		if err != nil {
+			b.Release()
 			return
 		}
-		conn, err := l.getConn(raddr)
-		if err != nil {
-			continue
+		b.n = n
+		l.dispatch(b, raddr)
+	}
+}
+
+// dispatch queues a datagram for the Conn of its remote without copying
+// it, the Conn holds the buffer until the datagram is read
+func (l *Listener) dispatch(b *Buffer, raddr net.Addr) {
+	conn, err := l.getConn(raddr)
+	if err != nil {
+		b.Release()
+		if err != errClosedListener {
+			atomic.AddUint64(&l.rejectedConns, 1)
+			if l.onReject != nil {
+				l.onReject(raddr, err)
+			}
 		}
-		cBuf := <-conn.readCh
-		n = copy(cBuf, buf[:n])
-		conn.sizeCh <- n
+		return
+	}
+	atomic.StoreInt64(&conn.lastReceived, time.Now().UnixNano())
+	select {
+	case conn.readCh <- b:
+	default:
+		b.Release()
+		atomic.AddUint64(&conn.droppedPackets, 1)
+		atomic.AddUint64(&l.droppedPackets, 1)
 	}
 }
 
@@ -138,6 +320,22 @@ func (l *Listener) getConn(raddr net.Addr) (*Conn, error) {
 		if !l.accepting.Load().(bool) {
 			return nil, errClosedListener
 		}
+		// readLoop is the only sender, so there is room to queue the Conn
+		// below once this check passed
+		if len(l.acceptCh) == cap(l.acceptCh) {
+			return nil, ErrBacklogFull
+		}
+		if l.maxConns > 0 && len(l.conns) >= l.maxConns {
+			return nil, ErrTooManyConns
+		}
+		if l.rateLimiter != nil && !l.rateLimiter.allow(raddr, time.Now()) {
+			return nil, ErrRateLimited
+		}
+		if l.acceptFilter != nil {
+			if err := l.acceptFilter(raddr); err != nil {
+				return nil, err
+			}
+		}
 		conn = l.newConn(raddr)
 		l.conns[raddr.String()] = conn
 		l.acceptCh <- conn
@@ -145,44 +343,89 @@ func (l *Listener) getConn(raddr net.Addr) (*Conn, error) {
 	return conn, nil
 }
 
+// reapLoop closes the accepted Conns which have been idle for idleTimeout
+func (l *Listener) reapLoop() {
+	ticker := time.NewTicker(l.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			var idle []*Conn
+			l.connLock.Lock()
+			for _, c := range l.conns {
+				if atomic.LoadInt32(&c.accepted) == 1 &&
+					now.Sub(time.Unix(0, atomic.LoadInt64(&c.lastReceived))) >= l.idleTimeout {
+					idle = append(idle, c)
+				}
+			}
+			l.connLock.Unlock()
+
+			for _, c := range idle {
+				_ = c.Close()
+			}
+		case <-l.closedCh:
+			return
+		}
+	}
+}
+
 // Conn augments a connection-oriented connection over a UDP PacketConn
 type Conn struct {
+	// 64-bit atomics first for alignment on 32-bit platforms
+	droppedPackets uint64
+	lastReceived   int64 // UnixNano
+
 	listener *Listener
 
 	rAddr net.Addr
 
-	readCh chan []byte
-	sizeCh chan int
+	readCh   chan *Buffer
+	accepted int32 // atomic, bool
 
 	doneCh   chan struct{}
 	doneOnce sync.Once
 
 	readDeadline  *deadline.Deadline
 	writeDeadline *deadline.Deadline
+
+	batchLock   sync.Mutex // Serializes WriteBatch, which reuses batchBuffer
+	batchBuffer batchWriteBuffer
 }
 
 func (l *Listener) newConn(rAddr net.Addr) *Conn {
 	return &Conn{
 		listener:      l,
 		rAddr:         rAddr,
-		readCh:        make(chan []byte),
-		sizeCh:        make(chan int),
+		readCh:        make(chan *Buffer, l.readBufferSize),
+		lastReceived:  time.Now().UnixNano(),
 		doneCh:        make(chan struct{}),
 		readDeadline:  deadline.New(),
 		writeDeadline: deadline.New(),
 	}
 }
 
-// Read
+// Read reads the next datagram of the remote into p, the part of the
+// datagram that does not fit into p is discarded
 func (c *Conn) Read(p []byte) (int, error) {
+	b, err := c.ReadBuffer()
+	if err != nil {
+		return 0, err
+	}
+	defer b.Release()
+	return copy(p, b.Bytes()), nil
+}
+
+// ReadBuffer returns the next datagram of the remote in the buffer it was
+// received in, the caller has to release it
+func (c *Conn) ReadBuffer() (*Buffer, error) {
 	select {
-	case c.readCh <- p:
-		n := <-c.sizeCh
-		return n, nil
+	case b := <-c.readCh:
+		return b, nil
 	case <-c.doneCh:
-		return 0, io.EOF
+		return nil, io.EOF
 	case <-c.readDeadline.Done():
-		return 0, context.DeadlineExceeded
+		return nil, context.DeadlineExceeded
 	}
 }
 
@@ -196,6 +439,28 @@ func (c *Conn) Write(p []byte) (n int, err error) {
 	return c.listener.pConn.WriteTo(p, c.rAddr)
 }
 
+// WriteBatch writes several datagrams to the remote, with a single syscall
+// if the Listener batches datagrams. It returns how many were written.
+func (c *Conn) WriteBatch(datagrams [][]byte) (int, error) {
+	select {
+	case <-c.writeDeadline.Done():
+		return 0, context.DeadlineExceeded
+	default:
+	}
+	if c.listener.batch != nil {
+		c.batchLock.Lock()
+		defer c.batchLock.Unlock()
+		return c.listener.batch.WriteBatch(datagrams, c.rAddr, &c.batchBuffer)
+	}
+
+	for i, p := range datagrams {
+		if _, err := c.listener.pConn.WriteTo(p, c.rAddr); err != nil {
+			return i, err
+		}
+	}
+	return len(datagrams), nil
+}
+
 // Close closes the conn and releases any Read calls
 func (c *Conn) Close() error {
 	var err error
@@ -221,6 +486,12 @@ func (c *Conn) Close() error {
 	return err
 }
 
+// DroppedPackets returns the number of datagrams of the remote dropped
+// because they were not read fast enough
+func (c *Conn) DroppedPackets() uint64 {
+	return atomic.LoadUint64(&c.droppedPackets)
+}
+
 // LocalAddr implements net.Conn.LocalAddr
 func (c *Conn) LocalAddr() net.Addr {
 	return c.listener.pConn.LocalAddr()
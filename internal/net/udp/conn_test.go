@@ -3,7 +3,9 @@
 package udp
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"testing"
 	"time"
@@ -221,3 +223,354 @@ func TestConnClose(t *testing.T) {
 		}
 	})
 }
+
+func TestConnReadBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, batchSize := range []int{1, defaultBatchSize} {
+		batchSize := batchSize
+		t.Run(fmt.Sprintf("BatchSize%d", batchSize), func(t *testing.T) {
+			network, addr := getConfig()
+			listener, err := (&ListenConfig{BatchSize: batchSize}).Listen(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dConn, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range []string{"first", "second"} {
+				if _, err = dConn.Write([]byte(d)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lConn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A held buffer is not reused for the following datagrams
+			first, err := lConn.ReadBuffer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := lConn.ReadBuffer()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(first.Bytes()) != "first" || string(second.Bytes()) != "second" {
+				t.Errorf("ReadBuffer: expected [first second] actual [%s %s]", first.Bytes(), second.Bytes())
+			}
+			first.Release()
+			second.Release()
+
+			if err = lConn.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err = listener.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err = dConn.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestNewListener(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	network, addr := getConfig()
+	pConn, err := net.ListenUDP(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := NewListener(pConn)
+	if listener.Addr() != pConn.LocalAddr() {
+		t.Errorf("Addr: expected(%v) actual(%v)", pConn.LocalAddr(), listener.Addr())
+	}
+
+	dConn, err := net.DialUDP(network, nil, pConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dConn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	lConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lConn.RemoteAddr().String() != dConn.LocalAddr().String() {
+		t.Errorf("RemoteAddr: expected(%v) actual(%v)", dConn.LocalAddr(), lConn.RemoteAddr())
+	}
+	buf := make([]byte, receiveMTU)
+	if _, err = lConn.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = lConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = dConn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The PacketConn is owned by the listener
+	if err = pConn.Close(); err == nil {
+		t.Error("PacketConn was not closed with the listener")
+	}
+}
+
+func TestListenerNoHeadOfLineBlocking(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{ReadBufferSize: 4}).Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first remote is never read, once its buffer is full
+	// its datagrams are dropped without stalling the second remote
+	slow, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err = slow.Write([]byte("slow")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	slowConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fast, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fast.Write([]byte("fast")); err != nil {
+		t.Fatal(err)
+	}
+	fastConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	n, err := fastConn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(buf[:n]) != "fast" {
+		t.Errorf("Read: expected(fast) actual(%s)", buf[:n])
+	}
+
+	if dropped := slowConn.DroppedPackets(); dropped != 6 {
+		t.Errorf("DroppedPackets: expected(6) actual(%d)", dropped)
+	}
+	if stats := listener.Stats(); stats.DroppedPackets != 6 {
+		t.Errorf("Stats().DroppedPackets: expected(6) actual(%d)", stats.DroppedPackets)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err = slowConn.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []io.Closer{slowConn, fastConn, listener, slow, fast} {
+		if err = c.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestListenerBacklog(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{Backlog: 1}).Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dConns []*net.UDPConn
+	for i := 0; i < 3; i++ {
+		dConn, errDial := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+		if errDial != nil {
+			t.Fatal(errDial)
+		}
+		defer func() {
+			_ = dConn.Close()
+		}()
+		if _, err = dConn.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		dConns = append(dConns, dConn)
+	}
+
+	// Only the first remote fits into the backlog
+	for listener.Stats().RejectedConns != 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lConn.RemoteAddr().String() != dConns[0].LocalAddr().String() {
+		t.Errorf("RemoteAddr: expected(%v) actual(%v)", dConns[0].LocalAddr(), lConn.RemoteAddr())
+	}
+
+	// A rejected remote is accepted once the backlog has room again
+	if _, err = dConns[1].Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	retried, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.RemoteAddr().String() != dConns[1].LocalAddr().String() {
+		t.Errorf("RemoteAddr: expected(%v) actual(%v)", dConns[1].LocalAddr(), retried.RemoteAddr())
+	}
+
+	for _, c := range []io.Closer{lConn, retried, listener} {
+		if err = c.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestListenerLimits(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	errFilter := errors.New("filtered")
+	for _, test := range []struct {
+		Name     string
+		Config   ListenConfig
+		Reason   error
+		Accepted bool
+	}{
+		{"MaxConns", ListenConfig{MaxConns: 1}, ErrTooManyConns, true},
+		{"ConnRateLimit", ListenConfig{ConnRateLimit: 0.001, ConnRateBurst: 1}, ErrRateLimited, true},
+		{"AcceptFilter", ListenConfig{AcceptFilter: func(net.Addr) error {
+			return errFilter
+		}}, errFilter, false},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			rejected := make(chan error, 10)
+			test.Config.OnReject = func(raddr net.Addr, reason error) {
+				rejected <- reason
+			}
+
+			network, addr := getConfig()
+			listener, err := test.Config.Listen(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var conns []io.Closer
+			for i := 0; i < 2; i++ {
+				dConn, errDial := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+				if errDial != nil {
+					t.Fatal(errDial)
+				}
+				conns = append(conns, dConn)
+				if _, err = dConn.Write([]byte("hello")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if reason := <-rejected; reason != test.Reason {
+				t.Errorf("OnReject: expected(%v) actual(%v)", test.Reason, reason)
+			}
+			if test.Accepted {
+				lConn, errAccept := listener.Accept()
+				if errAccept != nil {
+					t.Fatal(errAccept)
+				}
+				conns = append(conns, lConn)
+			}
+			if stats := listener.Stats(); stats.RejectedConns == 0 {
+				t.Error("Stats().RejectedConns: expected rejected conns")
+			}
+
+			for _, c := range append([]io.Closer{listener}, conns...) {
+				if err = c.Close(); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestListenerIdleTimeout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	network, addr := getConfig()
+	listener, err := (&ListenConfig{IdleTimeout: 100 * time.Millisecond}).Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dConn, err := net.DialUDP(network, nil, listener.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = dConn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	lConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	if _, err = lConn.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The Conn is closed once it stops receiving
+	start := time.Now()
+	if _, err = lConn.Read(buf); err != io.EOF {
+		t.Errorf("Read of idle Conn: expected(%v) actual(%v)", io.EOF, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Conn was reaped after %v", elapsed)
+	}
+
+	if err = listener.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = dConn.Close(); err != nil {
+		t.Error(err)
+	}
+}
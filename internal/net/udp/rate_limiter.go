@@ -0,0 +1,75 @@
+package udp
+
+import (
+	"container/list"
+	"net"
+	"time"
+)
+
+// rateLimiterMaxEntries is the number of tracked IPs, the least recently
+// seen one is evicted to make room for a new one
+const rateLimiterMaxEntries = 4096
+
+// rateLimiter is a token bucket per remote IP
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	buckets map[string]*list.Element
+	lru     *list.List // *rateBucket, most recently seen first
+}
+
+type rateBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// allow takes a token from the bucket of the IP of addr if there is one
+func (r *rateLimiter) allow(addr net.Addr, now time.Time) bool {
+	key := addr.String()
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		key = udpAddr.IP.String()
+	}
+
+	var b *rateBucket
+	if e, ok := r.buckets[key]; ok {
+		r.lru.MoveToFront(e)
+		b = e.Value.(*rateBucket)
+		b.refill(r, now)
+	} else {
+		if r.lru.Len() >= rateLimiterMaxEntries {
+			oldest := r.lru.Back()
+			r.lru.Remove(oldest)
+			delete(r.buckets, oldest.Value.(*rateBucket).key)
+		}
+		b = &rateBucket{key: key, tokens: r.burst, last: now}
+		r.buckets[key] = r.lru.PushFront(b)
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *rateBucket) refill(r *rateLimiter, now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * r.rate
+	if b.tokens > r.burst {
+		b.tokens = r.burst
+	}
+	b.last = now
+}
@@ -0,0 +1,64 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	r := newRateLimiter(2, 2)
+	now := time.Unix(0, 0)
+	a := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1000}
+	aOtherPort := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1001}
+	b := &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 1000}
+
+	for i, test := range []struct {
+		addr     net.Addr
+		elapsed  time.Duration
+		expected bool
+	}{
+		{a, 0, true},
+		{aOtherPort, 0, true},
+		{a, 0, false}, // burst of the IP is used
+		{b, 0, true},  // other IPs are not affected
+		{a, 250 * time.Millisecond, false},
+		{a, 250 * time.Millisecond, true}, // refilled with 2 per second
+		{a, 0, false},
+		{a, 10 * time.Second, true}, // refilled up to the burst only
+		{a, 0, true},
+		{a, 0, false},
+	} {
+		now = now.Add(test.elapsed)
+		if allowed := r.allow(test.addr, now); allowed != test.expected {
+			t.Errorf("%d: allow(%v): expected(%v) actual(%v)", i, test.addr, test.expected, allowed)
+		}
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	r := newRateLimiter(1, 1)
+	now := time.Unix(0, 0)
+	addr := func(i int) net.Addr {
+		return &net.UDPAddr{IP: net.ParseIP(fmt.Sprintf("10.0.%d.%d", i/256, i%256))}
+	}
+	for i := 0; i < rateLimiterMaxEntries; i++ {
+		r.allow(addr(i), now)
+	}
+
+	// The first IP is seen again, the second one becomes the least recent
+	if r.allow(addr(0), now) {
+		t.Error("First IP expected to be rate limited")
+	}
+	r.allow(&net.UDPAddr{IP: net.ParseIP("192.0.2.1")}, now)
+	if len(r.buckets) != rateLimiterMaxEntries || r.lru.Len() != rateLimiterMaxEntries {
+		t.Errorf("Buckets after eviction: expected(%d) actual(%d)", rateLimiterMaxEntries, len(r.buckets))
+	}
+	if _, ok := r.buckets[addr(1).(*net.UDPAddr).IP.String()]; ok {
+		t.Error("Least recently seen IP expected to be evicted")
+	}
+	if r.allow(addr(0), now) {
+		t.Error("First IP expected to be kept and still rate limited")
+	}
+}
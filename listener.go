@@ -1,53 +1,363 @@
 package dtls
 
 import (
+	"context"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/pion/dtls/v2/internal/net/udp"
 )
 
-// Listen creates a DTLS listener
+// Listener is a DTLS listener which can be shut down gracefully. It is
+// returned by the methods of ListenConfig, the net.Listener returned by
+// Listen and NewListener implements it as well:
+//
+//	listener.(dtls.Listener).Shutdown(ctx)
+type Listener interface {
+	net.Listener
+
+	// Shutdown closes the listener like Close, and then gracefully closes
+	// all accepted connections which are still open using CloseWithContext.
+	// It waits for the connections until ctx is done.
+	Shutdown(ctx context.Context) error
+}
+
+// Listen creates a DTLS listener, it implements Listener
 func Listen(network string, laddr *net.UDPAddr, config *Config) (net.Listener, error) {
+	return (&ListenConfig{}).Listen(network, laddr, config)
+}
+
+// NewListener creates a DTLS listener which accepts connections from the
+// remotes of an existing PacketConn, such as a TURN allocation or a socket
+// configured with SO_REUSEPORT. The listener takes ownership of the
+// PacketConn, it is closed once the listener and all accepted connections
+// are closed. The returned listener implements Listener.
+func NewListener(pc net.PacketConn, config *Config) (net.Listener, error) {
+	return (&ListenConfig{}).NewListener(pc, config)
+}
+
+// ListenConfig contains options limiting the resources a DTLS listener
+// spends on remotes. The zero value only bounds the queues of the listener.
+type ListenConfig struct {
+	// Backlog is the maximum number of new connections waiting for a
+	// handshake worker, and of handshaked connections waiting for Accept.
+	// The default is 128.
+	Backlog int
+
+	// ReadBufferSize is the maximum number of datagrams queued for a
+	// connection that is not read fast enough. The default is 64.
+	ReadBufferSize int
+
+	// HandshakeWorkers is the maximum number of handshakes performed
+	// concurrently, further connections wait in the backlog. The default is 16.
+	HandshakeWorkers int
+
+	// OnHandshakeError, if not nil, is called with the error of every
+	// handshake that failed, those connections are never returned by Accept.
+	OnHandshakeError func(raddr net.Addr, err error)
+
+	// MaxConns is the maximum number of connections, including the ones
+	// waiting for Accept or in handshake. If zero, it is not limited.
+	MaxConns int
+
+	// MaxPendingHandshakes is the maximum number of connections which have
+	// not finished the handshake yet. If zero, it is not limited.
+	MaxPendingHandshakes int
+
+	// ConnRateLimit is the number of new connections per second allowed from
+	// a single remote IP, with bursts of up to ConnRateBurst connections.
+	// If zero, the rate is not limited.
+	ConnRateLimit float64
+	ConnRateBurst int
+
+	// IdleTimeout is the duration after which a connection that has not
+	// received any datagram is closed. If zero, idle connections are kept.
+	IdleTimeout time.Duration
+
+	// OnReject, if not nil, is called when the datagram of a new remote is
+	// dropped instead of creating a connection. The reason is one of
+	// ErrListenerBacklogFull, ErrTooManyConns, ErrTooManyPendingHandshakes
+	// or ErrConnRateLimited. It is called from the read loop of the
+	// listener and must not block.
+	OnReject func(raddr net.Addr, reason error)
+}
+
+// Listen creates a DTLS listener based on the ListenConfig
+func (lc *ListenConfig) Listen(network string, laddr *net.UDPAddr, config *Config) (Listener, error) {
 	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
 
-	parent, err := udp.Listen(network, laddr)
+	l := lc.newListener(config)
+	parent, err := lc.udpListenConfig(l).Listen(network, laddr)
 	if err != nil {
 		return nil, err
 	}
-	return &listener{
-		config: config,
-		parent: parent,
-	}, nil
+	l.start(parent)
+	return l, nil
+}
+
+// NewListener creates a DTLS listener based on the ListenConfig serving the
+// remotes of an existing PacketConn, see NewListener.
+func (lc *ListenConfig) NewListener(pc net.PacketConn, config *Config) (Listener, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+
+	l := lc.newListener(config)
+	l.start(lc.udpListenConfig(l).NewListener(pc))
+	return l, nil
 }
 
+func (lc *ListenConfig) newListener(config *Config) *listener {
+	handshakeWorkers := lc.HandshakeWorkers
+	if handshakeWorkers <= 0 {
+		handshakeWorkers = defaultHandshakeWorkers
+	}
+	backlog := lc.Backlog
+	if backlog <= 0 {
+		backlog = defaultBacklog
+	}
+
+	l := &listener{
+		config:           config,
+		onReject:         lc.OnReject,
+		onHandshakeError: lc.OnHandshakeError,
+		handshakeWorkers: make(chan struct{}, handshakeWorkers),
+		connCh:           make(chan net.Conn, backlog),
+		conns:            map[*Conn]struct{}{},
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	if lc.MaxPendingHandshakes > 0 {
+		l.pendingHandshakes = make(chan struct{}, lc.MaxPendingHandshakes)
+	}
+	return l
+}
+
+func (lc *ListenConfig) udpListenConfig(l *listener) *udp.ListenConfig {
+	return &udp.ListenConfig{
+		Backlog:        lc.Backlog,
+		ReadBufferSize: lc.ReadBufferSize,
+		MaxConns:       lc.MaxConns,
+		ConnRateLimit:  lc.ConnRateLimit,
+		ConnRateBurst:  lc.ConnRateBurst,
+		IdleTimeout:    lc.IdleTimeout,
+		AcceptFilter:   l.acceptFilter,
+		OnReject:       l.reject,
+	}
+}
+
+const (
+	defaultHandshakeWorkers = 16
+	defaultBacklog          = 128
+)
+
 // listener represents a DTLS listener
 type listener struct {
 	config *Config
 	parent *udp.Listener
+
+	pendingHandshakes chan struct{} // nil if not limited
+	onReject          func(net.Addr, error)
+	onHandshakeError  func(net.Addr, error)
+
+	handshakeWorkers chan struct{}
+	connCh           chan net.Conn // handshaked connections waiting for Accept
+
+	connsLock sync.Mutex
+	conns     map[*Conn]struct{} // open connections, closed by Shutdown
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (l *listener) start(parent *udp.Listener) {
+	l.parent = parent
+	l.wg.Add(1)
+	go l.acceptLoop()
+}
+
+// acceptLoop starts the handshake of every new connection
+// once a worker is available
+func (l *listener) acceptLoop() {
+	defer l.wg.Done()
+
+	for {
+		c, err := l.parent.Accept()
+		if err != nil {
+			return
+		}
+
+		select {
+		case l.handshakeWorkers <- struct{}{}:
+		case <-l.ctx.Done():
+			l.releasePendingHandshake()
+			_ = c.Close()
+			return
+		}
+		l.wg.Add(1)
+		go l.handshake(c)
+	}
+}
+
+// handshake runs in a worker until the handshake is done, the worker is
+// released before the connection waits for Accept. The handshake will timeout
+// using the ConnectContextMaker in the Config, or when the listener is closed.
+func (l *listener) handshake(c net.Conn) {
+	defer l.wg.Done()
+
+	conn, err := l.serverHandshake(c)
+	<-l.handshakeWorkers
+	if err != nil {
+		_ = c.Close()
+		if l.onHandshakeError != nil && l.ctx.Err() == nil {
+			l.onHandshakeError(c.RemoteAddr(), err)
+		}
+		return
+	}
+
+	l.track(conn)
+	select {
+	case l.connCh <- conn:
+	case <-l.ctx.Done():
+		_ = conn.Close()
+	}
+}
+
+func (l *listener) serverHandshake(c net.Conn) (*Conn, error) {
+	ctx, cancel := l.config.connectContextMaker()
+	defer cancel()
+	go func() {
+		select {
+		case <-l.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	conn, err := ServerWithContext(ctx, c, l.config)
+	l.releasePendingHandshake()
+	return conn, err
+}
+
+// track remembers conn for Shutdown until it is closed
+func (l *listener) track(conn *Conn) {
+	l.connsLock.Lock()
+	l.conns[conn] = struct{}{}
+	l.connsLock.Unlock()
+
+	go func() {
+		<-conn.connectionClosed.Done()
+		l.connsLock.Lock()
+		delete(l.conns, conn)
+		l.connsLock.Unlock()
+	}()
+}
+
+func (l *listener) releasePendingHandshake() {
+	if l.pendingHandshakes != nil {
+		<-l.pendingHandshakes
+	}
 }
 
 // Accept waits for and returns the next connection to the listener.
-// You have to either close or read on all connection that are created.
-// Connection handshake will timeout using ConnectContextMaker in the Config.
-// If you want to specify the timeout duration, set ConnectContextMaker.
+// Handshakes are performed concurrently in the background and only
+// connections that completed their handshake are returned.
+// You have to either close or read on all connection that are returned.
 func (l *listener) Accept() (net.Conn, error) {
-	c, err := l.parent.Accept()
-	if err != nil {
-		return nil, err
+	if l.ctx.Err() != nil {
+		return nil, ErrListenerClosed
+	}
+
+	select {
+	case c := <-l.connCh:
+		// Close may have drained connCh before c was received
+		if l.ctx.Err() != nil {
+			_ = c.Close()
+			return nil, ErrListenerClosed
+		}
+		return c, nil
+	case <-l.ctx.Done():
+		return nil, ErrListenerClosed
 	}
-	return Server(c, l.config)
 }
 
 // Close closes the listener.
-// Any blocked Accept operations will be unblocked and return errors.
-// Already Accepted connections are not closed.
+// Any blocked Accept operations will be unblocked and return ErrListenerClosed.
+// Handshakes in progress are aborted and connections waiting for Accept are
+// closed, already Accepted connections are not closed.
 func (l *listener) Close() error {
-	return l.parent.Close()
+	l.cancel()
+	err := l.parent.Close()
+	l.wg.Wait()
+
+	for {
+		select {
+		case c := <-l.connCh:
+			_ = c.Close()
+		default:
+			return err
+		}
+	}
+}
+
+// Shutdown closes the listener and gracefully closes all accepted
+// connections, see Listener.
+func (l *listener) Shutdown(ctx context.Context) error {
+	err := l.Close()
+
+	l.connsLock.Lock()
+	conns := make([]*Conn, 0, len(l.conns))
+	for conn := range l.conns {
+		conns = append(conns, conn)
+	}
+	l.connsLock.Unlock()
+
+	errs := make(chan error, len(conns))
+	for _, conn := range conns {
+		go func(conn *Conn) {
+			errs <- conn.CloseWithContext(ctx)
+		}(conn)
+	}
+	for range conns {
+		if closeErr := <-errs; err == nil {
+			err = closeErr
+		}
+	}
+	return err
 }
 
 // Addr returns the listener's network address.
 func (l *listener) Addr() net.Addr {
 	return l.parent.Addr()
 }
+
+// acceptFilter reserves a pending handshake for a new remote
+func (l *listener) acceptFilter(net.Addr) error {
+	if l.pendingHandshakes == nil {
+		return nil
+	}
+	select {
+	case l.pendingHandshakes <- struct{}{}:
+		return nil
+	default:
+		return ErrTooManyPendingHandshakes
+	}
+}
+
+func (l *listener) reject(raddr net.Addr, reason error) {
+	if l.onReject == nil {
+		return
+	}
+	switch reason {
+	case udp.ErrBacklogFull:
+		reason = ErrListenerBacklogFull
+	case udp.ErrTooManyConns:
+		reason = ErrTooManyConns
+	case udp.ErrRateLimited:
+		reason = ErrConnRateLimited
+	}
+	l.onReject(raddr, reason)
+}
@@ -0,0 +1,390 @@
+package dtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/transport/test"
+)
+
+// closeRecordingPacketConn is a PacketConn not created by the listener
+type closeRecordingPacketConn struct {
+	net.PacketConn
+	closed int32
+}
+
+func (c *closeRecordingPacketConn) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return c.PacketConn.Close()
+}
+
+func TestNewListener(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := &closeRecordingPacketConn{PacketConn: udpConn}
+
+	if _, err = NewListener(pc, &Config{Certificates: []tls.Certificate{serverCert}, PSK: func([]byte) ([]byte, error) {
+		return nil, nil
+	}}); err != errPSKAndCertificate {
+		t.Fatalf("NewListener with invalid config: expected(%v) actual(%v)", errPSKAndCertificate, err)
+	}
+
+	listener, err := NewListener(pc, &Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listener.Addr().String() != udpConn.LocalAddr().String() {
+		t.Errorf("Addr: expected(%v) actual(%v)", udpConn.LocalAddr(), listener.Addr())
+	}
+
+	message := []byte("Hello")
+	serverErr := make(chan error, 1)
+	go func() {
+		server, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		buf := make([]byte, 1024)
+		n, err := server.Read(buf)
+		if err == nil {
+			_, err = server.Write(buf[:n])
+		}
+		if closeErr := server.Close(); err == nil {
+			err = closeErr
+		}
+		serverErr <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := DialWithContext(ctx, "udp", listener.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Write(message); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:n], message) {
+		t.Errorf("Echo: expected(%s) actual(%s)", message, buf[:n])
+	}
+	if err = <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close()
+
+	if err = listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&pc.closed) != 1 {
+		t.Error("PacketConn was not closed with the listener")
+	}
+}
+
+func TestListenConfigLimits(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		Name         string
+		ListenConfig ListenConfig
+		Remotes      int
+		Reason       error
+	}{
+		{"MaxPendingHandshakes", ListenConfig{MaxPendingHandshakes: 1}, 2, ErrTooManyPendingHandshakes},
+		{"MaxConns", ListenConfig{MaxConns: 1}, 2, ErrTooManyConns},
+		{"Backlog", ListenConfig{Backlog: 1, HandshakeWorkers: 1}, 3, ErrListenerBacklogFull},
+		{"ConnRateLimit", ListenConfig{ConnRateLimit: 0.001}, 2, ErrConnRateLimited},
+	} {
+		test := test
+		t.Run(test.Name, func(t *testing.T) {
+			rejected := make(chan error, 10)
+			test.ListenConfig.OnReject = func(raddr net.Addr, reason error) {
+				rejected <- reason
+			}
+			listener, err := test.ListenConfig.Listen("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &Config{
+				Certificates: []tls.Certificate{serverCert},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = listener.Close()
+			}()
+
+			// The remotes never finish their handshake
+			for i := 0; i < test.Remotes; i++ {
+				conn, err := net.DialUDP("udp", nil, listener.Addr().(*net.UDPAddr))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer func() {
+					_ = conn.Close()
+				}()
+				if _, err = conn.Write([]byte("ClientHello")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if reason := <-rejected; reason != test.Reason {
+				t.Errorf("OnReject: expected(%v) actual(%v)", test.Reason, reason)
+			}
+		})
+	}
+}
+
+func TestListenerConcurrentHandshakes(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handshakeErrs := make(chan error, 10)
+	listener, err := (&ListenConfig{
+		OnHandshakeError: func(raddr net.Addr, err error) {
+			handshakeErrs <- err
+		},
+	}).Listen("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &Config{
+		Certificates: []tls.Certificate{serverCert},
+		CipherSuites: []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	addr := listener.Addr().(*net.UDPAddr)
+
+	// A remote that never finishes its handshake does not delay others
+	stalled, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = stalled.Close()
+	}()
+	clientHello, err := (&recordlayer.RecordLayer{
+		Header: recordlayer.Header{Version: protocol.Version1_2},
+		Content: &handshake.Handshake{Message: &handshake.MessageClientHello{
+			Version:            protocol.Version1_2,
+			CipherSuiteIDs:     []uint16{uint16(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256)},
+			CompressionMethods: defaultCompressionMethods(),
+		}},
+	}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stalled.Write(clientHello); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed handshake is reported and never accepted
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err = DialWithContext(ctx, "udp", addr, &Config{
+		InsecureSkipVerify: true,
+		CipherSuites:       []CipherSuiteID{TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA},
+	}); err == nil {
+		t.Fatal("Handshake without a shared cipher suite succeeded")
+	}
+	if err = <-handshakeErrs; !errors.Is(err, errCipherSuiteNoIntersection) {
+		t.Errorf("OnHandshakeError: expected(%v) actual(%v)", errCipherSuiteNoIntersection, err)
+	}
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+	client, err := DialWithContext(ctx, "udp", addr, &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server.RemoteAddr().String() != client.LocalAddr().String() {
+		t.Errorf("Accept: expected(%v) actual(%v)", client.LocalAddr(), server.RemoteAddr())
+	}
+	_ = client.Close()
+	_ = server.Close()
+}
+
+func TestListenerSlowAccept(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := (&ListenConfig{HandshakeWorkers: 1}).Listen("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	// Handshaked connections waiting for Accept don't hold the worker
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clients := []net.Conn{}
+	for i := 0; i < 3; i++ {
+		client, err := DialWithContext(ctx, "udp", listener.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+	}
+
+	for _, client := range clients {
+		server, err := listener.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = server.Close()
+		_ = client.Close()
+	}
+}
+
+func TestListenerCloseBacklog(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc := &closeRecordingPacketConn{PacketConn: udpConn}
+	listener, err := (&ListenConfig{}).NewListener(pc, &Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	clients := []net.Conn{}
+	for i := 0; i < 3; i++ {
+		client, err := DialWithContext(ctx, "udp", listener.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+		clients = append(clients, client)
+	}
+
+	// Connections which were never accepted are closed with the listener
+	if err = listener.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, client := range clients {
+		if _, err := client.Read(make([]byte, 64)); err != io.EOF {
+			t.Errorf("Client Read: expected(%v) actual(%v)", io.EOF, err)
+		}
+	}
+	if atomic.LoadInt32(&pc.closed) != 1 {
+		t.Error("PacketConn was not closed with the listener")
+	}
+	if _, err := listener.Accept(); err != ErrListenerClosed {
+		t.Errorf("Accept: expected(%v) actual(%v)", ErrListenerClosed, err)
+	}
+}
+
+func TestListenerShutdown(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	serverCert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := Listen("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")}, &Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		server, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- server
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := DialWithContext(ctx, "udp", listener.Addr().(*net.UDPAddr), &Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+
+	// The client responds to the close_notify of the server
+	if err = listener.(Listener).Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Read(make([]byte, 64)); err != io.EOF {
+		t.Errorf("Client Read: expected(%v) actual(%v)", io.EOF, err)
+	}
+	if _, err = server.Write([]byte("Hello")); err != ErrConnClosed {
+		t.Errorf("Server Write: expected(%v) actual(%v)", ErrConnClosed, err)
+	}
+	if _, err = listener.Accept(); err != ErrListenerClosed {
+		t.Errorf("Accept: expected(%v) actual(%v)", ErrListenerClosed, err)
+	}
+	_ = client.Close()
+}
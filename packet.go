@@ -1,7 +1,10 @@
 package dtls
 
+import (
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
 type packet struct {
-	record                   *recordLayer
-	shouldEncrypt            bool
-	resetLocalSequenceNumber bool
+	record        *recordlayer.RecordLayer
+	shouldEncrypt bool
 }
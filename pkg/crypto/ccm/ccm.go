@@ -119,17 +119,24 @@ var (
 
 func (c *ccm) tag(nonce, plaintext, adata []byte) ([]byte, error) {
 	var mac [ccmBlockSize]byte
+	if err := c.tagTo(&mac, nonce, plaintext, adata); err != nil {
+		return nil, err
+	}
+	return mac[:c.M], nil
+}
 
+// tagTo is tag without allocating, the tag is left in the first M bytes of mac
+func (c *ccm) tagTo(mac *[ccmBlockSize]byte, nonce, plaintext, adata []byte) error {
 	if len(adata) > 0 {
 		mac[0] |= 1 << 6
 	}
 	mac[0] |= (c.M - 2) << 2
 	mac[0] |= c.L - 1
 	if len(nonce) != c.NonceSize() {
-		return nil, errInvalidNonceSize
+		return errInvalidNonceSize
 	}
 	if len(plaintext) > c.MaxLength() {
-		return nil, errPlaintextTooLong
+		return errPlaintextTooLong
 	}
 	binary.BigEndian.PutUint64(mac[ccmBlockSize-8:], uint64(len(plaintext)))
 	copy(mac[1:ccmBlockSize-c.L], nonce)
@@ -161,7 +168,7 @@ func (c *ccm) tag(nonce, plaintext, adata []byte) ([]byte, error) {
 		c.cbcData(mac[:], plaintext)
 	}
 
-	return mac[:c.M], nil
+	return nil
 }
 
 // sliceForAppend takes a slice and a requested number of bytes. It returns a
@@ -215,6 +222,12 @@ var (
 	errCiphertextTooLong  = errors.New("ccm: ciphertext too long")
 )
 
+// Open decrypts and authenticates ciphertext, authenticates the
+// additional data and, if successful, appends the resulting plaintext
+// to dst, returning the updated slice.
+//
+// The ciphertext and dst may alias exactly or not at all. To reuse
+// ciphertext's storage for the decrypted output, use ciphertext[:0] as dst.
 func (c *ccm) Open(dst, nonce, ciphertext, adata []byte) ([]byte, error) {
 	if len(ciphertext) < int(c.M) {
 		return nil, errCiphertextTooShort
@@ -223,8 +236,8 @@ func (c *ccm) Open(dst, nonce, ciphertext, adata []byte) ([]byte, error) {
 		return nil, errCiphertextTooLong
 	}
 
-	var tag = make([]byte, int(c.M))
-	copy(tag, ciphertext[len(ciphertext)-int(c.M):])
+	var tag [ccmBlockSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-int(c.M):])
 	ciphertextWithoutTag := ciphertext[:len(ciphertext)-int(c.M)]
 
 	var iv, s0 [ccmBlockSize]byte
@@ -237,17 +250,26 @@ func (c *ccm) Open(dst, nonce, ciphertext, adata []byte) ([]byte, error) {
 	iv[len(iv)-1] |= 1
 	stream := cipher.NewCTR(c.b, iv[:])
 
-	// Cannot decrypt directly to dst since we're not supposed to
-	// reveal the plaintext to the caller if authentication fails.
-	plaintext := make([]byte, len(ciphertextWithoutTag))
+	// The plaintext is cleared if authentication fails, it must not be
+	// revealed to the caller
+	ret, plaintext := sliceForAppend(dst, len(ciphertextWithoutTag))
 	stream.XORKeyStream(plaintext, ciphertextWithoutTag)
-	expectedTag, err := c.tag(nonce, plaintext, adata)
-	if err != nil {
+
+	var expectedTag [ccmBlockSize]byte
+	if err := c.tagTo(&expectedTag, nonce, plaintext, adata); err != nil {
+		zero(plaintext)
 		return nil, err
 	}
 
-	if subtle.ConstantTimeCompare(tag, expectedTag) != 1 {
+	if subtle.ConstantTimeCompare(tag[:c.M], expectedTag[:c.M]) != 1 {
+		zero(plaintext)
 		return nil, errOpen
 	}
-	return append(dst, plaintext...), nil
+	return ret, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
 }
@@ -273,6 +273,18 @@ func TestRFC3610Vectors(t *testing.T) {
 						c.Data[c.ClearHeaderOctets:], dst)
 				}
 			})
+
+			t.Run("open in place", func(t *testing.T) {
+				ciphertext := append([]byte{}, c.CipherText[c.ClearHeaderOctets:]...)
+				dst, err := lccm.Open(ciphertext[:0], c.Nonce, ciphertext, c.CipherText[:c.ClearHeaderOctets])
+				if err != nil {
+					t.Fatalf("failed to unseal: %v", err)
+				}
+				if !bytes.Equal(c.Data[c.ClearHeaderOctets:], dst) {
+					t.Fatalf("plaintext does not match, wanted %v, got %v",
+						c.Data[c.ClearHeaderOctets:], dst)
+				}
+			})
 		})
 	}
 }
@@ -391,6 +403,13 @@ func TestOpenError(t *testing.T) {
 				Nonce:             mustHexDecode("00000003020100a0a1a2a3a4a5"),
 			}, errCiphertextTooLong,
 		},
+		"AuthenticationFailed": {
+			vector{
+				CipherText:        make([]byte, 40),
+				ClearHeaderOctets: 8,
+				Nonce:             mustHexDecode("00000003020100a0a1a2a3a4a5"),
+			}, errOpen,
+		},
 	}
 
 	blk, err := aes.NewCipher(aesKey1to12)
@@ -0,0 +1,20 @@
+// Package clientcertificate provides the ClientCertificateType registry
+package clientcertificate
+
+// Type is used to communicate what type of certificate is being transported
+// https://www.iana.org/assignments/tls-parameters/tls-parameters.xhtml#tls-parameters-2
+type Type byte
+
+// ClientCertificateType enums
+const (
+	RSASign   Type = 1
+	ECDSASign Type = 64
+)
+
+// Types returns all the supported client certificate types
+func Types() map[Type]bool {
+	return map[Type]bool{
+		RSASign:   true,
+		ECDSASign: true,
+	}
+}
@@ -0,0 +1,86 @@
+// Package elliptic provides the named curves of TLS and their key generation
+package elliptic
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+var errInvalidNamedCurve = errors.New("elliptic: invalid named curve")
+
+// CurveType is used to represent the type of the elliptic curve
+// https://www.iana.org/assignments/tls-parameters/tls-parameters.xhtml#tls-parameters-10
+type CurveType byte
+
+// CurveType enums
+const (
+	CurveTypeNamedCurve CurveType = 0x03
+)
+
+// CurveTypes returns all the supported curve types
+func CurveTypes() map[CurveType]struct{} {
+	return map[CurveType]struct{}{
+		CurveTypeNamedCurve: {},
+	}
+}
+
+// Curve is a named elliptic curve
+// https://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-8
+type Curve uint16
+
+// Curve enums
+const (
+	P256   Curve = 0x0017
+	P384   Curve = 0x0018
+	X25519 Curve = 0x001d
+)
+
+// Curves returns all the supported curves
+func Curves() map[Curve]bool {
+	return map[Curve]bool{
+		X25519: true,
+		P256:   true,
+		P384:   true,
+	}
+}
+
+// Keypair is an ephemeral key pair on a named curve
+type Keypair struct {
+	Curve      Curve
+	PublicKey  []byte
+	PrivateKey []byte
+}
+
+// GenerateKeypair generates a keypair for the given curve
+func GenerateKeypair(c Curve, rand io.Reader) (*Keypair, error) {
+	switch c {
+	case X25519:
+		tmp := make([]byte, 32)
+		if _, err := io.ReadFull(rand, tmp); err != nil {
+			return nil, err
+		}
+
+		var public, private [32]byte
+		copy(private[:], tmp)
+
+		curve25519.ScalarBaseMult(&public, &private)
+		return &Keypair{X25519, public[:], private[:]}, nil
+	case P256:
+		return ellipticCurveKeypair(P256, elliptic.P256(), elliptic.P256(), rand)
+	case P384:
+		return ellipticCurveKeypair(P384, elliptic.P384(), elliptic.P384(), rand)
+	}
+	return nil, errInvalidNamedCurve
+}
+
+func ellipticCurveKeypair(nc Curve, c1, c2 elliptic.Curve, rand io.Reader) (*Keypair, error) {
+	privateKey, x, y, err := elliptic.GenerateKey(c1, rand)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keypair{nc, elliptic.Marshal(c2, x, y), privateKey}, nil
+}
@@ -1,4 +1,4 @@
-package dtls
+package hash
 
 import (
 	"testing"
@@ -7,9 +7,9 @@ import (
 )
 
 func TestHashAlgorithm_StringRoundtrip(t *testing.T) {
-	for algo := range hashAlgorithms {
+	for algo := range Algorithms() {
 		str := algo.String()
-		hash1 := algo.cryptoHash()
+		hash1 := algo.CryptoHash()
 		hash2, err := fingerprint.HashFromString(str)
 		if err != nil {
 			t.Fatalf("fingerprint.HashFromString failed: %v", err)
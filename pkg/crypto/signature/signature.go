@@ -0,0 +1,20 @@
+// Package signature provides the TLS SignatureAlgorithm registry
+package signature
+
+// Algorithm as defined in TLS 1.2
+// https://www.iana.org/assignments/tls-parameters/tls-parameters.xhtml#tls-parameters-16
+type Algorithm uint16
+
+// SignatureAlgorithm enums
+const (
+	RSA   Algorithm = 1
+	ECDSA Algorithm = 3
+)
+
+// Algorithms returns all the supported signature algorithms
+func Algorithms() map[Algorithm]bool {
+	return map[Algorithm]bool{
+		RSA:   true,
+		ECDSA: true,
+	}
+}
@@ -0,0 +1,24 @@
+// Package signaturehash provides the SignatureAndHashAlgorithm pairs of
+// TLS 1.2
+package signaturehash
+
+import (
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+)
+
+// Algorithm is a signature/hash algorithm pair
+// https://tools.ietf.org/html/rfc5246#section-7.4.1.4.1
+type Algorithm struct {
+	Hash      hash.Algorithm
+	Signature signature.Algorithm
+}
+
+// IsSupported returns true if both the hash and the signature algorithm
+// are known
+func (a Algorithm) IsSupported() bool {
+	if _, ok := hash.Algorithms()[a.Hash]; !ok {
+		return false
+	}
+	return signature.Algorithms()[a.Signature]
+}
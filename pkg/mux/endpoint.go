@@ -0,0 +1,120 @@
+package mux
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/dtls/v2/internal/net/deadline"
+)
+
+// Endpoint implements net.Conn. It is used to read the packets
+// matched by its MatchFunc, writes go to the Conn of the Mux.
+type Endpoint struct {
+	droppedPackets uint64 // atomic, first for alignment on 32-bit platforms
+
+	mux   *Mux
+	match MatchFunc
+
+	readCh    chan []byte
+	doneCh    chan struct{}
+	closeOnce sync.Once
+
+	readDeadline *deadline.Deadline
+}
+
+func newEndpoint(m *Mux, f MatchFunc) *Endpoint {
+	return &Endpoint{
+		mux:          m,
+		match:        f,
+		readCh:       make(chan []byte, m.readBufferSize),
+		doneCh:       make(chan struct{}),
+		readDeadline: deadline.New(),
+	}
+}
+
+// deliver queues a copy of the packet, it is dropped if the queue is full
+func (e *Endpoint) deliver(buf []byte) {
+	packet := make([]byte, len(buf))
+	copy(packet, buf)
+
+	select {
+	case e.readCh <- packet:
+	case <-e.doneCh:
+	default:
+		atomic.AddUint64(&e.droppedPackets, 1)
+	}
+}
+
+func (e *Endpoint) close() {
+	e.closeOnce.Do(func() {
+		close(e.doneCh)
+	})
+}
+
+// Close unregisters the Endpoint from the Mux
+func (e *Endpoint) Close() error {
+	e.close()
+	e.mux.RemoveEndpoint(e)
+	return nil
+}
+
+// Read reads the next packet into p, the part of the packet that does
+// not fit into p is discarded
+func (e *Endpoint) Read(p []byte) (int, error) {
+	select {
+	case packet := <-e.readCh:
+		return copy(p, packet), nil
+	case <-e.doneCh:
+		return 0, io.EOF
+	case <-e.readDeadline.Done():
+		return 0, context.DeadlineExceeded
+	}
+}
+
+// Write writes len(p) bytes to the underlying Conn
+func (e *Endpoint) Write(p []byte) (int, error) {
+	select {
+	case <-e.doneCh:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	return e.mux.nextConn.Write(p)
+}
+
+// DroppedPackets returns the number of packets dropped because
+// they were not read fast enough
+func (e *Endpoint) DroppedPackets() uint64 {
+	return atomic.LoadUint64(&e.droppedPackets)
+}
+
+// LocalAddr returns the local address of the underlying Conn
+func (e *Endpoint) LocalAddr() net.Addr {
+	return e.mux.nextConn.LocalAddr()
+}
+
+// RemoteAddr returns the remote address of the underlying Conn
+func (e *Endpoint) RemoteAddr() net.Addr {
+	return e.mux.nextConn.RemoteAddr()
+}
+
+// SetDeadline sets the read deadline of the Endpoint, the write
+// deadline of the shared Conn is not changed
+func (e *Endpoint) SetDeadline(t time.Time) error {
+	return e.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the Endpoint
+func (e *Endpoint) SetReadDeadline(t time.Time) error {
+	e.readDeadline.Set(t)
+	return nil
+}
+
+// SetWriteDeadline is a stub, the deadline of the shared Conn
+// is not changed
+func (e *Endpoint) SetWriteDeadline(time.Time) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package mux
+
+// MatchFunc decides if a packet is routed to an Endpoint
+type MatchFunc func([]byte) bool
+
+// MatchAll always returns true
+func MatchAll([]byte) bool {
+	return true
+}
+
+// MatchRange returns a MatchFunc matching packets whose first byte is
+// within [lower, upper]
+func MatchRange(lower, upper byte) MatchFunc {
+	return func(buf []byte) bool {
+		if len(buf) < 1 {
+			return false
+		}
+		return buf[0] >= lower && buf[0] <= upper
+	}
+}
+
+// The first byte ranges of the protocols multiplexed on one 5-tuple
+// https://tools.ietf.org/html/rfc7983#section-7
+//
+//                 +----------------+
+//                 |        [0..3] -+--> forward to STUN
+//                 |                |
+//                 |      [16..19] -+--> forward to ZRTP
+//                 |                |
+//     packet -->  |      [20..63] -+--> forward to DTLS
+//                 |                |
+//                 |      [64..79] -+--> forward to TURN Channel
+//                 |                |
+//                 |    [128..191] -+--> forward to RTP/RTCP
+//                 +----------------+
+
+// MatchSTUN is a MatchFunc that accepts STUN packets
+func MatchSTUN(buf []byte) bool {
+	return MatchRange(0, 3)(buf)
+}
+
+// MatchZRTP is a MatchFunc that accepts ZRTP packets
+func MatchZRTP(buf []byte) bool {
+	return MatchRange(16, 19)(buf)
+}
+
+// MatchDTLS is a MatchFunc that accepts DTLS records
+func MatchDTLS(buf []byte) bool {
+	return MatchRange(20, 63)(buf)
+}
+
+// MatchTURN is a MatchFunc that accepts TURN ChannelData messages
+func MatchTURN(buf []byte) bool {
+	return MatchRange(64, 79)(buf)
+}
+
+// MatchSRTPOrSRTCP is a MatchFunc that accepts SRTP and SRTCP packets
+func MatchSRTPOrSRTCP(buf []byte) bool {
+	return MatchRange(128, 191)(buf)
+}
+
+// isRTCP tells RTCP from RTP by the payload type, RTCP packet types are
+// within [192, 223] which RTP payload types must not use
+// https://tools.ietf.org/html/rfc5761#section-4
+func isRTCP(buf []byte) bool {
+	if len(buf) < 4 {
+		return false
+	}
+	return buf[1] >= 192 && buf[1] <= 223
+}
+
+// MatchSRTP is a MatchFunc that only accepts SRTP packets
+func MatchSRTP(buf []byte) bool {
+	return MatchSRTPOrSRTCP(buf) && !isRTCP(buf)
+}
+
+// MatchSRTCP is a MatchFunc that only accepts SRTCP packets
+func MatchSRTCP(buf []byte) bool {
+	return MatchSRTPOrSRTCP(buf) && isRTCP(buf)
+}
@@ -0,0 +1,43 @@
+package mux
+
+import "testing"
+
+func TestMatchFuncs(t *testing.T) {
+	matchFuncs := []struct {
+		Name  string
+		Match MatchFunc
+	}{
+		{"MatchSTUN", MatchSTUN},
+		{"MatchZRTP", MatchZRTP},
+		{"MatchDTLS", MatchDTLS},
+		{"MatchTURN", MatchTURN},
+		{"MatchSRTPOrSRTCP", MatchSRTPOrSRTCP},
+		{"MatchSRTP", MatchSRTP},
+		{"MatchSRTCP", MatchSRTCP},
+	}
+
+	for _, test := range []struct {
+		Name    string
+		Packet  []byte
+		Matches map[string]bool
+	}{
+		{"STUN binding request", []byte{0x00, 0x01, 0x00, 0x00}, map[string]bool{"MatchSTUN": true}},
+		{"ZRTP", []byte{0x10, 0x00, 0x00, 0x00}, map[string]bool{"MatchZRTP": true}},
+		{"DTLS handshake", []byte{0x16, 0xfe, 0xfd, 0x00}, map[string]bool{"MatchDTLS": true}},
+		{"DTLS application data", []byte{0x17, 0xfe, 0xfd, 0x00}, map[string]bool{"MatchDTLS": true}},
+		{"TURN ChannelData", []byte{0x40, 0x00, 0x00, 0x04}, map[string]bool{"MatchTURN": true}},
+		{"SRTP", []byte{0x80, 0x60, 0x00, 0x01}, map[string]bool{"MatchSRTPOrSRTCP": true, "MatchSRTP": true}},
+		{"SRTCP sender report", []byte{0x80, 0xc8, 0x00, 0x06}, map[string]bool{"MatchSRTPOrSRTCP": true, "MatchSRTCP": true}},
+		{"Unknown", []byte{0xff, 0x00, 0x00, 0x00}, map[string]bool{}},
+		{"Empty", []byte{}, map[string]bool{}},
+	} {
+		for _, m := range matchFuncs {
+			if actual := m.Match(test.Packet); actual != test.Matches[m.Name] {
+				t.Errorf("%s(%s): expected(%v) actual(%v)", m.Name, test.Name, test.Matches[m.Name], actual)
+			}
+		}
+		if !MatchAll(test.Packet) {
+			t.Errorf("MatchAll(%s): expected(true) actual(false)", test.Name)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package mux routes the packets of one connection to multiple endpoints,
+// such as DTLS, STUN and SRTP sharing a UDP 5-tuple as described in RFC 7983
+package mux
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/pion/logging"
+)
+
+const (
+	receiveMTU            = 8192
+	defaultReadBufferSize = 64 // packets
+)
+
+var errMuxClosed = errors.New("mux: closed")
+
+// Config collects the arguments to mux.Mux construction into
+// a single structure
+type Config struct {
+	// Conn is read by the Mux, which takes ownership of it
+	Conn net.Conn
+
+	// ReadBufferSize is the maximum number of packets queued for each
+	// Endpoint, packets for an Endpoint that is not read fast enough are
+	// dropped. If zero, 64 is used.
+	ReadBufferSize int
+
+	LoggerFactory logging.LoggerFactory
+}
+
+// Mux allows multiplexing
+type Mux struct {
+	nextConn       net.Conn
+	readBufferSize int
+
+	lock      sync.RWMutex
+	endpoints []*Endpoint // in order of precedence
+	closed    bool
+
+	droppedPackets uint64 // guarded by lock, packets no Endpoint matched
+
+	readDone chan struct{}
+	log      logging.LeveledLogger
+}
+
+// NewMux creates a new Mux and starts reading from the Conn
+func NewMux(config Config) *Mux {
+	readBufferSize := config.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = defaultReadBufferSize
+	}
+	loggerFactory := config.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	m := &Mux{
+		nextConn:       config.Conn,
+		readBufferSize: readBufferSize,
+		readDone:       make(chan struct{}),
+		log:            loggerFactory.NewLogger("mux"),
+	}
+
+	go m.readLoop()
+
+	return m
+}
+
+// NewEndpoint creates a new Endpoint receiving the packets accepted by f.
+// Packets are routed to the first Endpoint, in order of creation, that
+// matches them.
+func (m *Mux) NewEndpoint(f MatchFunc) *Endpoint {
+	e := newEndpoint(m, f)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.closed {
+		e.close()
+		return e
+	}
+	m.endpoints = append(m.endpoints, e)
+	return e
+}
+
+// RemoveEndpoint removes an Endpoint from the Mux
+func (m *Mux) RemoveEndpoint(e *Endpoint) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, endpoint := range m.endpoints {
+		if endpoint == e {
+			m.endpoints = append(m.endpoints[:i], m.endpoints[i+1:]...)
+			return
+		}
+	}
+}
+
+// DroppedPackets returns the number of packets no Endpoint matched
+func (m *Mux) DroppedPackets() uint64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.droppedPackets
+}
+
+// Close closes the Mux, its Conn and all of its Endpoints
+func (m *Mux) Close() error {
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return errMuxClosed
+	}
+	m.closed = true
+	endpoints := m.endpoints
+	m.endpoints = nil
+	m.lock.Unlock()
+
+	for _, e := range endpoints {
+		e.close()
+	}
+
+	err := m.nextConn.Close()
+
+	// Wait for readLoop to end
+	<-m.readDone
+
+	return err
+}
+
+func (m *Mux) readLoop() {
+	defer func() {
+		close(m.readDone)
+	}()
+
+	buf := make([]byte, receiveMTU)
+	for {
+		n, err := m.nextConn.Read(buf)
+		if err != nil {
+			// The Endpoints are done once the Conn is
+			m.lock.Lock()
+			m.closed = true
+			endpoints := m.endpoints
+			m.endpoints = nil
+			m.lock.Unlock()
+
+			for _, e := range endpoints {
+				e.close()
+			}
+			return
+		}
+
+		m.dispatch(buf[:n])
+	}
+}
+
+func (m *Mux) dispatch(buf []byte) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, e := range m.endpoints {
+		if e.match(buf) {
+			e.deliver(buf)
+			return
+		}
+	}
+
+	m.droppedPackets++
+	if len(buf) > 0 {
+		m.log.Debugf("dropped packet, no endpoint for first byte %d", buf[0])
+	} else {
+		m.log.Debug("dropped empty packet")
+	}
+}
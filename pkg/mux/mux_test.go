@@ -0,0 +1,200 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/internal/net/dpipe"
+	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/transport/test"
+)
+
+func TestMuxRouting(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	// Check for leaking routines
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ca, cb := dpipe.Pipe()
+	m := NewMux(Config{Conn: cb})
+
+	stun := m.NewEndpoint(MatchSTUN)
+	dtlsEndpoint := m.NewEndpoint(MatchDTLS)
+	srtp := m.NewEndpoint(MatchSRTP)
+	srtcp := m.NewEndpoint(MatchSRTCP)
+
+	packets := []struct {
+		packet   []byte
+		endpoint *Endpoint
+	}{
+		{[]byte{0x80, 0xc8, 0x00, 0x06}, srtcp},
+		{[]byte{0x00, 0x01, 0x00, 0x00}, stun},
+		{[]byte{0xff, 0x00}, nil}, // no endpoint, dropped
+		{[]byte{0x16, 0xfe, 0xfd, 0x00}, dtlsEndpoint},
+		{[]byte{0x80, 0x60, 0x00, 0x01}, srtp},
+	}
+	for _, p := range packets {
+		if _, err := ca.Write(p.packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, receiveMTU)
+	for _, p := range packets {
+		if p.endpoint == nil {
+			continue
+		}
+		n, err := p.endpoint.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:n], p.packet) {
+			t.Errorf("Read: expected(%v) actual(%v)", p.packet, buf[:n])
+		}
+	}
+	if dropped := m.DroppedPackets(); dropped != 1 {
+		t.Errorf("DroppedPackets: expected(1) actual(%d)", dropped)
+	}
+
+	// Writes of all Endpoints go to the Conn
+	if _, err := srtp.Write([]byte{0x80}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ca.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf[:n], []byte{0x80}) {
+		t.Errorf("Write: expected(%v) actual(%v)", []byte{0x80}, buf[:n])
+	}
+
+	// A closed Endpoint no longer receives, its packets are dropped
+	if err = stun.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stun.Read(buf); err != io.EOF {
+		t.Errorf("Read after Close: expected(%v) actual(%v)", io.EOF, err)
+	}
+
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*Endpoint{dtlsEndpoint, srtp, srtcp} {
+		if _, err = e.Read(buf); err != io.EOF {
+			t.Errorf("Read after Mux.Close: expected(%v) actual(%v)", io.EOF, err)
+		}
+	}
+	_ = ca.Close()
+}
+
+func TestMuxReadBufferSize(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	ca, cb := dpipe.Pipe()
+	m := NewMux(Config{Conn: cb, ReadBufferSize: 2})
+	slow := m.NewEndpoint(MatchSRTP)
+	fast := m.NewEndpoint(MatchDTLS)
+
+	// The unread Endpoint does not block the other one
+	for i := 0; i < 5; i++ {
+		if _, err := ca.Write([]byte{0x80, 0x60}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ca.Write([]byte{0x16}); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, receiveMTU)
+	if _, err := fast.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	if dropped := slow.DroppedPackets(); dropped != 3 {
+		t.Errorf("DroppedPackets: expected(3) actual(%d)", dropped)
+	}
+
+	_ = m.Close()
+	_ = ca.Close()
+}
+
+func TestMuxDTLS(t *testing.T) {
+	// Limit runtime in case of deadlocks
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	ca, cb := dpipe.Pipe()
+	clientMux := NewMux(Config{Conn: ca})
+	serverMux := NewMux(Config{Conn: cb})
+	defer func() {
+		_ = clientMux.Close()
+		_ = serverMux.Close()
+	}()
+
+	// STUN sent before and during the handshake does not reach DTLS
+	clientSTUN := clientMux.NewEndpoint(MatchSTUN)
+	serverSTUN := serverMux.NewEndpoint(MatchSTUN)
+	stunPacket := []byte{0x00, 0x01, 0x00, 0x00}
+	if _, err := clientSTUN.Write(stunPacket); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := selfsign.GenerateSelfSigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type result struct {
+		c   *dtls.Conn
+		err error
+	}
+	serverRes := make(chan result)
+	go func() {
+		server, err := dtls.ServerWithContext(ctx, serverMux.NewEndpoint(MatchDTLS), &dtls.Config{
+			Certificates: []tls.Certificate{cert},
+		})
+		serverRes <- result{server, err}
+	}()
+	client, err := dtls.ClientWithContext(ctx, clientMux.NewEndpoint(MatchDTLS), &dtls.Config{
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := <-serverRes
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	server := res.c
+
+	buf := make([]byte, receiveMTU)
+	n, err := serverSTUN.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf[:n], stunPacket) {
+		t.Errorf("STUN: expected(%v) actual(%v)", stunPacket, buf[:n])
+	}
+
+	message := []byte("Hello")
+	if _, err = client.Write(message); err != nil {
+		t.Fatal(err)
+	}
+	n, err = server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(buf[:n], message) {
+		t.Errorf("DTLS: expected(%s) actual(%s)", message, buf[:n])
+	}
+
+	_ = client.Close()
+	_ = server.Close()
+}
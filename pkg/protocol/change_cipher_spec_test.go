@@ -1,4 +1,4 @@
-package dtls
+package protocol
 
 import (
 	"reflect"
@@ -6,13 +6,13 @@ import (
 )
 
 func TestChangeCipherSpecRoundTrip(t *testing.T) {
-	c := changeCipherSpec{}
+	c := ChangeCipherSpec{}
 	raw, err := c.Marshal()
 	if err != nil {
 		t.Error(err)
 	}
 
-	var cNew changeCipherSpec
+	var cNew ChangeCipherSpec
 	if err := cNew.Unmarshal(raw); err != nil {
 		t.Error(err)
 	}
@@ -23,7 +23,7 @@ func TestChangeCipherSpecRoundTrip(t *testing.T) {
 }
 
 func TestChangeCipherSpecInvalid(t *testing.T) {
-	c := changeCipherSpec{}
+	c := ChangeCipherSpec{}
 	if err := c.Unmarshal([]byte{0x00}); err != errInvalidCipherSpec {
 		t.Errorf("ChangeCipherSpec invalid assert: got %#v, want %#v", err, errInvalidCipherSpec)
 	}
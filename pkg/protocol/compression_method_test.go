@@ -0,0 +1,27 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCompressionMethods(t *testing.T) {
+	testCases := []struct {
+		buf    []byte
+		result []*CompressionMethod
+		err    error
+	}{
+		{[]byte{}, nil, errInvalidLength},
+		{[]byte{0x02, 0x01, 0x00}, []*CompressionMethod{{ID: CompressionMethodNull}}, nil},
+		{[]byte{0x02, 0x00}, nil, errBufferTooSmall},
+	}
+
+	for _, testCase := range testCases {
+		result, err := DecodeCompressionMethods(testCase.buf)
+		if err != testCase.err {
+			t.Fatal("Unexpected error", err)
+		} else if !reflect.DeepEqual(result, testCase.result) {
+			t.Errorf("DecodeCompressionMethods(% 02x): got %v, want %v", testCase.buf, result, testCase.result)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package protocol
+
+import "errors"
+
+var (
+	errBufferTooSmall    = errors.New("protocol: buffer is too small")
+	errInvalidCipherSpec = errors.New("protocol: cipher spec invalid")
+	errInvalidLength     = errors.New("protocol: packet is too short")
+)
@@ -0,0 +1,41 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionALPN(t *testing.T) {
+	rawALPN := []byte{
+		0x00, 0x10, 0x00, 0x12, 0x00, 0x10,
+		0x06, 0x77, 0x65, 0x62, 0x72, 0x74, 0x63, // webrtc
+		0x08, 0x63, 0x2d, 0x77, 0x65, 0x62, 0x72, 0x74, 0x63, // c-webrtc
+	}
+	parsedALPN := &ALPN{
+		ProtocolNameList: []string{"webrtc", "c-webrtc"},
+	}
+
+	raw, err := parsedALPN.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawALPN) {
+		t.Errorf("ALPN marshal: got %#v, want %#v", raw, rawALPN)
+	}
+
+	parsed := &ALPN{}
+	if err := parsed.Unmarshal(rawALPN); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(parsed, parsedALPN) {
+		t.Errorf("ALPN unmarshal: got %#v, want %#v", parsed, parsedALPN)
+	}
+
+	for _, invalid := range [][]byte{
+		{0x00, 0x10, 0x00, 0x02, 0x00, 0x00},       // Empty list
+		{0x00, 0x10, 0x00, 0x03, 0x00, 0x01, 0x00}, // Empty protocol name
+		{0x00, 0x10, 0x00, 0x04, 0x00, 0x02, 0x05, 0x61},
+	} {
+		if err := (&ALPN{}).Unmarshal(invalid); err != errALPNInvalidFormat {
+			t.Errorf("ALPN unmarshal %#v: expected %v, got %v", invalid, errALPNInvalidFormat, err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionEncryptThenMAC(t *testing.T) {
+	rawExtensionEncryptThenMAC := []byte{0x00, 0x16, 0x00, 0x00}
+	parsedExtensionEncryptThenMAC := &EncryptThenMAC{
+		Supported: true,
+	}
+
+	raw, err := parsedExtensionEncryptThenMAC.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawExtensionEncryptThenMAC) {
+		t.Errorf("EncryptThenMAC marshal: got %#v, want %#v", raw, rawExtensionEncryptThenMAC)
+	}
+
+	parsed := &EncryptThenMAC{}
+	if err := parsed.Unmarshal(rawExtensionEncryptThenMAC); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(parsed, parsedExtensionEncryptThenMAC) {
+		t.Errorf("EncryptThenMAC unmarshal: got %#v, want %#v", parsed, parsedExtensionEncryptThenMAC)
+	}
+}
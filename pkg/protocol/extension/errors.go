@@ -0,0 +1,21 @@
+package extension
+
+import "errors"
+
+var (
+	// ErrDuplicateExtension is returned when an extension type appears more
+	// than once in a hello message, which peers answer with a decode_error alert
+	ErrDuplicateExtension = errors.New("extension: duplicate extension")
+)
+
+var (
+	errALPNInvalidFormat              = errors.New("extension: invalid alpn format")
+	errBufferTooSmall                 = errors.New("extension: buffer is too small")
+	errInvalidExtensionType           = errors.New("extension: invalid extension type")
+	errInvalidSNIFormat               = errors.New("extension: invalid server name format")
+	errLengthMismatch                 = errors.New("extension: data length and declared length do not match")
+	errRawExtensionTooLong            = errors.New("extension: extension data must not be longer than 65535 bytes")
+	errRenegotiationInfoTooLong       = errors.New("extension: renegotiation_info must not be longer than 255 bytes")
+	errSRTPMasterKeyIdentifierTooLong = errors.New("extension: SRTP MKI must not be longer than 255 bytes")
+	errUnknownSRTPProtectionProfile   = errors.New("extension: unknown SRTPProtectionProfile")
+)
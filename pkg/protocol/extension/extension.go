@@ -0,0 +1,139 @@
+// Package extension implements the extensions of the DTLS hello messages
+package extension
+
+import (
+	"encoding/binary"
+)
+
+// TypeValue is the 2 byte value for a TLS Extension as registered in the IANA
+//
+// https://www.iana.org/assignments/tls-extensiontype-values/tls-extensiontype-values.xhtml
+type TypeValue uint16
+
+// TypeValue constants
+const (
+	ServerNameTypeValue                   TypeValue = 0
+	SupportedEllipticCurvesTypeValue      TypeValue = 10
+	SupportedPointFormatsTypeValue        TypeValue = 11
+	SupportedSignatureAlgorithmsTypeValue TypeValue = 13
+	UseSRTPTypeValue                      TypeValue = 14
+	ALPNTypeValue                         TypeValue = 16
+	EncryptThenMACTypeValue               TypeValue = 22
+	UseExtendedMasterSecretTypeValue      TypeValue = 23
+	RenegotiationInfoTypeValue            TypeValue = 65281
+)
+
+// Implemented returns true if extensions of type t are decoded by this
+// package instead of being returned as Raw
+func Implemented(t TypeValue) bool {
+	switch t {
+	case ServerNameTypeValue,
+		SupportedEllipticCurvesTypeValue,
+		SupportedPointFormatsTypeValue,
+		SupportedSignatureAlgorithmsTypeValue,
+		UseSRTPTypeValue,
+		ALPNTypeValue,
+		EncryptThenMACTypeValue,
+		UseExtendedMasterSecretTypeValue,
+		RenegotiationInfoTypeValue:
+		return true
+	}
+	return false
+}
+
+// Extension represents a single TLS extension
+type Extension interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+	TypeValue() TypeValue
+}
+
+// Unmarshal many extensions at once, extensions which are not implemented
+// are returned as Raw. An extension type appearing more than once is an
+// ErrDuplicateExtension
+func Unmarshal(buf []byte) ([]Extension, error) {
+	if len(buf) < 2 {
+		return nil, errBufferTooSmall
+	}
+	declaredLen := binary.BigEndian.Uint16(buf)
+	if len(buf)-2 != int(declaredLen) {
+		return nil, errLengthMismatch
+	}
+
+	extensions := []Extension{}
+	seen := map[TypeValue]bool{}
+	unmarshalAndAppend := func(data []byte, e Extension) error {
+		err := e.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+		extensions = append(extensions, e)
+		return nil
+	}
+
+	for offset := 2; offset < len(buf); {
+		if len(buf) < (offset + 2) {
+			return nil, errBufferTooSmall
+		}
+		typeValue := TypeValue(binary.BigEndian.Uint16(buf[offset:]))
+		if seen[typeValue] {
+			return nil, ErrDuplicateExtension
+		}
+		seen[typeValue] = true
+
+		var err error
+		switch typeValue {
+		case ServerNameTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ServerName{})
+		case SupportedEllipticCurvesTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &SupportedEllipticCurves{})
+		case SupportedPointFormatsTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &SupportedPointFormats{})
+		case SupportedSignatureAlgorithmsTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &SupportedSignatureAlgorithms{})
+		case UseSRTPTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &UseSRTP{})
+		case ALPNTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &ALPN{})
+		case EncryptThenMACTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &EncryptThenMAC{})
+		case UseExtendedMasterSecretTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &UseExtendedMasterSecret{})
+		case RenegotiationInfoTypeValue:
+			err = unmarshalAndAppend(buf[offset:], &RenegotiationInfo{})
+		default:
+			err = unmarshalAndAppend(buf[offset:], &Raw{})
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) < (offset + 4) {
+			return nil, errBufferTooSmall
+		}
+		extensionLength := binary.BigEndian.Uint16(buf[offset+2:])
+		offset += (4 + int(extensionLength))
+	}
+	return extensions, nil
+}
+
+// Marshal many extensions at once, an extension type appearing more than
+// once is an ErrDuplicateExtension
+func Marshal(e []Extension) ([]byte, error) {
+	extensions := []byte{}
+	seen := map[TypeValue]bool{}
+	for _, e := range e {
+		if seen[e.TypeValue()] {
+			return nil, ErrDuplicateExtension
+		}
+		seen[e.TypeValue()] = true
+
+		raw, err := e.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, raw...)
+	}
+	out := []byte{0x00, 0x00}
+	binary.BigEndian.PutUint16(out, uint16(len(extensions)))
+	return append(out, extensions...), nil
+}
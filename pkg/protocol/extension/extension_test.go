@@ -0,0 +1,51 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensions(t *testing.T) {
+	rawExtensions := []byte{
+		0x00, 0x0b,
+		0xff, 0x01, 0x00, 0x01, 0x00,
+		0xfe, 0x00, 0x00, 0x02, 0x01, 0x02,
+	}
+	parsedExtensions := []Extension{
+		&RenegotiationInfo{RenegotiatedConnection: []byte{}},
+		&Raw{Type: 0xfe00, Data: []byte{0x01, 0x02}},
+	}
+
+	raw, err := Marshal(parsedExtensions)
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawExtensions) {
+		t.Errorf("Extensions marshal: got %#v, want %#v", raw, rawExtensions)
+	}
+
+	extensions, err := Unmarshal(rawExtensions)
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(extensions, parsedExtensions) {
+		t.Errorf("Extensions unmarshal: got %#v, want %#v", extensions, parsedExtensions)
+	}
+}
+
+func TestExtensionsDuplicate(t *testing.T) {
+	rawExtensions := []byte{
+		0x00, 0x0c,
+		0xfe, 0x00, 0x00, 0x02, 0x01, 0x02,
+		0xfe, 0x00, 0x00, 0x02, 0x03, 0x04,
+	}
+	if _, err := Unmarshal(rawExtensions); err != ErrDuplicateExtension {
+		t.Errorf("Extensions unmarshal: expected(%v) actual(%v)", ErrDuplicateExtension, err)
+	}
+
+	parsedExtensions := []Extension{
+		&Raw{Type: ALPNTypeValue, Data: []byte{}},
+		&ALPN{ProtocolNameList: []string{"h2"}},
+	}
+	if _, err := Marshal(parsedExtensions); err != ErrDuplicateExtension {
+		t.Errorf("Extensions marshal: expected(%v) actual(%v)", ErrDuplicateExtension, err)
+	}
+}
@@ -0,0 +1,49 @@
+package extension
+
+import "encoding/binary"
+
+const (
+	rawHeaderSize = 4
+)
+
+// Raw is an extension this package doesn't implement. Its data is kept
+// undecoded, so applications can negotiate capabilities of their own
+type Raw struct {
+	Type TypeValue
+	Data []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (r Raw) TypeValue() TypeValue {
+	return r.Type
+}
+
+// Marshal encodes the extension
+func (r *Raw) Marshal() ([]byte, error) {
+	if len(r.Data) > 0xffff {
+		return nil, errRawExtensionTooLong
+	}
+
+	out := make([]byte, rawHeaderSize)
+
+	binary.BigEndian.PutUint16(out, uint16(r.Type))
+	binary.BigEndian.PutUint16(out[2:], uint16(len(r.Data)))
+
+	return append(out, r.Data...), nil
+}
+
+// Unmarshal populates the extension from encoded data
+func (r *Raw) Unmarshal(data []byte) error {
+	if len(data) < rawHeaderSize {
+		return errBufferTooSmall
+	}
+
+	dataLength := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < rawHeaderSize+dataLength {
+		return errLengthMismatch
+	}
+
+	r.Type = TypeValue(binary.BigEndian.Uint16(data))
+	r.Data = append([]byte{}, data[rawHeaderSize:rawHeaderSize+dataLength]...)
+	return nil
+}
@@ -0,0 +1,29 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionRaw(t *testing.T) {
+	rawExtension := []byte{0xfe, 0x00, 0x00, 0x02, 0x01, 0x02}
+	parsedExtension := &Raw{Type: 0xfe00, Data: []byte{0x01, 0x02}}
+
+	raw, err := parsedExtension.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawExtension) {
+		t.Errorf("Raw marshal: got %#v, want %#v", raw, rawExtension)
+	}
+
+	e := &Raw{}
+	if err := e.Unmarshal(rawExtension); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(e, parsedExtension) {
+		t.Errorf("Raw unmarshal: got %#v, want %#v", e, parsedExtension)
+	}
+
+	if err := (&Raw{}).Unmarshal(rawExtension[:5]); err != errLengthMismatch {
+		t.Errorf("Raw truncated: expected(%v) actual(%v)", errLengthMismatch, err)
+	}
+}
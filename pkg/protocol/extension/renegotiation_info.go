@@ -0,0 +1,53 @@
+package extension
+
+import "encoding/binary"
+
+const (
+	renegotiationInfoHeaderSize = 5
+)
+
+// RenegotiationInfo binds a renegotiation to the connection it is
+// performed on. It is empty on the initial handshake, and carries the
+// verify_data of the previous handshake's Finished messages when renegotiating
+//
+// https://tools.ietf.org/html/rfc5746#section-3.2
+type RenegotiationInfo struct {
+	RenegotiatedConnection []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (r RenegotiationInfo) TypeValue() TypeValue {
+	return RenegotiationInfoTypeValue
+}
+
+// Marshal encodes the extension
+func (r *RenegotiationInfo) Marshal() ([]byte, error) {
+	if len(r.RenegotiatedConnection) > 255 {
+		return nil, errRenegotiationInfoTooLong
+	}
+
+	out := make([]byte, renegotiationInfoHeaderSize)
+
+	binary.BigEndian.PutUint16(out, uint16(r.TypeValue()))
+	binary.BigEndian.PutUint16(out[2:], uint16(1+len(r.RenegotiatedConnection)))
+	out[4] = byte(len(r.RenegotiatedConnection))
+
+	return append(out, r.RenegotiatedConnection...), nil
+}
+
+// Unmarshal populates the extension from encoded data
+func (r *RenegotiationInfo) Unmarshal(data []byte) error {
+	if len(data) < renegotiationInfoHeaderSize {
+		return errBufferTooSmall
+	} else if TypeValue(binary.BigEndian.Uint16(data)) != r.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	infoLength := int(data[4])
+	if int(binary.BigEndian.Uint16(data[2:])) != infoLength+1 || len(data) < renegotiationInfoHeaderSize+infoLength {
+		return errLengthMismatch
+	}
+
+	r.RenegotiatedConnection = append([]byte{}, data[renegotiationInfoHeaderSize:renegotiationInfoHeaderSize+infoLength]...)
+	return nil
+}
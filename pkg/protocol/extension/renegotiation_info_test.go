@@ -0,0 +1,43 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionRenegotiationInfo(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Raw    []byte
+		Parsed *RenegotiationInfo
+	}{
+		{
+			Name:   "Initial handshake",
+			Raw:    []byte{0xff, 0x01, 0x00, 0x01, 0x00},
+			Parsed: &RenegotiationInfo{RenegotiatedConnection: []byte{}},
+		},
+		{
+			Name:   "Renegotiation",
+			Raw:    []byte{0xff, 0x01, 0x00, 0x04, 0x03, 0x01, 0x02, 0x03},
+			Parsed: &RenegotiationInfo{RenegotiatedConnection: []byte{0x01, 0x02, 0x03}},
+		},
+	} {
+		raw, err := test.Parsed.Marshal()
+		if err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(raw, test.Raw) {
+			t.Errorf("RenegotiationInfo marshal '%s': got %#v, want %#v", test.Name, raw, test.Raw)
+		}
+
+		e := &RenegotiationInfo{}
+		if err := e.Unmarshal(test.Raw); err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(e, test.Parsed) {
+			t.Errorf("RenegotiationInfo unmarshal '%s': got %#v, want %#v", test.Name, e, test.Parsed)
+		}
+	}
+
+	if err := (&RenegotiationInfo{}).Unmarshal([]byte{0xff, 0x01, 0x00, 0x04, 0x03, 0x01}); err != errLengthMismatch {
+		t.Errorf("RenegotiationInfo truncated: expected(%v) actual(%v)", errLengthMismatch, err)
+	}
+}
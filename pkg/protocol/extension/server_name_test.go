@@ -0,0 +1,22 @@
+package extension
+
+import "testing"
+
+func TestServerName(t *testing.T) {
+	ext := ServerName{ServerName: "test.domain"}
+
+	raw, err := ext.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newExtension := ServerName{}
+	err = newExtension.Unmarshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if newExtension.ServerName != ext.ServerName {
+		t.Errorf("ServerName marshal: got %s expected %s", newExtension.ServerName, ext.ServerName)
+	}
+}
@@ -0,0 +1,60 @@
+package extension
+
+// SRTPProtectionProfile defines the parameters and options that are in effect for the SRTP processing
+// https://tools.ietf.org/html/rfc5764#section-4.1.2
+type SRTPProtectionProfile uint16
+
+const (
+	SRTP_AES128_CM_HMAC_SHA1_80 SRTPProtectionProfile = 0x0001 // nolint
+	SRTP_AES128_CM_HMAC_SHA1_32 SRTPProtectionProfile = 0x0002 // nolint
+	SRTP_AEAD_AES_128_GCM       SRTPProtectionProfile = 0x0007 // nolint
+	SRTP_AEAD_AES_256_GCM       SRTPProtectionProfile = 0x0008 // nolint
+)
+
+// srtpProtectionProfileParams are the master key and master salt lengths in bytes
+// https://tools.ietf.org/html/rfc5764#section-4.1.2
+// https://tools.ietf.org/html/rfc7714#section-14.2
+type srtpProtectionProfileParams struct {
+	keyLen, saltLen int
+}
+
+var srtpProtectionProfiles = map[SRTPProtectionProfile]srtpProtectionProfileParams{
+	SRTP_AES128_CM_HMAC_SHA1_80: {keyLen: 16, saltLen: 14},
+	SRTP_AES128_CM_HMAC_SHA1_32: {keyLen: 16, saltLen: 14},
+	SRTP_AEAD_AES_128_GCM:       {keyLen: 16, saltLen: 12},
+	SRTP_AEAD_AES_256_GCM:       {keyLen: 32, saltLen: 12},
+}
+
+// String returns the name of the profile as registered with IANA
+func (p SRTPProtectionProfile) String() string {
+	switch p {
+	case SRTP_AES128_CM_HMAC_SHA1_80:
+		return "SRTP_AES128_CM_HMAC_SHA1_80"
+	case SRTP_AES128_CM_HMAC_SHA1_32:
+		return "SRTP_AES128_CM_HMAC_SHA1_32"
+	case SRTP_AEAD_AES_128_GCM:
+		return "SRTP_AEAD_AES_128_GCM"
+	case SRTP_AEAD_AES_256_GCM:
+		return "SRTP_AEAD_AES_256_GCM"
+	default:
+		return "Unknown SRTPProtectionProfile"
+	}
+}
+
+// KeyLen returns the length of the SRTP master key used by the profile
+func (p SRTPProtectionProfile) KeyLen() (int, error) {
+	params, ok := srtpProtectionProfiles[p]
+	if !ok {
+		return 0, errUnknownSRTPProtectionProfile
+	}
+	return params.keyLen, nil
+}
+
+// SaltLen returns the length of the SRTP master salt used by the profile
+func (p SRTPProtectionProfile) SaltLen() (int, error) {
+	params, ok := srtpProtectionProfiles[p]
+	if !ok {
+		return 0, errUnknownSRTPProtectionProfile
+	}
+	return params.saltLen, nil
+}
@@ -0,0 +1,31 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionSupportedPointFormats(t *testing.T) {
+	rawExtensionSupportedPointFormats := []byte{0x00, 0x0b, 0x00, 0x02, 0x01, 0x00}
+	parsedExtensionSupportedPointFormats := &SupportedPointFormats{
+		PointFormats: []EllipticCurvePointFormat{EllipticCurvePointFormatUncompressed},
+	}
+
+	raw, err := parsedExtensionSupportedPointFormats.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawExtensionSupportedPointFormats) {
+		t.Errorf("SupportedPointFormats marshal: got %#v, want %#v", raw, rawExtensionSupportedPointFormats)
+	}
+
+	e := &SupportedPointFormats{}
+	if err := e.Unmarshal(rawExtensionSupportedPointFormats); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(e, parsedExtensionSupportedPointFormats) {
+		t.Errorf("SupportedPointFormats unmarshal: got %#v, want %#v", e, parsedExtensionSupportedPointFormats)
+	}
+
+	if err := (&SupportedPointFormats{}).Unmarshal([]byte{0x00, 0x0b, 0x00, 0x03, 0x02, 0x00}); err != errLengthMismatch {
+		t.Errorf("SupportedPointFormats truncated: expected(%v) actual(%v)", errLengthMismatch, err)
+	}
+}
@@ -0,0 +1,75 @@
+package extension
+
+import "encoding/binary"
+
+const (
+	useSRTPHeaderSize = 6
+)
+
+// UseSRTP allows a Client/Server to negotiate what SRTPProtectionProfiles
+// they both support
+//
+// https://tools.ietf.org/html/rfc5764#section-4.1.1
+type UseSRTP struct {
+	ProtectionProfiles  []SRTPProtectionProfile
+	MasterKeyIdentifier []byte
+}
+
+// TypeValue returns the extension TypeValue
+func (u UseSRTP) TypeValue() TypeValue {
+	return UseSRTPTypeValue
+}
+
+// Marshal encodes the extension
+func (u *UseSRTP) Marshal() ([]byte, error) {
+	if len(u.MasterKeyIdentifier) > 255 {
+		return nil, errSRTPMasterKeyIdentifierTooLong
+	}
+
+	out := make([]byte, useSRTPHeaderSize)
+
+	binary.BigEndian.PutUint16(out, uint16(u.TypeValue()))
+	binary.BigEndian.PutUint16(out[2:], uint16(2+(len(u.ProtectionProfiles)*2)+ /* MKI Length */ 1+len(u.MasterKeyIdentifier)))
+	binary.BigEndian.PutUint16(out[4:], uint16(len(u.ProtectionProfiles)*2))
+
+	for _, v := range u.ProtectionProfiles {
+		out = append(out, []byte{0x00, 0x00}...)
+		binary.BigEndian.PutUint16(out[len(out)-2:], uint16(v))
+	}
+
+	out = append(out, byte(len(u.MasterKeyIdentifier)))
+	out = append(out, u.MasterKeyIdentifier...)
+	return out, nil
+}
+
+// Unmarshal populates the extension from encoded data, unknown profiles
+// are skipped
+func (u *UseSRTP) Unmarshal(data []byte) error {
+	if len(data) <= useSRTPHeaderSize {
+		return errBufferTooSmall
+	} else if TypeValue(binary.BigEndian.Uint16(data)) != u.TypeValue() {
+		return errInvalidExtensionType
+	}
+
+	profileCount := int(binary.BigEndian.Uint16(data[4:]) / 2)
+	mkiOffset := useSRTPHeaderSize + (profileCount * 2)
+	if mkiOffset+1 > len(data) {
+		return errLengthMismatch
+	}
+
+	for i := 0; i < profileCount; i++ {
+		supportedProfile := SRTPProtectionProfile(binary.BigEndian.Uint16(data[(useSRTPHeaderSize + (i * 2)):]))
+		if _, ok := srtpProtectionProfiles[supportedProfile]; ok {
+			u.ProtectionProfiles = append(u.ProtectionProfiles, supportedProfile)
+		}
+	}
+
+	mkiLen := int(data[mkiOffset])
+	if mkiOffset+1+mkiLen > len(data) {
+		return errLengthMismatch
+	}
+	if mkiLen > 0 {
+		u.MasterKeyIdentifier = append([]byte{}, data[mkiOffset+1:mkiOffset+1+mkiLen]...)
+	}
+	return nil
+}
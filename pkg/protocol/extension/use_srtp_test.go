@@ -0,0 +1,55 @@
+package extension
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtensionUseSRTP(t *testing.T) {
+	for _, test := range []struct {
+		Name   string
+		Raw    []byte
+		Parsed *UseSRTP
+	}{
+		{
+			Name: "No MKI",
+			Raw:  []byte{0x00, 0x0e, 0x00, 0x05, 0x00, 0x02, 0x00, 0x01, 0x00},
+			Parsed: &UseSRTP{
+				ProtectionProfiles: []SRTPProtectionProfile{SRTP_AES128_CM_HMAC_SHA1_80},
+			},
+		},
+		{
+			Name: "AEAD profiles with MKI",
+			Raw:  []byte{0x00, 0x0e, 0x00, 0x0b, 0x00, 0x06, 0x00, 0x08, 0x00, 0x07, 0x00, 0x02, 0x02, 0xca, 0xfe},
+			Parsed: &UseSRTP{
+				ProtectionProfiles:  []SRTPProtectionProfile{SRTP_AEAD_AES_256_GCM, SRTP_AEAD_AES_128_GCM, SRTP_AES128_CM_HMAC_SHA1_32},
+				MasterKeyIdentifier: []byte{0xca, 0xfe},
+			},
+		},
+	} {
+		raw, err := test.Parsed.Marshal()
+		if err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(raw, test.Raw) {
+			t.Errorf("UseSRTP marshal %s: got %#v, want %#v", test.Name, raw, test.Raw)
+		}
+
+		parsed := &UseSRTP{}
+		if err := parsed.Unmarshal(test.Raw); err != nil {
+			t.Error(err)
+		} else if !reflect.DeepEqual(parsed, test.Parsed) {
+			t.Errorf("UseSRTP unmarshal %s: got %#v, want %#v", test.Name, parsed, test.Parsed)
+		}
+	}
+}
+
+func TestExtensionUseSRTPInvalid(t *testing.T) {
+	for name, raw := range map[string][]byte{
+		"Truncated profiles": {0x00, 0x0e, 0x00, 0x05, 0x00, 0x04, 0x00, 0x01},
+		"Truncated MKI":      {0x00, 0x0e, 0x00, 0x05, 0x00, 0x02, 0x00, 0x01, 0x02, 0xca},
+	} {
+		if err := (&UseSRTP{}).Unmarshal(raw); err != errLengthMismatch {
+			t.Errorf("UseSRTP unmarshal %s: got %v, want %v", name, err, errLengthMismatch)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package handshake
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCipherSuiteIDs(t *testing.T) {
+	testCases := []struct {
+		buf    []byte
+		result []uint16
+		err    error
+	}{
+		{[]byte{}, nil, errInvalidLength},
+		{[]byte{0x00, 0x04, 0xc0, 0x2b, 0x00, 0xff}, []uint16{0xc02b, 0x00ff}, nil},
+		{[]byte{0x00, 0x04, 0xc0, 0x2b}, nil, errBufferTooSmall},
+	}
+
+	for _, testCase := range testCases {
+		result, err := decodeCipherSuiteIDs(testCase.buf)
+		if err != testCase.err {
+			t.Fatal("Unexpected error", err)
+		} else if !reflect.DeepEqual(result, testCase.result) {
+			t.Errorf("decodeCipherSuiteIDs(% 02x): got %v, want %v", testCase.buf, result, testCase.result)
+		}
+	}
+}
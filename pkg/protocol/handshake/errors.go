@@ -0,0 +1,21 @@
+package handshake
+
+import "errors"
+
+var (
+	errBufferTooSmall            = errors.New("handshake: buffer is too small")
+	errCipherSuiteUnset          = errors.New("handshake: server hello can not be created without a cipher suite")
+	errCompressionMethodUnset    = errors.New("handshake: server hello can not be created without a compression method")
+	errCookieTooLong             = errors.New("handshake: cookie must not be longer than 255 bytes")
+	errHandshakeMessageUnset     = errors.New("handshake: handshake message unset, unable to marshal")
+	errInvalidClientKeyExchange  = errors.New("handshake: unable to determine if ClientKeyExchange is a public key or PSK Identity")
+	errInvalidCompressionMethod  = errors.New("handshake: invalid or unknown compression method")
+	errInvalidEllipticCurveType  = errors.New("handshake: invalid or unknown elliptic curve type")
+	errInvalidHashAlgorithm      = errors.New("handshake: invalid hash algorithm")
+	errInvalidLength             = errors.New("handshake: packet is too short")
+	errInvalidNamedCurve         = errors.New("handshake: invalid named curve")
+	errInvalidSignatureAlgorithm = errors.New("handshake: invalid signature algorithm")
+	errLengthMismatch            = errors.New("handshake: data length and declared length do not match")
+	errNotImplemented            = errors.New("handshake: feature has not been implemented yet")
+	errUnableToMarshalFragmented = errors.New("handshake: unable to marshal fragmented handshakes")
+)
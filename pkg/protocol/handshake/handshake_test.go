@@ -1,9 +1,12 @@
-package dtls
+package handshake
 
 import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 )
 
 func TestHandshakeMessage(t *testing.T) {
@@ -13,36 +16,36 @@ func TestHandshakeMessage(t *testing.T) {
 		0x16, 0xc9, 0x15, 0x8d, 0x95, 0x71, 0x8a, 0xbb, 0x22, 0xd7, 0x47, 0xec, 0xd8, 0x3d, 0xdc,
 		0x4b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	}
-	parsedHandshake := &handshake{
-		handshakeHeader: handshakeHeader{
-			length:         0x29,
-			fragmentLength: 0x29,
-			handshakeType:  handshakeTypeClientHello,
+	parsedHandshake := &Handshake{
+		Header: Header{
+			Length:         0x29,
+			FragmentLength: 0x29,
+			Type:           TypeClientHello,
 		},
-		handshakeMessage: &handshakeMessageClientHello{
-			version: protocolVersion{0xFE, 0xFD},
-			random: handshakeRandom{
+		Message: &MessageClientHello{
+			Version: protocol.Version{Major: 0xFE, Minor: 0xFD},
+			Random: Random{
 				time.Unix(3056586332, 0),
 				[28]byte{0x42, 0x54, 0xff, 0x86, 0xe1, 0x24, 0x41, 0x91, 0x42, 0x62, 0x15, 0xad, 0x16, 0xc9, 0x15, 0x8d, 0x95, 0x71, 0x8a, 0xbb, 0x22, 0xd7, 0x47, 0xec, 0xd8, 0x3d, 0xdc, 0x4b},
 			},
-			cookie:             []byte{},
-			cipherSuites:       []cipherSuite{},
-			compressionMethods: []*compressionMethod{},
-			extensions:         []extension{},
+			Cookie:             []byte{},
+			CipherSuiteIDs:     []uint16{},
+			CompressionMethods: []*protocol.CompressionMethod{},
+			Extensions:         []extension.Extension{},
 		},
 	}
 
-	h := &handshake{}
+	h := &Handshake{}
 	if err := h.Unmarshal(rawHandshakeMessage); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(h, parsedHandshake) {
-		t.Errorf("handshakeMessageClientHello unmarshal: got %#v, want %#v", h, parsedHandshake)
+		t.Errorf("MessageClientHello unmarshal: got %#v, want %#v", h, parsedHandshake)
 	}
 
 	raw, err := h.Marshal()
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawHandshakeMessage) {
-		t.Errorf("handshakeMessageClientHello marshal: got %#v, want %#v", raw, rawHandshakeMessage)
+		t.Errorf("MessageClientHello marshal: got %#v, want %#v", raw, rawHandshakeMessage)
 	}
 }
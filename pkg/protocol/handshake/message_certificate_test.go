@@ -1,4 +1,4 @@
-package dtls
+package handshake
 
 import (
 	"crypto/x509"
@@ -56,17 +56,17 @@ func TestHandshakeMessageCertificate(t *testing.T) {
 		Version:                 1,
 	}
 
-	c := &handshakeMessageCertificate{}
+	c := &MessageCertificate{}
 	if err := c.Unmarshal(rawCertificate); err != nil {
 		t.Error(err)
 	} else {
-		certificate, err := x509.ParseCertificate(c.certificate[0])
+		certificate, err := x509.ParseCertificate(c.Certificate[0])
 		if err != nil {
 			t.Error(err)
 		}
 		copyCertificatePrivateMembers(certificate, parsedCertificate)
 		if !reflect.DeepEqual(certificate, parsedCertificate) {
-			t.Errorf("handshakeMessageCertificate unmarshal: got %#v, want %#v", c, parsedCertificate)
+			t.Errorf("MessageCertificate unmarshal: got %#v, want %#v", c, parsedCertificate)
 		}
 	}
 
@@ -74,7 +74,7 @@ func TestHandshakeMessageCertificate(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawCertificate) {
-		t.Errorf("handshakeMessageCertificate marshal: got %#v, want %#v", raw, rawCertificate)
+		t.Errorf("MessageCertificate marshal: got %#v, want %#v", raw, rawCertificate)
 	}
 }
 
@@ -83,16 +83,16 @@ func TestEmptyHandshakeMessageCertificate(t *testing.T) {
 		0x00, 0x00, 0x00,
 	}
 
-	expectedCertificate := &handshakeMessageCertificate{
-		certificate: nil,
+	expectedCertificate := &MessageCertificate{
+		Certificate: nil,
 	}
 
-	c := &handshakeMessageCertificate{}
+	c := &MessageCertificate{}
 	if err := c.Unmarshal(rawCertificate); err != nil {
 		t.Error(err)
 	}
 
 	if !reflect.DeepEqual(c, expectedCertificate) {
-		t.Errorf("handshakeMessageCertificate unmarshal: got %#v, want %#v", c, expectedCertificate)
+		t.Errorf("MessageCertificate unmarshal: got %#v, want %#v", c, expectedCertificate)
 	}
 }
@@ -1,8 +1,11 @@
-package dtls
+package handshake
 
 import (
 	"reflect"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
 )
 
 func TestHandshakeMessageCertificateVerify(t *testing.T) {
@@ -13,23 +16,23 @@ func TestHandshakeMessageCertificateVerify(t *testing.T) {
 		0x6c, 0x04, 0xa1, 0xdc, 0x28, 0xaa, 0x5f, 0xf7, 0x1e, 0x9c, 0xc0, 0x1e, 0x00, 0x2c, 0xe5, 0x94,
 		0xbb, 0x03, 0x0e, 0xf1, 0xcb, 0x28, 0x22, 0x33, 0x23, 0x88, 0xad,
 	}
-	parsedCertificateVerify := &handshakeMessageCertificateVerify{
-		hashAlgorithm:      hashAlgorithm(rawCertificateVerify[0]),
-		signatureAlgorithm: signatureAlgorithm(rawCertificateVerify[1]),
-		signature:          rawCertificateVerify[4:],
+	parsedCertificateVerify := &MessageCertificateVerify{
+		HashAlgorithm:      hash.Algorithm(rawCertificateVerify[0]),
+		SignatureAlgorithm: signature.Algorithm(rawCertificateVerify[1]),
+		Signature:          rawCertificateVerify[4:],
 	}
 
-	c := &handshakeMessageCertificateVerify{}
+	c := &MessageCertificateVerify{}
 	if err := c.Unmarshal(rawCertificateVerify); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(c, parsedCertificateVerify) {
-		t.Errorf("handshakeMessageCertificate unmarshal: got %#v, want %#v", c, parsedCertificateVerify)
+		t.Errorf("MessageCertificate unmarshal: got %#v, want %#v", c, parsedCertificateVerify)
 	}
 
 	raw, err := c.Marshal()
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawCertificateVerify) {
-		t.Errorf("handshakeMessageCertificateVerify marshal: got %#v, want %#v", raw, rawCertificateVerify)
+		t.Errorf("MessageCertificateVerify marshal: got %#v, want %#v", raw, rawCertificateVerify)
 	}
 }
@@ -1,9 +1,13 @@
-package dtls
+package handshake
 
 import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
 )
 
 func TestHandshakeMessageClientHello(t *testing.T) {
@@ -14,36 +18,33 @@ func TestHandshakeMessageClientHello(t *testing.T) {
 		0xd6, 0x6c, 0x57, 0xd0, 0x0e, 0x32, 0x85, 0x76, 0x18, 0xde, 0xd8, 0x00, 0x04, 0xc0, 0x2b,
 		0xc0, 0x0a, 0x01, 0x00, 0x00, 0x08, 0x00, 0x0a, 0x00, 0x04, 0x00, 0x02, 0x00, 0x1d,
 	}
-	parsedClientHello := &handshakeMessageClientHello{
-		version: protocolVersion{0xFE, 0xFD},
-		random: handshakeRandom{
+	parsedClientHello := &MessageClientHello{
+		Version: protocol.Version{Major: 0xFE, Minor: 0xFD},
+		Random: Random{
 			time.Unix(3056586332, 0),
 			[28]byte{0x42, 0x54, 0xff, 0x86, 0xe1, 0x24, 0x41, 0x91, 0x42, 0x62, 0x15, 0xad, 0x16, 0xc9, 0x15, 0x8d, 0x95, 0x71, 0x8a, 0xbb, 0x22, 0xd7, 0x47, 0xec, 0xd8, 0x3d, 0xdc, 0x4b},
 		},
-		cookie: []byte{0xe6, 0x14, 0x3a, 0x1b, 0x04, 0xea, 0x9e, 0x7a, 0x14, 0xd6, 0x6c, 0x57, 0xd0, 0x0e, 0x32, 0x85, 0x76, 0x18, 0xde, 0xd8},
-		cipherSuites: []cipherSuite{
-			&cipherSuiteTLSEcdheEcdsaWithAes128GcmSha256{},
-			&cipherSuiteTLSEcdheEcdsaWithAes256CbcSha{},
-		},
-		compressionMethods: []*compressionMethod{
-			compressionMethods[compressionMethodNull],
+		Cookie:         []byte{0xe6, 0x14, 0x3a, 0x1b, 0x04, 0xea, 0x9e, 0x7a, 0x14, 0xd6, 0x6c, 0x57, 0xd0, 0x0e, 0x32, 0x85, 0x76, 0x18, 0xde, 0xd8},
+		CipherSuiteIDs: []uint16{0xc02b, 0xc00a},
+		CompressionMethods: []*protocol.CompressionMethod{
+			{ID: protocol.CompressionMethodNull},
 		},
-		extensions: []extension{
-			&extensionSupportedEllipticCurves{ellipticCurves: []namedCurve{namedCurveX25519}},
+		Extensions: []extension.Extension{
+			&extension.SupportedEllipticCurves{EllipticCurves: []elliptic.Curve{elliptic.X25519}},
 		},
 	}
 
-	c := &handshakeMessageClientHello{}
+	c := &MessageClientHello{}
 	if err := c.Unmarshal(rawClientHello); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(c, parsedClientHello) {
-		t.Errorf("handshakeMessageClientHello unmarshal: got %#v, want %#v", c, parsedClientHello)
+		t.Errorf("MessageClientHello unmarshal: got %#v, want %#v", c, parsedClientHello)
 	}
 
 	raw, err := c.Marshal()
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawClientHello) {
-		t.Errorf("handshakeMessageClientHello marshal: got %#v, want %#v", raw, rawClientHello)
+		t.Errorf("MessageClientHello marshal: got %#v, want %#v", raw, rawClientHello)
 	}
 }
@@ -0,0 +1,25 @@
+package handshake
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHandshakeMessageHelloRequest(t *testing.T) {
+	rawHelloRequest := []byte{}
+	parsedHelloRequest := &MessageHelloRequest{}
+
+	c := &MessageHelloRequest{}
+	if err := c.Unmarshal(rawHelloRequest); err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(c, parsedHelloRequest) {
+		t.Errorf("MessageHelloRequest unmarshal: got %#v, want %#v", c, parsedHelloRequest)
+	}
+
+	raw, err := c.Marshal()
+	if err != nil {
+		t.Error(err)
+	} else if !reflect.DeepEqual(raw, rawHelloRequest) {
+		t.Errorf("MessageHelloRequest marshal: got %#v, want %#v", raw, rawHelloRequest)
+	}
+}
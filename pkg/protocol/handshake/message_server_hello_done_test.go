@@ -1,4 +1,4 @@
-package dtls
+package handshake
 
 import (
 	"reflect"
@@ -7,19 +7,19 @@ import (
 
 func TestHandshakeMessageServerHelloDone(t *testing.T) {
 	rawServerHelloDone := []byte{}
-	parsedServerHelloDone := &handshakeMessageServerHelloDone{}
+	parsedServerHelloDone := &MessageServerHelloDone{}
 
-	c := &handshakeMessageServerHelloDone{}
+	c := &MessageServerHelloDone{}
 	if err := c.Unmarshal(rawServerHelloDone); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(c, parsedServerHelloDone) {
-		t.Errorf("handshakeMessageServerHelloDone unmarshal: got %#v, want %#v", c, parsedServerHelloDone)
+		t.Errorf("MessageServerHelloDone unmarshal: got %#v, want %#v", c, parsedServerHelloDone)
 	}
 
 	raw, err := c.Marshal()
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawServerHelloDone) {
-		t.Errorf("handshakeMessageServerHelloDone marshal: got %#v, want %#v", raw, rawServerHelloDone)
+		t.Errorf("MessageServerHelloDone marshal: got %#v, want %#v", raw, rawServerHelloDone)
 	}
 }
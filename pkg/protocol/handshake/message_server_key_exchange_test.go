@@ -1,8 +1,12 @@
-package dtls
+package handshake
 
 import (
 	"reflect"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
 )
 
 func TestHandshakeMessageServerKeyExchange(t *testing.T) {
@@ -17,26 +21,26 @@ func TestHandshakeMessageServerKeyExchange(t *testing.T) {
 		0x49, 0x9c, 0x9d, 0xdf, 0x84, 0x60, 0x33, 0x27, 0x96, 0x9e, 0x58, 0x6d, 0x72, 0x13, 0xe7, 0x3a,
 		0xe8, 0xdf, 0x43, 0x75, 0xc7, 0xb9, 0x37, 0x6e, 0x90, 0xe5, 0x3b, 0x81, 0xd4, 0xda, 0x68, 0xcd,
 	}
-	parsedServerKeyExchange := &handshakeMessageServerKeyExchange{
-		ellipticCurveType:  ellipticCurveTypeNamedCurve,
-		namedCurve:         namedCurveX25519,
-		publicKey:          rawServerKeyExchange[4:69],
-		hashAlgorithm:      hashAlgorithmSHA1,
-		signatureAlgorithm: signatureAlgorithmECDSA,
-		signature:          rawServerKeyExchange[73:144],
+	parsedServerKeyExchange := &MessageServerKeyExchange{
+		EllipticCurveType:  elliptic.CurveTypeNamedCurve,
+		NamedCurve:         elliptic.X25519,
+		PublicKey:          rawServerKeyExchange[4:69],
+		HashAlgorithm:      hash.SHA1,
+		SignatureAlgorithm: signature.ECDSA,
+		Signature:          rawServerKeyExchange[73:144],
 	}
 
-	c := &handshakeMessageServerKeyExchange{}
+	c := &MessageServerKeyExchange{}
 	if err := c.Unmarshal(rawServerKeyExchange); err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(c, parsedServerKeyExchange) {
-		t.Errorf("handshakeMessageServerKeyExchange unmarshal: got %#v, want %#v", c, parsedServerKeyExchange)
+		t.Errorf("MessageServerKeyExchange unmarshal: got %#v, want %#v", c, parsedServerKeyExchange)
 	}
 
 	raw, err := c.Marshal()
 	if err != nil {
 		t.Error(err)
 	} else if !reflect.DeepEqual(raw, rawServerKeyExchange) {
-		t.Errorf("handshakeMessageServerKeyExchange marshal: got %#v, want %#v", raw, rawServerKeyExchange)
+		t.Errorf("MessageServerKeyExchange marshal: got %#v, want %#v", raw, rawServerKeyExchange)
 	}
 }
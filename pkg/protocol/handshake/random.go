@@ -0,0 +1,54 @@
+package handshake
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Consts for Random in Handshake
+const (
+	RandomBytesLength = 28
+	RandomLength      = RandomBytesLength + 4
+)
+
+// Random value that is used in ClientHello and ServerHello
+//
+// https://tools.ietf.org/html/rfc4346#section-7.4.1.2
+type Random struct {
+	GMTUnixTime time.Time
+	RandomBytes [RandomBytesLength]byte
+}
+
+// Marshal encodes the Random
+func (r *Random) Marshal() ([]byte, error) {
+	out := make([]byte, RandomLength)
+
+	binary.BigEndian.PutUint32(out[0:], uint32(r.GMTUnixTime.Unix()))
+	copy(out[4:], r.RandomBytes[:])
+
+	return out, nil
+}
+
+// Unmarshal populates the Random from encoded data
+func (r *Random) Unmarshal(data []byte) error {
+	if len(data) != RandomLength {
+		return errBufferTooSmall
+	}
+	r.GMTUnixTime = time.Unix(int64(binary.BigEndian.Uint32(data[0:])), 0)
+	copy(r.RandomBytes[:], data[4:])
+
+	return nil
+}
+
+// Populate fills the Random with the given time and random bytes, it may
+// be called multiple times
+func (r *Random) Populate(now time.Time, rand io.Reader) error {
+	r.GMTUnixTime = now
+
+	tmp := make([]byte, RandomBytesLength)
+	_, err := io.ReadFull(rand, tmp)
+	copy(r.RandomBytes[:], tmp)
+
+	return err
+}
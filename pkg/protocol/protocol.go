@@ -0,0 +1,13 @@
+// Package protocol provides the DTLS wire format shared by all record
+// content types. The subpackages alert, extension, handshake and
+// recordlayer implement the individual protocols on top of it.
+package protocol
+
+// Version is the DTLS protocol version of a record or hello message
+// https://tools.ietf.org/html/rfc4346#section-6.2.1
+type Version struct {
+	Major, Minor uint8
+}
+
+// Version1_2 is DTLS 1.2
+var Version1_2 = Version{Major: 0xfe, Minor: 0xfd} // nolint
@@ -0,0 +1,11 @@
+package recordlayer
+
+import "errors"
+
+var (
+	errBufferTooSmall         = errors.New("recordlayer: buffer is too small")
+	errInvalidContentType     = errors.New("recordlayer: invalid content type")
+	errInvalidPacketLength    = errors.New("recordlayer: packet is too short")
+	errLengthMismatch         = errors.New("recordlayer: data length and declared length do not match")
+	errSequenceNumberOverflow = errors.New("recordlayer: sequence number overflow")
+)
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"reflect"
 	"testing"
+
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
 )
 
 func TestPreMasterSecret(t *testing.T) {
@@ -12,7 +14,7 @@ func TestPreMasterSecret(t *testing.T) {
 	publicKey := []byte{0x9f, 0xd7, 0xad, 0x6d, 0xcf, 0xf4, 0x29, 0x8d, 0xd3, 0xf9, 0x6d, 0x5b, 0x1b, 0x2a, 0xf9, 0x10, 0xa0, 0x53, 0x5b, 0x14, 0x88, 0xd7, 0xf8, 0xfa, 0xbb, 0x34, 0x9a, 0x98, 0x28, 0x80, 0xb6, 0x15}
 	expectedPreMasterSecret := []byte{0xdf, 0x4a, 0x29, 0x1b, 0xaa, 0x1e, 0xb7, 0xcf, 0xa6, 0x93, 0x4b, 0x29, 0xb4, 0x74, 0xba, 0xad, 0x26, 0x97, 0xe2, 0x9f, 0x1f, 0x92, 0x0d, 0xcc, 0x77, 0xc8, 0xa0, 0xa0, 0x88, 0x44, 0x76, 0x24}
 
-	preMasterSecret, err := prfPreMasterSecret(publicKey, privateKey, namedCurveX25519)
+	preMasterSecret, err := prfPreMasterSecret(publicKey, privateKey, elliptic.X25519)
 	if err != nil {
 		t.Fatal(err)
 	} else if !bytes.Equal(expectedPreMasterSecret, preMasterSecret) {
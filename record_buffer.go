@@ -0,0 +1,29 @@
+package dtls
+
+import "github.com/pion/dtls/v2/internal/net/udp"
+
+// inboundRecord is the decrypted application data of a record, it is
+// backed by buf if the record was decrypted in a pooled buffer
+type inboundRecord struct {
+	data []byte
+	buf  *udp.Buffer
+}
+
+// RecordBuffer is the application data of a received record, decrypted in
+// place in the buffer the datagram was read in. The buffer is reused for
+// other datagrams once Release is called, which must be done exactly once.
+type RecordBuffer struct {
+	record inboundRecord
+}
+
+// Bytes returns the application data, it must not be used after Release
+func (r RecordBuffer) Bytes() []byte {
+	return r.record.data
+}
+
+// Release returns the buffer of the record to the pool
+func (r RecordBuffer) Release() {
+	if r.record.buf != nil {
+		r.record.buf.Release()
+	}
+}
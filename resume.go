@@ -3,11 +3,15 @@ package dtls
 import (
 	"context"
 	"net"
+
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
 )
 
 // Export extracts dtls state and inner connection from an already handshaked dtls conn
 func (c *Conn) Export() (*State, net.Conn, error) {
+	c.lock.RLock()
 	state, err := c.state.clone()
+	c.lock.RUnlock()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -17,21 +21,21 @@ func (c *Conn) Export() (*State, net.Conn, error) {
 // Resume imports an already stablished dtls connection using a specific dtls state
 func Resume(state *State, conn net.Conn, config *Config) (*Conn, error) {
 	// Custom flight handler that sets imported data and signals as handshaked
-	flightHandler := func(c *Conn) (bool, *alert, error) {
+	flightHandler := func(c *Conn) (bool, *alert.Alert, error) {
 		c.state = *state
 		c.handshakeDoneSignal.Close()
 		return true, nil, nil
 	}
 
 	// Empty handshake handler, since handshake was already done
-	handshakeHandler := func(c *Conn) (*alert, error) {
+	handshakeHandler := func(c *Conn) (*alert.Alert, error) {
 		return nil, nil
 	}
 
-	c, err := createConn(context.Background(), conn, flightHandler, handshakeHandler, config, state.isClient)
+	c, err := createConn(conn, flightHandler, handshakeHandler, config, state.isClient)
 	if err != nil {
 		return nil, err
 	}
 
-	return c, c.handshakeErr.load()
+	return c, c.Handshake(context.Background())
 }
@@ -3,6 +3,7 @@ package dtls
 import (
 	"bytes"
 	"crypto/tls"
+	"encoding/gob"
 	"fmt"
 	"net"
 	"sync"
@@ -10,6 +11,8 @@ import (
 	"time"
 
 	"github.com/pion/dtls/v2/pkg/crypto/selfsign"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 func TestResumeClient(t *testing.T) {
@@ -96,6 +99,11 @@ func DoTestResume(t *testing.T, newLocal, newRemote func(net.Conn, *Config) (*Co
 		fatal(t, errChan, fmt.Errorf("messages missmatch: %s != %s", message, recv[:n]))
 	}
 
+	keyingMaterial, err := local.ExportKeyingMaterial("EXPERIMENTAL_resume", []byte{}, 16)
+	if err != nil {
+		fatal(t, errChan, err)
+	}
+
 	// Export dtls connection
 	var state *State
 	var innerConn net.Conn
@@ -119,12 +127,20 @@ func DoTestResume(t *testing.T, newLocal, newRemote func(net.Conn, *Config) (*Co
 	}
 
 	// Resume dtls connection
-	var resumed net.Conn
-	resumed, err = Resume(state, localConn2, config)
+	var resumed *Conn
+	resumed, err = Resume(deserialized, localConn2, config)
 	if err != nil {
 		fatal(t, errChan, err)
 	}
 
+	resumedKeyingMaterial, err := resumed.ExportKeyingMaterial("EXPERIMENTAL_resume", []byte{}, 16)
+	if err != nil {
+		fatal(t, errChan, err)
+	}
+	if !bytes.Equal(keyingMaterial, resumedKeyingMaterial) {
+		fatal(t, errChan, fmt.Errorf("keying material missmatch: % 02x != % 02x", keyingMaterial, resumedKeyingMaterial))
+	}
+
 	// Test write and read on resumed connection
 	if _, err = resumed.Write(message); err != nil {
 		fatal(t, errChan, err)
@@ -194,3 +210,52 @@ func (b *backupConn) SetReadDeadline(t time.Time) error {
 func (b *backupConn) SetWriteDeadline(t time.Time) error {
 	return nil
 }
+
+func TestStateUnmarshalBinaryInvalid(t *testing.T) {
+	random, err := (&handshake.Random{}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := serializedState{
+		LocalRandom:   random,
+		RemoteRandom:  random,
+		CipherSuiteID: uint16(TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256),
+		MasterSecret:  make([]byte, 48),
+		IsClient:      true,
+	}
+
+	for name, tt := range map[string]struct {
+		modify      func(*serializedState)
+		expectedErr error
+	}{
+		"MaxEpoch": {
+			modify: func(s *serializedState) {
+				s.LocalEpoch = 0xffff
+			},
+		},
+		"UnknownCipherSuite": {
+			modify: func(s *serializedState) {
+				s.CipherSuiteID = 0
+			},
+			expectedErr: errInvalidCipherSuite,
+		},
+		"SequenceNumberOverflow": {
+			modify: func(s *serializedState) {
+				s.SequenceNumber = recordlayer.MaxSequenceNumber + 1
+			},
+			expectedErr: errInvalidSequenceNumber,
+		},
+	} {
+		serialized := valid
+		tt.modify(&serialized)
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(serialized); err != nil {
+			t.Fatal(err)
+		}
+
+		state := &State{}
+		if err := state.UnmarshalBinary(buf.Bytes()); err != tt.expectedErr {
+			t.Errorf("%s: UnmarshalBinary expected(%v) actual(%v)", name, tt.expectedErr, err)
+		}
+	}
+}
@@ -4,129 +4,194 @@ import (
 	"bytes"
 	"crypto/x509"
 	"fmt"
+
+	"github.com/pion/dtls/v2/pkg/crypto/clientcertificate"
+	"github.com/pion/dtls/v2/pkg/crypto/elliptic"
+	"github.com/pion/dtls/v2/pkg/crypto/hash"
+	"github.com/pion/dtls/v2/pkg/crypto/signature"
+	"github.com/pion/dtls/v2/pkg/crypto/signaturehash"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/extension"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
-func serverHandshakeHandler(c *Conn) (*alert, error) {
-	handleSingleHandshake := func(buf []byte) (*alert, error) {
-		rawHandshake := &handshake{}
+func serverHandshakeHandler(c *Conn) (*alert.Alert, error) {
+	handleSingleHandshake := func(buf []byte) (*alert.Alert, error) {
+		rawHandshake := &handshake.Handshake{}
 		if err := rawHandshake.Unmarshal(buf); err != nil {
-			return &alert{alertLevelFatal, alertDecodeError}, err
+			return &alert.Alert{Level: alert.Fatal, Description: alert.DecodeError}, err
 		}
 
-		switch h := rawHandshake.handshakeMessage.(type) {
-		case *handshakeMessageClientHello:
+		switch h := rawHandshake.Message.(type) {
+		case *handshake.MessageClientHello:
 			if c.currFlight.get() == flight2 {
-				if !bytes.Equal(c.cookie, h.cookie) {
-					return &alert{alertLevelFatal, alertAccessDenied}, errCookieMismatch
+				if !bytes.Equal(c.cookie, h.Cookie) {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.AccessDenied}, errCookieMismatch
 				}
 				c.handshakeMessageSequence = 1
-				c.currFlight.set(flight4)
+				if err := c.currFlight.set(flight4); err != nil {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+				}
 				break
 			}
 
-			c.state.remoteRandom = h.random
+			c.state.remoteRandom = h.Random
 
-			if _, ok := findMatchingCipherSuite(h.cipherSuites, c.localCipherSuites); !ok {
-				return &alert{alertLevelFatal, alertInsufficientSecurity}, errCipherSuiteNoIntersection
+			cipherSuites := cipherSuitesForIDs(h.CipherSuiteIDs)
+			if _, ok := findMatchingCipherSuite(cipherSuites, c.localCipherSuites); !ok {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errCipherSuiteNoIntersection
+			}
+			c.state.cipherSuite = cipherSuites[0]
+
+			renegotiationSCSV := false
+			for _, id := range h.CipherSuiteIDs {
+				if CipherSuiteID(id) == renegotiationInfoSCSV {
+					renegotiationSCSV = true
+				}
 			}
-			c.state.cipherSuite = h.cipherSuites[0]
 
-			for _, extension := range h.extensions {
-				switch e := extension.(type) {
-				case *extensionSupportedEllipticCurves:
-					if len(e.ellipticCurves) == 0 {
-						return &alert{alertLevelFatal, alertInsufficientSecurity}, errNoSupportedEllipticCurves
+			var renegotiationInfo *extension.RenegotiationInfo
+			for _, val := range h.Extensions {
+				switch e := val.(type) {
+				case *extension.SupportedEllipticCurves:
+					if len(e.EllipticCurves) == 0 {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errNoSupportedEllipticCurves
 					}
-					c.namedCurve = e.ellipticCurves[0]
-				case *extensionUseSRTP:
-					profile, ok := findMatchingSRTPProfile(e.protectionProfiles, c.localSRTPProtectionProfiles)
+					c.namedCurve = e.EllipticCurves[0]
+				case *extension.UseSRTP:
+					profile, ok := findMatchingSRTPProfile(e.ProtectionProfiles, c.localSRTPProtectionProfiles)
 					if !ok {
-						return &alert{alertLevelFatal, alertInsufficientSecurity}, errServerNoMatchingSRTPProfile
+						return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errServerNoMatchingSRTPProfile
 					}
 					c.state.srtpProtectionProfile = profile
-				case *extensionUseExtendedMasterSecret:
+					c.state.srtpMasterKeyIdentifier = e.MasterKeyIdentifier
+				case *extension.UseExtendedMasterSecret:
 					if c.extendedMasterSecret != DisableExtendedMasterSecret {
 						c.state.extendedMasterSecret = true
 					}
-				case *extensionServerName:
-					c.serverName = e.serverName
+				case *extension.ServerName:
+					c.serverName = e.ServerName
+				case *extension.RenegotiationInfo:
+					renegotiationInfo = e
+				case *extension.ALPN:
+					proto, err := negotiateALPN(c.localNextProtos, e.ProtocolNameList)
+					if err != nil {
+						return &alert.Alert{Level: alert.Fatal, Description: alert.NoApplicationProtocol}, err
+					}
+					c.state.negotiatedProtocol = proto
+				case *extension.EncryptThenMAC:
+					if cipherSuite, ok := c.state.cipherSuite.(encryptThenMACCipherSuite); ok {
+						cipherSuite.setEncryptThenMAC()
+						c.state.encryptThenMAC = true
+					}
 				}
 			}
 
+			switch {
+			case c.renegotiating && renegotiationSCSV:
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errRenegotiationInfoMismatch
+			case renegotiationInfo != nil:
+				if !bytes.Equal(renegotiationInfo.RenegotiatedConnection, c.clientVerifyData) {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errRenegotiationInfoMismatch
+				}
+				c.secureRenegotiation = true
+			case c.renegotiating:
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errRenegotiationUnsupported
+			case renegotiationSCSV:
+				c.secureRenegotiation = true
+			}
+
 			if c.extendedMasterSecret == RequireExtendedMasterSecret && !c.state.extendedMasterSecret {
-				return &alert{alertLevelFatal, alertInsufficientSecurity}, errServerRequiredButNoClientEMS
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InsufficientSecurity}, errServerRequiredButNoClientEMS
+			}
+
+			remoteHelloExtensions, err := c.handleRemoteHelloExtensions(h.Extensions)
+			if err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
+			}
+			if err := c.populateLocalHelloExtensions(remoteHelloExtensions); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
 
 			if c.localKeypair == nil {
 				var err error
-				c.localKeypair, err = generateKeypair(c.namedCurve)
+				c.localKeypair, err = elliptic.GenerateKeypair(c.namedCurve, c.rand)
 				if err != nil {
-					return &alert{alertLevelFatal, alertIllegalParameter}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
 				}
 			}
 
-			c.currFlight.set(flight2)
+			nextFlight := flight2
+			if c.renegotiating {
+				// The cookie exchange is skipped, the remote is already authenticated
+				nextFlight = flight4
+			}
+			if err := c.currFlight.set(nextFlight); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			}
 
-		case *handshakeMessageCertificateVerify:
+		case *handshake.MessageCertificateVerify:
 			if c.state.remoteCertificate == nil {
-				return &alert{alertLevelFatal, alertNoCertificate}, errCertificateVerifyNoCertificate
+				return &alert.Alert{Level: alert.Fatal, Description: alert.NoCertificate}, errCertificateVerifyNoCertificate
 			}
 
 			plainText := c.handshakeCache.pullAndMerge(
-				handshakeCachePullRule{handshakeTypeClientHello, true},
-				handshakeCachePullRule{handshakeTypeServerHello, false},
-				handshakeCachePullRule{handshakeTypeCertificate, false},
-				handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-				handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-				handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-				handshakeCachePullRule{handshakeTypeCertificate, true},
-				handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
+				handshakeCachePullRule{handshake.TypeClientHello, true},
+				handshakeCachePullRule{handshake.TypeServerHello, false},
+				handshakeCachePullRule{handshake.TypeCertificate, false},
+				handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+				handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+				handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+				handshakeCachePullRule{handshake.TypeCertificate, true},
+				handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
 			)
 
-			if err := verifyCertificateVerify(plainText, h.hashAlgorithm, h.signature, c.state.remoteCertificate); err != nil {
-				return &alert{alertLevelFatal, alertBadCertificate}, err
+			if err := verifyCertificateVerify(plainText, h.HashAlgorithm, h.Signature, c.state.remoteCertificate); err != nil {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 			}
 			var chains [][]*x509.Certificate
 			var err error
 			var verified bool
 			if c.clientAuth >= VerifyClientCertIfGiven {
-				if chains, err = verifyClientCert(c.state.remoteCertificate, c.clientCAs); err != nil {
-					return &alert{alertLevelFatal, alertBadCertificate}, err
+				if chains, err = verifyClientCert(c.state.remoteCertificate, c.clientCAs, c.clock.Now()); err != nil {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 				}
 				verified = true
 			}
 			if c.verifyPeerCertificate != nil {
 				if err := c.verifyPeerCertificate(c.state.remoteCertificate, chains); err != nil {
-					return &alert{alertLevelFatal, alertBadCertificate}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, err
 				}
 			}
 			c.remoteCertificateVerified = verified
 
-		case *handshakeMessageCertificate:
-			c.state.remoteCertificate = h.certificate
+		case *handshake.MessageCertificate:
+			c.state.remoteCertificate = h.Certificate
 
-		case *handshakeMessageClientKeyExchange:
+		case *handshake.MessageClientKeyExchange:
 			serverRandom, err := c.state.localRandom.Marshal()
 			if err != nil {
-				return &alert{alertLevelFatal, alertInternalError}, err
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
 			clientRandom, err := c.state.remoteRandom.Marshal()
 			if err != nil {
-				return &alert{alertLevelFatal, alertInternalError}, err
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
 
 			var preMasterSecret []byte
 			if c.localPSKCallback != nil {
 				var psk []byte
-				if psk, err = c.localPSKCallback(h.identityHint); err != nil {
-					return &alert{alertLevelFatal, alertInternalError}, err
+				if psk, err = c.localPSKCallback(h.IdentityHint); err != nil {
+					return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 
 				preMasterSecret = prfPSKPreMasterSecret(psk)
 			} else {
-				preMasterSecret, err = prfPreMasterSecret(h.publicKey, c.localKeypair.privateKey, c.localKeypair.curve)
+				preMasterSecret, err = prfPreMasterSecret(h.PublicKey, c.localKeypair.PrivateKey, c.localKeypair.Curve)
 				if err != nil {
-					return &alert{alertLevelFatal, alertIllegalParameter}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.IllegalParameter}, err
 				}
 			}
 
@@ -134,44 +199,45 @@ func serverHandshakeHandler(c *Conn) (*alert, error) {
 				var sessionHash []byte
 				sessionHash, err = c.handshakeCache.sessionHash(c.state.cipherSuite.hashFunc())
 				if err != nil {
-					return &alert{alertLevelFatal, alertInternalError}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 
 				c.state.masterSecret, err = prfExtendedMasterSecret(preMasterSecret, sessionHash, c.state.cipherSuite.hashFunc())
 				if err != nil {
-					return &alert{alertLevelFatal, alertInternalError}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 			} else {
 				c.state.masterSecret, err = prfMasterSecret(preMasterSecret, clientRandom, serverRandom, c.state.cipherSuite.hashFunc())
 				if err != nil {
-					return &alert{alertLevelFatal, alertInternalError}, err
+					return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 			}
 
 			if err := c.state.cipherSuite.init(c.state.masterSecret, clientRandom, serverRandom /* isClient */, false); err != nil {
-				return &alert{alertLevelFatal, alertInternalError}, err
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
-		case *handshakeMessageFinished:
+		case *handshake.MessageFinished:
 			plainText := c.handshakeCache.pullAndMerge(
-				handshakeCachePullRule{handshakeTypeClientHello, true},
-				handshakeCachePullRule{handshakeTypeServerHello, false},
-				handshakeCachePullRule{handshakeTypeCertificate, false},
-				handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-				handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-				handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-				handshakeCachePullRule{handshakeTypeCertificate, true},
-				handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
-				handshakeCachePullRule{handshakeTypeCertificateVerify, true},
+				handshakeCachePullRule{handshake.TypeClientHello, true},
+				handshakeCachePullRule{handshake.TypeServerHello, false},
+				handshakeCachePullRule{handshake.TypeCertificate, false},
+				handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+				handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+				handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+				handshakeCachePullRule{handshake.TypeCertificate, true},
+				handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
+				handshakeCachePullRule{handshake.TypeCertificateVerify, true},
 			)
 			expectedVerifyData, err := prfVerifyDataClient(c.state.masterSecret, plainText, c.state.cipherSuite.hashFunc())
 			if err != nil {
-				return &alert{alertLevelFatal, alertInternalError}, err
-			} else if !bytes.Equal(expectedVerifyData, h.verifyData) {
-				return &alert{alertLevelFatal, alertHandshakeFailure}, errVerifyDataMismatch
+				return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+			} else if !bytes.Equal(expectedVerifyData, h.VerifyData) {
+				return &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, errVerifyDataMismatch
 			}
+			c.clientVerifyData = append([]byte{}, h.VerifyData...)
 
 		default:
-			return &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("unhandled handshake %d", h.handshakeType())
+			return &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("unhandled handshake %d", h.Type())
 		}
 
 		return nil, nil
@@ -180,23 +246,23 @@ func serverHandshakeHandler(c *Conn) (*alert, error) {
 	switch c.currFlight.get() {
 	case flight0:
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeClientHello, true},
+			handshakeCachePullRule{handshake.TypeClientHello, true},
 		)
 		if expectedMessages[0] != nil && expectedMessages[0].messageSequence == 0 {
 			return handleSingleHandshake(expectedMessages[0].data)
 		}
 	case flight2:
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeClientHello, true},
+			handshakeCachePullRule{handshake.TypeClientHello, true},
 		)
 		if expectedMessages[0] != nil && expectedMessages[0].messageSequence == 1 {
 			return handleSingleHandshake(expectedMessages[0].data)
 		}
 	case flight4:
 		expectedMessages := c.handshakeCache.pull(
-			handshakeCachePullRule{handshakeTypeCertificate, true},
-			handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
-			handshakeCachePullRule{handshakeTypeCertificateVerify, true},
+			handshakeCachePullRule{handshake.TypeCertificate, true},
+			handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
+			handshakeCachePullRule{handshake.TypeCertificateVerify, true},
 		)
 
 		var expectedSeqnum uint16
@@ -210,7 +276,7 @@ func serverHandshakeHandler(c *Conn) (*alert, error) {
 		}
 
 		for i, msg := range expectedMessages {
-			// handshakeTypeCertificate and handshakeTypeCertificateVerify can be nil, just make sure we have no gaps
+			// handshake.TypeCertificate and handshake.TypeCertificateVerify can be nil, just make sure we have no gaps
 			switch {
 			case (i == 0 || i == 2) && msg == nil:
 				continue
@@ -230,7 +296,7 @@ func serverHandshakeHandler(c *Conn) (*alert, error) {
 			}
 		}
 
-		finishedMsg := c.handshakeCache.pull(handshakeCachePullRule{handshakeTypeFinished, true})
+		finishedMsg := c.handshakeCache.pull(handshakeCachePullRule{handshake.TypeFinished, true})
 		if finishedMsg[0] == nil {
 			return nil, nil
 		} else if alertPtr, err := handleSingleHandshake(finishedMsg[0].data); err != nil {
@@ -240,204 +306,253 @@ func serverHandshakeHandler(c *Conn) (*alert, error) {
 		switch c.clientAuth {
 		case RequireAnyClientCert:
 			if c.state.remoteCertificate == nil {
-				return &alert{alertLevelFatal, alertNoCertificate}, errClientCertificateRequired
+				return &alert.Alert{Level: alert.Fatal, Description: alert.NoCertificate}, errClientCertificateRequired
 			}
 		case VerifyClientCertIfGiven:
 			if c.state.remoteCertificate != nil && !c.remoteCertificateVerified {
-				return &alert{alertLevelFatal, alertBadCertificate}, errClientCertificateNotVerified
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, errClientCertificateNotVerified
 			}
 		case RequireAndVerifyClientCert:
 			if c.state.remoteCertificate == nil {
-				return &alert{alertLevelFatal, alertNoCertificate}, errClientCertificateRequired
+				return &alert.Alert{Level: alert.Fatal, Description: alert.NoCertificate}, errClientCertificateRequired
 			}
 			if !c.remoteCertificateVerified {
-				return &alert{alertLevelFatal, alertBadCertificate}, errClientCertificateNotVerified
+				return &alert.Alert{Level: alert.Fatal, Description: alert.BadCertificate}, errClientCertificateNotVerified
 			}
 		}
 
-		switch {
-		case c.localPSKIdentityHint != nil:
-			c.handshakeMessageSequence = 4
-		case c.localPSKCallback != nil:
-			c.handshakeMessageSequence = 3
-		case c.clientAuth > NoClientCert:
-			c.handshakeMessageSequence = 6
-		default:
-			c.handshakeMessageSequence = 5
+		// Finished follows the ServerHelloDone which ended flight4
+		serverHelloDone := c.handshakeCache.pull(handshakeCachePullRule{handshake.TypeServerHelloDone, false})
+		if serverHelloDone[0] == nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, errServerHelloDoneNotSent
 		}
+		c.handshakeMessageSequence = int(serverHelloDone[0].messageSequence) + 1
 
-		c.setLocalEpoch(1)
-		c.currFlight.set(flight6)
+		c.setLocalEpoch(c.handshakeEpoch + 1)
+		if err := c.currFlight.set(flight6); err != nil {
+			return &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
 	}
 	return nil, nil
 }
 
-func serverFlightHandler(c *Conn) (bool, *alert, error) {
+func serverFlightHandler(c *Conn) (bool, *alert.Alert, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	switch c.currFlight.get() {
 	case flight0:
-		// Waiting for ClientHello
+		if !c.helloRequestPending {
+			break // Waiting for ClientHello
+		}
+
+		if err := c.bufferPacket(&packet{
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.getLocalEpoch(),
+					Version: protocol.Version1_2,
+				},
+				Content: &handshake.Handshake{
+					Message: &handshake.MessageHelloRequest{},
+				},
+			},
+			shouldEncrypt: true,
+		}); err != nil {
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
+		if err := c.flushPacketBuffer(); err != nil {
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
+		}
 	case flight2:
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(c.handshakeMessageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(c.handshakeMessageSequence),
 					},
-					handshakeMessage: &handshakeMessageHelloVerifyRequest{
-						version: protocolVersion1_2,
-						cookie:  c.cookie,
+					Message: &handshake.MessageHelloVerifyRequest{
+						Version: protocol.Version1_2,
+						Cookie:  c.cookie,
 					},
 				},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 	case flight4:
-		extensions := []extension{}
+		extensions := []extension.Extension{}
 		if (c.extendedMasterSecret == RequestExtendedMasterSecret ||
 			c.extendedMasterSecret == RequireExtendedMasterSecret) && c.state.extendedMasterSecret {
-			extensions = append(extensions, &extensionUseExtendedMasterSecret{
-				supported: true,
+			extensions = append(extensions, &extension.UseExtendedMasterSecret{
+				Supported: true,
 			})
 		}
 		if c.state.srtpProtectionProfile != 0 {
-			extensions = append(extensions, &extensionUseSRTP{
-				protectionProfiles: []SRTPProtectionProfile{c.state.srtpProtectionProfile},
+			extensions = append(extensions, &extension.UseSRTP{
+				ProtectionProfiles:  []SRTPProtectionProfile{c.state.srtpProtectionProfile},
+				MasterKeyIdentifier: c.state.srtpMasterKeyIdentifier,
 			})
 		}
 		if c.localPSKCallback == nil {
-			extensions = append(extensions, []extension{
-				&extensionSupportedEllipticCurves{
-					ellipticCurves: []namedCurve{namedCurveX25519, namedCurveP256, namedCurveP384},
+			extensions = append(extensions, []extension.Extension{
+				&extension.SupportedEllipticCurves{
+					EllipticCurves: []elliptic.Curve{elliptic.X25519, elliptic.P256, elliptic.P384},
 				},
-				&extensionSupportedPointFormats{
-					pointFormats: []ellipticCurvePointFormat{ellipticCurvePointFormatUncompressed},
+				&extension.SupportedPointFormats{
+					PointFormats: []extension.EllipticCurvePointFormat{extension.EllipticCurvePointFormatUncompressed},
 				},
 			}...)
 		}
+		if c.state.negotiatedProtocol != "" {
+			extensions = append(extensions, &extension.ALPN{
+				ProtocolNameList: []string{c.state.negotiatedProtocol},
+			})
+		}
+		if c.state.encryptThenMAC {
+			extensions = append(extensions, &extension.EncryptThenMAC{
+				Supported: true,
+			})
+		}
+		if c.secureRenegotiation {
+			extensions = append(extensions, &extension.RenegotiationInfo{
+				RenegotiatedConnection: append(append([]byte{}, c.clientVerifyData...), c.serverVerifyData...),
+			})
+		}
+
+		for i := range c.localHelloExtensions {
+			extensions = append(extensions, &c.localHelloExtensions[i])
+		}
 
+		cipherSuiteID := uint16(c.state.cipherSuite.ID())
 		messageSequence := c.handshakeMessageSequence
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(messageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(messageSequence),
 					},
-					handshakeMessage: &handshakeMessageServerHello{
-						version:           protocolVersion1_2,
-						random:            c.state.localRandom,
-						cipherSuite:       c.state.cipherSuite,
-						compressionMethod: defaultCompressionMethods[0],
-						extensions:        extensions,
+					Message: &handshake.MessageServerHello{
+						Version:           protocol.Version1_2,
+						Random:            c.state.localRandom,
+						CipherSuiteID:     &cipherSuiteID,
+						CompressionMethod: defaultCompressionMethods()[0],
+						Extensions:        extensions,
 					}},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 		messageSequence++
 
 		if c.localPSKCallback == nil {
 			certificate, err := c.getCertificate(c.serverName)
 			if err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 
 			if err := c.bufferPacket(&packet{
-				record: &recordLayer{
-					recordLayerHeader: recordLayerHeader{
-						protocolVersion: protocolVersion1_2,
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Epoch:   c.handshakeEpoch,
+						Version: protocol.Version1_2,
 					},
-					content: &handshake{
-						handshakeHeader: handshakeHeader{
-							messageSequence: uint16(messageSequence),
+					Content: &handshake.Handshake{
+						Header: handshake.Header{
+							MessageSequence: uint16(messageSequence),
 						},
-						handshakeMessage: &handshakeMessageCertificate{
-							certificate: certificate.Certificate,
+						Message: &handshake.MessageCertificate{
+							Certificate: certificate.Certificate,
 						}},
 				},
+				shouldEncrypt: c.handshakeEpoch != 0,
 			}); err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 			messageSequence++
 
 			if len(c.localKeySignature) == 0 {
 				serverRandom, err := c.state.localRandom.Marshal()
 				if err != nil {
-					return false, &alert{alertLevelFatal, alertInternalError}, err
+					return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 				clientRandom, err := c.state.remoteRandom.Marshal()
 				if err != nil {
-					return false, &alert{alertLevelFatal, alertInternalError}, err
+					return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 
-				signature, err := generateKeySignature(clientRandom, serverRandom, c.localKeypair.publicKey, c.namedCurve, certificate.PrivateKey, hashAlgorithmSHA256)
+				signature, err := generateKeySignature(clientRandom, serverRandom, c.localKeypair.PublicKey, c.namedCurve, certificate.PrivateKey, hash.SHA256, c.rand)
 				if err != nil {
-					return false, &alert{alertLevelFatal, alertInternalError}, err
+					return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 				}
 				c.localKeySignature = signature
 			}
 
 			if err := c.bufferPacket(&packet{
-				record: &recordLayer{
-					recordLayerHeader: recordLayerHeader{
-						protocolVersion: protocolVersion1_2,
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Epoch:   c.handshakeEpoch,
+						Version: protocol.Version1_2,
 					},
-					content: &handshake{
-						handshakeHeader: handshakeHeader{
-							messageSequence: uint16(messageSequence),
+					Content: &handshake.Handshake{
+						Header: handshake.Header{
+							MessageSequence: uint16(messageSequence),
 						},
-						handshakeMessage: &handshakeMessageServerKeyExchange{
-							ellipticCurveType:  ellipticCurveTypeNamedCurve,
-							namedCurve:         c.namedCurve,
-							publicKey:          c.localKeypair.publicKey,
-							hashAlgorithm:      hashAlgorithmSHA256,
-							signatureAlgorithm: signatureAlgorithmECDSA,
-							signature:          c.localKeySignature,
+						Message: &handshake.MessageServerKeyExchange{
+							EllipticCurveType:  elliptic.CurveTypeNamedCurve,
+							NamedCurve:         c.namedCurve,
+							PublicKey:          c.localKeypair.PublicKey,
+							HashAlgorithm:      hash.SHA256,
+							SignatureAlgorithm: signature.ECDSA,
+							Signature:          c.localKeySignature,
 						}},
 				},
+				shouldEncrypt: c.handshakeEpoch != 0,
 			}); err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 			messageSequence++
 
 			if c.clientAuth > NoClientCert {
 				if err := c.bufferPacket(&packet{
-					record: &recordLayer{
-						recordLayerHeader: recordLayerHeader{
-							protocolVersion: protocolVersion1_2,
+					record: &recordlayer.RecordLayer{
+						Header: recordlayer.Header{
+							Epoch:   c.handshakeEpoch,
+							Version: protocol.Version1_2,
 						},
-						content: &handshake{
-							handshakeHeader: handshakeHeader{
-								messageSequence: uint16(messageSequence),
+						Content: &handshake.Handshake{
+							Header: handshake.Header{
+								MessageSequence: uint16(messageSequence),
 							},
-							handshakeMessage: &handshakeMessageCertificateRequest{
-								certificateTypes: []clientCertificateType{clientCertificateTypeRSASign, clientCertificateTypeECDSASign},
-								signatureHashAlgorithms: []signatureHashAlgorithm{
-									{hashAlgorithmSHA256, signatureAlgorithmRSA},
-									{hashAlgorithmSHA384, signatureAlgorithmRSA},
-									{hashAlgorithmSHA512, signatureAlgorithmRSA},
-									{hashAlgorithmSHA256, signatureAlgorithmECDSA},
-									{hashAlgorithmSHA384, signatureAlgorithmECDSA},
-									{hashAlgorithmSHA512, signatureAlgorithmECDSA},
+							Message: &handshake.MessageCertificateRequest{
+								CertificateTypes: []clientcertificate.Type{clientcertificate.RSASign, clientcertificate.ECDSASign},
+								SignatureHashAlgorithms: []signaturehash.Algorithm{
+									{Hash: hash.SHA256, Signature: signature.RSA},
+									{Hash: hash.SHA384, Signature: signature.RSA},
+									{Hash: hash.SHA512, Signature: signature.RSA},
+									{Hash: hash.SHA256, Signature: signature.ECDSA},
+									{Hash: hash.SHA384, Signature: signature.ECDSA},
+									{Hash: hash.SHA512, Signature: signature.ECDSA},
 								},
 							},
 						},
 					},
+					shouldEncrypt: c.handshakeEpoch != 0,
 				}); err != nil {
-					return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+					return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 				}
 				messageSequence++
 			}
@@ -449,105 +564,112 @@ func serverFlightHandler(c *Conn) (bool, *alert, error) {
 			*  https://tools.ietf.org/html/rfc4279#section-2
 			 */
 			if err := c.bufferPacket(&packet{
-				record: &recordLayer{
-					recordLayerHeader: recordLayerHeader{
-						protocolVersion: protocolVersion1_2,
+				record: &recordlayer.RecordLayer{
+					Header: recordlayer.Header{
+						Epoch:   c.handshakeEpoch,
+						Version: protocol.Version1_2,
 					},
-					content: &handshake{
-						handshakeHeader: handshakeHeader{
-							messageSequence: uint16(messageSequence),
+					Content: &handshake.Handshake{
+						Header: handshake.Header{
+							MessageSequence: uint16(messageSequence),
 						},
-						handshakeMessage: &handshakeMessageServerKeyExchange{
-							identityHint: c.localPSKIdentityHint,
+						Message: &handshake.MessageServerKeyExchange{
+							IdentityHint: c.localPSKIdentityHint,
 						}},
 				},
+				shouldEncrypt: c.handshakeEpoch != 0,
 			}); err != nil {
-				return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 			}
 			messageSequence++
 		}
 
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(messageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(messageSequence),
 					},
-					handshakeMessage: &handshakeMessageServerHelloDone{},
+					Message: &handshake.MessageServerHelloDone{},
 				},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 	case flight6:
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch,
+					Version: protocol.Version1_2,
 				},
-				content: &changeCipherSpec{},
+				Content: &protocol.ChangeCipherSpec{},
 			},
+			shouldEncrypt: c.handshakeEpoch != 0,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if len(c.localVerifyData) == 0 {
 			plainText := c.handshakeCache.pullAndMerge(
-				handshakeCachePullRule{handshakeTypeClientHello, true},
-				handshakeCachePullRule{handshakeTypeServerHello, false},
-				handshakeCachePullRule{handshakeTypeCertificate, false},
-				handshakeCachePullRule{handshakeTypeServerKeyExchange, false},
-				handshakeCachePullRule{handshakeTypeCertificateRequest, false},
-				handshakeCachePullRule{handshakeTypeServerHelloDone, false},
-				handshakeCachePullRule{handshakeTypeCertificate, true},
-				handshakeCachePullRule{handshakeTypeClientKeyExchange, true},
-				handshakeCachePullRule{handshakeTypeCertificateVerify, true},
-				handshakeCachePullRule{handshakeTypeFinished, true},
+				handshakeCachePullRule{handshake.TypeClientHello, true},
+				handshakeCachePullRule{handshake.TypeServerHello, false},
+				handshakeCachePullRule{handshake.TypeCertificate, false},
+				handshakeCachePullRule{handshake.TypeServerKeyExchange, false},
+				handshakeCachePullRule{handshake.TypeCertificateRequest, false},
+				handshakeCachePullRule{handshake.TypeServerHelloDone, false},
+				handshakeCachePullRule{handshake.TypeCertificate, true},
+				handshakeCachePullRule{handshake.TypeClientKeyExchange, true},
+				handshakeCachePullRule{handshake.TypeCertificateVerify, true},
+				handshakeCachePullRule{handshake.TypeFinished, true},
 			)
 
 			var err error
 			c.localVerifyData, err = prfVerifyDataServer(c.state.masterSecret, plainText, c.state.cipherSuite.hashFunc())
 			if err != nil {
-				return false, &alert{alertLevelFatal, alertInternalError}, err
+				return false, &alert.Alert{Level: alert.Fatal, Description: alert.InternalError}, err
 			}
 		}
 
 		if err := c.bufferPacket(&packet{
-			record: &recordLayer{
-				recordLayerHeader: recordLayerHeader{
-					epoch:           1,
-					protocolVersion: protocolVersion1_2,
+			record: &recordlayer.RecordLayer{
+				Header: recordlayer.Header{
+					Epoch:   c.handshakeEpoch + 1,
+					Version: protocol.Version1_2,
 				},
-				content: &handshake{
-					handshakeHeader: handshakeHeader{
-						messageSequence: uint16(c.handshakeMessageSequence),
+				Content: &handshake.Handshake{
+					Header: handshake.Header{
+						MessageSequence: uint16(c.handshakeMessageSequence),
 					},
 
-					handshakeMessage: &handshakeMessageFinished{
-						verifyData: c.localVerifyData,
+					Message: &handshake.MessageFinished{
+						VerifyData: c.localVerifyData,
 					}},
 			},
-			shouldEncrypt:            true,
-			resetLocalSequenceNumber: true,
+			shouldEncrypt: true,
 		}); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
 		if err := c.flushPacketBuffer(); err != nil {
-			return false, &alert{alertLevelFatal, alertHandshakeFailure}, err
+			return false, &alert.Alert{Level: alert.Fatal, Description: alert.HandshakeFailure}, err
 		}
 
+		c.serverVerifyData = c.localVerifyData
+		c.finishRenegotiation()
 		c.handshakeDoneSignal.Close()
 		return true, nil, nil
 	default:
-		return false, &alert{alertLevelFatal, alertUnexpectedMessage}, fmt.Errorf("unhandled flight %s", c.currFlight.get())
+		return false, &alert.Alert{Level: alert.Fatal, Description: alert.UnexpectedMessage}, fmt.Errorf("unhandled flight %s", c.currFlight.get())
 	}
 	return false, nil, nil
 }
@@ -1,6 +0,0 @@
-package dtls
-
-type signatureHashAlgorithm struct {
-	hash      hashAlgorithm
-	signature signatureAlgorithm
-}
@@ -1,13 +1,27 @@
 package dtls
 
+import "github.com/pion/dtls/v2/pkg/protocol/extension"
+
 // SRTPProtectionProfile defines the parameters and options that are in effect for the SRTP processing
 // https://tools.ietf.org/html/rfc5764#section-4.1.2
-type SRTPProtectionProfile uint16
+type SRTPProtectionProfile = extension.SRTPProtectionProfile
 
 const (
-	SRTP_AES128_CM_HMAC_SHA1_80 SRTPProtectionProfile = 0x0001 // nolint
+	SRTP_AES128_CM_HMAC_SHA1_80 = extension.SRTP_AES128_CM_HMAC_SHA1_80 // nolint
+	SRTP_AES128_CM_HMAC_SHA1_32 = extension.SRTP_AES128_CM_HMAC_SHA1_32 // nolint
+	SRTP_AEAD_AES_128_GCM       = extension.SRTP_AEAD_AES_128_GCM       // nolint
+	SRTP_AEAD_AES_256_GCM       = extension.SRTP_AEAD_AES_256_GCM       // nolint
 )
 
-var srtpProtectionProfiles = map[SRTPProtectionProfile]bool{
-	SRTP_AES128_CM_HMAC_SHA1_80: true,
+// SRTPKeyingMaterial are the SRTP master keys and salts for both directions
+// of a connection, as extracted from DTLS by RFC 5764 section 4.2
+type SRTPKeyingMaterial struct {
+	LocalMasterKey   []byte
+	LocalMasterSalt  []byte
+	RemoteMasterKey  []byte
+	RemoteMasterSalt []byte
 }
+
+// labelExtractorDtlsSrtp is the exporter label for SRTP keys
+// https://tools.ietf.org/html/rfc5764#section-4.2
+const labelExtractorDtlsSrtp = "EXTRACTOR-dtls_srtp"
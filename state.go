@@ -4,36 +4,45 @@ import (
 	"bytes"
 	"encoding/gob"
 	"sync/atomic"
+
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
 )
 
 // State holds the dtls connection state and implements both encoding.BinaryMarshaler and encoding.BinaryUnmarshaler
 type State struct {
 	localEpoch, remoteEpoch   atomic.Value
-	localSequenceNumber       uint64 // uint48
-	localRandom, remoteRandom handshakeRandom
+	localSequenceNumber       []uint64 // uint48, indexed by epoch
+	localRandom, remoteRandom handshake.Random
 	masterSecret              []byte
 	cipherSuite               cipherSuite // nil if a cipherSuite hasn't been chosen
 
-	srtpProtectionProfile SRTPProtectionProfile // Negotiated SRTPProtectionProfile
-	remoteCertificate     [][]byte
+	srtpProtectionProfile   SRTPProtectionProfile // Negotiated SRTPProtectionProfile
+	srtpMasterKeyIdentifier []byte                // Negotiated SRTP MKI, empty if not in use
+	negotiatedProtocol      string                // Negotiated application protocol, empty if none
+	remoteCertificate       [][]byte
 
 	isClient bool
 
 	preMasterSecret      []byte
 	extendedMasterSecret bool
+	encryptThenMAC       bool
 }
 
 type serializedState struct {
-	LocalEpoch            uint16
-	RemoteEpoch           uint16
-	LocalRandom           []byte
-	RemoteRandom          []byte
-	CipherSuiteID         uint16
-	MasterSecret          []byte
-	SequenceNumber        uint64
-	SRTPProtectionProfile uint16
-	RemoteCertificate     []byte
-	IsClient              bool
+	LocalEpoch              uint16
+	RemoteEpoch             uint16
+	LocalRandom             []byte
+	RemoteRandom            []byte
+	CipherSuiteID           uint16
+	MasterSecret            []byte
+	SequenceNumber          uint64
+	SRTPProtectionProfile   uint16
+	SRTPMasterKeyIdentifier []byte
+	RemoteCertificate       []byte
+	IsClient                bool
+	EncryptThenMAC          bool
+	NegotiatedProtocol      string
 }
 
 func (s *State) clone() (*State, error) {
@@ -62,24 +71,33 @@ func (s *State) serialize() (*serializedState, error) {
 	// Marshal remote certificate
 	var cert []byte
 	if s.remoteCertificate != nil {
-		h := &handshakeMessageCertificate{s.remoteCertificate}
+		h := &handshake.MessageCertificate{Certificate: s.remoteCertificate}
 		cert, err = h.Marshal()
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	localEpoch := s.localEpoch.Load().(uint16)
+	var sequenceNumber uint64
+	if int(localEpoch) < len(s.localSequenceNumber) {
+		sequenceNumber = s.localSequenceNumber[localEpoch]
+	}
+
 	serialized := serializedState{
-		LocalEpoch:            s.localEpoch.Load().(uint16),
-		RemoteEpoch:           s.remoteEpoch.Load().(uint16),
-		CipherSuiteID:         uint16(s.cipherSuite.ID()),
-		MasterSecret:          s.masterSecret,
-		SequenceNumber:        atomic.LoadUint64(&s.localSequenceNumber),
-		LocalRandom:           localRnd,
-		RemoteRandom:          remoteRnd,
-		SRTPProtectionProfile: uint16(s.srtpProtectionProfile),
-		RemoteCertificate:     cert,
-		IsClient:              s.isClient,
+		LocalEpoch:              localEpoch,
+		RemoteEpoch:             s.remoteEpoch.Load().(uint16),
+		CipherSuiteID:           uint16(s.cipherSuite.ID()),
+		MasterSecret:            s.masterSecret,
+		SequenceNumber:          sequenceNumber,
+		LocalRandom:             localRnd,
+		RemoteRandom:            remoteRnd,
+		SRTPProtectionProfile:   uint16(s.srtpProtectionProfile),
+		SRTPMasterKeyIdentifier: s.srtpMasterKeyIdentifier,
+		RemoteCertificate:       cert,
+		IsClient:                s.isClient,
+		EncryptThenMAC:          s.encryptThenMAC,
+		NegotiatedProtocol:      s.negotiatedProtocol,
 	}
 
 	return &serialized, nil
@@ -91,21 +109,29 @@ func (s *State) deserialize(serialized serializedState) error {
 	s.remoteEpoch.Store(serialized.RemoteEpoch)
 
 	// Set random values
-	localRandom := &handshakeRandom{}
+	localRandom := &handshake.Random{}
 	if err := localRandom.Unmarshal(serialized.LocalRandom); err != nil {
 		return err
 	}
 	s.localRandom = *localRandom
-	remoteRandom := &handshakeRandom{}
+	remoteRandom := &handshake.Random{}
 	if err := remoteRandom.Unmarshal(serialized.RemoteRandom); err != nil {
 		return err
 	}
 	s.remoteRandom = *remoteRandom
 
 	s.isClient = serialized.IsClient
+	s.masterSecret = serialized.MasterSecret
 
 	// Set cipher suite
 	s.cipherSuite = cipherSuiteForID(CipherSuiteID(serialized.CipherSuiteID))
+	if s.cipherSuite == nil {
+		return errInvalidCipherSuite
+	}
+	s.encryptThenMAC = serialized.EncryptThenMAC
+	if e, ok := s.cipherSuite.(encryptThenMACCipherSuite); ok && s.encryptThenMAC {
+		e.setEncryptThenMAC()
+	}
 	var err error
 	if serialized.IsClient {
 		err = s.cipherSuite.init(serialized.MasterSecret, serialized.LocalRandom, serialized.RemoteRandom, true)
@@ -116,16 +142,23 @@ func (s *State) deserialize(serialized serializedState) error {
 		return err
 	}
 
-	atomic.StoreUint64(&s.localSequenceNumber, serialized.SequenceNumber)
+	// The sequence numbers are indexed by epoch, only the current one is kept
+	if serialized.SequenceNumber > recordlayer.MaxSequenceNumber {
+		return errInvalidSequenceNumber
+	}
+	s.localSequenceNumber = make([]uint64, int(serialized.LocalEpoch)+1)
+	s.localSequenceNumber[serialized.LocalEpoch] = serialized.SequenceNumber
 	s.srtpProtectionProfile = SRTPProtectionProfile(serialized.SRTPProtectionProfile)
+	s.srtpMasterKeyIdentifier = serialized.SRTPMasterKeyIdentifier
+	s.negotiatedProtocol = serialized.NegotiatedProtocol
 
 	// Set remote certificate
 	if serialized.RemoteCertificate != nil {
-		h := &handshakeMessageCertificate{}
+		h := &handshake.MessageCertificate{}
 		if err := h.Unmarshal(serialized.RemoteCertificate); err != nil {
 			return err
 		}
-		s.remoteCertificate = h.certificate
+		s.remoteCertificate = h.Certificate
 	}
 
 	return nil